@@ -0,0 +1,298 @@
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// FormatVersion is bumped when the backup layout changes incompatibly
+const FormatVersion = 1
+
+// ManifestFile is the name of the manifest inside a backup directory
+const ManifestFile = "manifest.json"
+
+// Manifest describes a backup: one gzip-compressed JSONL file of
+// vector.Record per namespace, with record counts and checksums
+type Manifest struct {
+	FormatVersion      int                 `json:"format_version"`
+	CreatedAt          time.Time           `json:"created_at"`
+	ToolVersion        string              `json:"tool_version,omitempty"`
+	Dimension          int                 `json:"dimension"`
+	SimilarityFunction string              `json:"similarity_function"`
+	Namespaces         []NamespaceManifest `json:"namespaces"`
+}
+
+// NamespaceManifest locates and verifies one namespace's records
+type NamespaceManifest struct {
+	Name    string `json:"name"`
+	File    string `json:"file"`
+	Records int    `json:"records"`
+	SHA256  string `json:"sha256"`
+}
+
+// ExportOptions controls Export
+type ExportOptions struct {
+	Namespaces  []string // defaults to every namespace
+	PageSize    int      // records per Range request, defaults to 100
+	ToolVersion string
+}
+
+// ImportOptions controls Import
+type ImportOptions struct {
+	Namespaces []string          // backup namespaces to restore, defaults to all
+	Remap      map[string]string // backup namespace -> target namespace
+	BatchSize  int               // records per upsert, defaults to 100
+}
+
+// Export writes every requested namespace of store into dir, then the manifest.
+// The manifest is written last, and a manifest left by an earlier export into
+// dir is removed first, so a directory without one is incomplete.
+func Export(ctx context.Context, store vector.VectorStore, dir string, opts ExportOptions) (*Manifest, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = 100
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := os.Remove(filepath.Join(dir, ManifestFile)); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove the previous manifest: %w", err)
+	}
+
+	info, err := store.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := opts.Namespaces
+	if len(namespaces) == 0 {
+		for name := range info.Namespaces {
+			namespaces = append(namespaces, name)
+		}
+		sort.Strings(namespaces)
+	}
+
+	manifest := &Manifest{
+		FormatVersion:      FormatVersion,
+		CreatedAt:          time.Now().UTC(),
+		ToolVersion:        opts.ToolVersion,
+		Dimension:          info.Dimension,
+		SimilarityFunction: info.SimilarityFunction,
+	}
+	for _, name := range namespaces {
+		if _, ok := info.Namespaces[name]; !ok {
+			return nil, fmt.Errorf("namespace '%s' does not exist", name)
+		}
+		entry, err := exportNamespace(ctx, store, dir, name, opts.PageSize, info.Namespaces[name].VectorCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export namespace '%s': %w", name, err)
+		}
+		manifest.Namespaces = append(manifest.Namespaces, entry)
+		logger.LogSuccess(fmt.Sprintf("Exported %d records from namespace '%s'", entry.Records, name))
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func exportNamespace(ctx context.Context, store vector.VectorStore, dir, namespace string, pageSize, expected int) (NamespaceManifest, error) {
+	entry := NamespaceManifest{Name: namespace, File: namespaceFile(namespace)}
+
+	file, err := os.Create(filepath.Join(dir, entry.File))
+	if err != nil {
+		return entry, err
+	}
+	defer file.Close()
+
+	// Checksum the compressed bytes as they are written
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(file, hash))
+	encoder := json.NewEncoder(gz)
+
+	cursor := ""
+	for {
+		page, err := store.Range(ctx, namespace, vector.RangeRequest{
+			Cursor:          cursor,
+			Limit:           pageSize,
+			IncludeMetadata: true,
+			IncludeData:     true,
+			IncludeVectors:  true,
+		})
+		if err != nil {
+			return entry, err
+		}
+		for _, record := range page.Records {
+			if err := encoder.Encode(record); err != nil {
+				return entry, err
+			}
+		}
+		entry.Records += len(page.Records)
+		logger.LogProgress(entry.Records, expected, fmt.Sprintf("Exporting %s", namespace))
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if err := gz.Close(); err != nil {
+		return entry, err
+	}
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return entry, file.Close()
+}
+
+// ReadManifest loads and checks the manifest of a backup directory
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest (is this a complete backup?): %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("backup format version %d is newer than supported version %d", manifest.FormatVersion, FormatVersion)
+	}
+	return &manifest, nil
+}
+
+// Import restores a backup directory into store. Every file is verified
+// against its checksum before any of its records are upserted.
+func Import(ctx context.Context, store vector.VectorStore, dir string, opts ImportOptions) (map[string]int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := store.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.Dimension != 0 && manifest.Dimension != 0 && info.Dimension != manifest.Dimension {
+		return nil, fmt.Errorf("backup has dimension %d but the target index has %d", manifest.Dimension, info.Dimension)
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range opts.Namespaces {
+		selected[name] = true
+	}
+
+	imported := make(map[string]int)
+	for _, entry := range manifest.Namespaces {
+		if len(selected) > 0 && !selected[entry.Name] {
+			continue
+		}
+		target := entry.Name
+		if mapped, ok := opts.Remap[entry.Name]; ok {
+			target = mapped
+		}
+
+		path := filepath.Join(dir, entry.File)
+		if err := verifyChecksum(path, entry.SHA256); err != nil {
+			return imported, err
+		}
+		count, err := importNamespace(ctx, store, path, target, opts.BatchSize, entry.Records)
+		if err != nil {
+			return imported, fmt.Errorf("failed to import namespace '%s' into '%s' after %d records: %w", entry.Name, target, count, err)
+		}
+		imported[target] += count
+		logger.LogSuccess(fmt.Sprintf("Imported %d records from '%s' into namespace '%s'", count, entry.Name, target))
+	}
+	return imported, nil
+}
+
+func importNamespace(ctx context.Context, store vector.VectorStore, path, namespace string, batchSize, expected int) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+	batch := make([]vector.Record, 0, batchSize)
+	count := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := store.UpsertRecords(ctx, namespace, batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		logger.LogProgress(count, expected, fmt.Sprintf("Importing %s", namespace))
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		var record vector.Record
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return count, fmt.Errorf("corrupt record after %d records: %w", count+len(batch), err)
+		}
+		if _, ok := record.Metadata["namespace"]; ok {
+			record.Metadata["namespace"] = namespace
+		}
+		batch = append(batch, record)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, flush()
+}
+
+func verifyChecksum(path, expected string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: backup is corrupt or was modified", filepath.Base(path))
+	}
+	return nil
+}
+
+// namespaceFile names a namespace's file; the default namespace is "_default"
+func namespaceFile(namespace string) string {
+	if namespace == "" {
+		return "_default.jsonl.gz"
+	}
+	return url.PathEscape(namespace) + ".jsonl.gz"
+}
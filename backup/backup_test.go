@@ -0,0 +1,171 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+func openStore(t *testing.T, path string, dimension int) *vector.LocalStore {
+	t.Helper()
+	logger.SetOutput(io.Discard)
+	store, err := vector.NewLocalStore(config.LocalStoreConfig{Path: path, Dimension: dimension}, nil)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	return store
+}
+
+// seedStore fills a store with records in the docs and default namespaces
+func seedStore(t *testing.T, store vector.VectorStore) {
+	t.Helper()
+	ctx := context.Background()
+	for namespace, ids := range map[string][]string{"docs": {"a", "b", "c"}, "": {"d"}} {
+		documents := make([]vector.Document, len(ids))
+		for i, id := range ids {
+			documents[i] = vector.Document{ID: id, Content: "content of " + id, Metadata: map[string]interface{}{"namespace": namespace, "id": id}}
+		}
+		if err := store.UpsertBatch(ctx, documents, namespace); err != nil {
+			t.Fatalf("UpsertBatch: %v", err)
+		}
+	}
+}
+
+// namespaceRecords returns the records of a namespace sorted by ID
+func namespaceRecords(t *testing.T, store vector.VectorStore, namespace string) []vector.Record {
+	t.Helper()
+	page, err := store.Range(context.Background(), namespace, vector.RangeRequest{Limit: 100, IncludeMetadata: true, IncludeData: true, IncludeVectors: true})
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	sort.Slice(page.Records, func(i, j int) bool { return page.Records[i].ID < page.Records[j].ID })
+	return page.Records
+}
+
+func exportTestBackup(t *testing.T) (string, vector.VectorStore) {
+	t.Helper()
+	dir := t.TempDir()
+	src := openStore(t, filepath.Join(dir, "src.jsonl"), 32)
+	seedStore(t, src)
+	backupDir := filepath.Join(dir, "backup")
+	manifest, err := Export(context.Background(), src, backupDir, ExportOptions{PageSize: 2, ToolVersion: "test"})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(manifest.Namespaces) != 2 || manifest.Dimension != 32 {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	return backupDir, src
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	backupDir, src := exportTestBackup(t)
+	manifest, err := ReadManifest(backupDir)
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+	records := map[string]int{}
+	for _, entry := range manifest.Namespaces {
+		records[entry.Name] = entry.Records
+	}
+	if records["docs"] != 3 || records[""] != 1 {
+		t.Errorf("manifest record counts %v", records)
+	}
+
+	dst := openStore(t, filepath.Join(t.TempDir(), "dst.jsonl"), 32)
+	imported, err := Import(context.Background(), dst, backupDir, ImportOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if imported["docs"] != 3 || imported[""] != 1 {
+		t.Errorf("imported %v", imported)
+	}
+
+	for _, namespace := range []string{"docs", ""} {
+		want, got := namespaceRecords(t, src, namespace), namespaceRecords(t, dst, namespace)
+		if len(got) != len(want) {
+			t.Fatalf("namespace %q: %d records restored, want %d", namespace, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].ID != want[i].ID || got[i].Data != want[i].Data || len(got[i].Vector) != len(want[i].Vector) || got[i].Vector[0] != want[i].Vector[0] {
+				t.Errorf("namespace %q: restored %+v, want %+v", namespace, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestImportRemapsNamespaces(t *testing.T) {
+	backupDir, _ := exportTestBackup(t)
+	dst := openStore(t, filepath.Join(t.TempDir(), "dst.jsonl"), 32)
+	imported, err := Import(context.Background(), dst, backupDir, ImportOptions{
+		Namespaces: []string{"docs"},
+		Remap:      map[string]string{"docs": "restored"},
+	})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(imported) != 1 || imported["restored"] != 3 {
+		t.Fatalf("imported %v", imported)
+	}
+	records := namespaceRecords(t, dst, "restored")
+	if len(records) != 3 || records[0].Metadata["namespace"] != "restored" {
+		t.Errorf("remapped records %+v", records)
+	}
+	if len(namespaceRecords(t, dst, "docs")) != 0 || len(namespaceRecords(t, dst, "")) != 0 {
+		t.Error("records were restored outside the selected, remapped namespace")
+	}
+}
+
+func TestImportRejectsChecksumMismatch(t *testing.T) {
+	backupDir, _ := exportTestBackup(t)
+	path := filepath.Join(backupDir, namespaceFile("docs"))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := openStore(t, filepath.Join(t.TempDir(), "dst.jsonl"), 32)
+	_, err = Import(context.Background(), dst, backupDir, ImportOptions{Namespaces: []string{"docs"}})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if len(namespaceRecords(t, dst, "docs")) != 0 {
+		t.Error("records of a corrupt file were imported")
+	}
+}
+
+func TestImportRejectsDimensionMismatch(t *testing.T) {
+	backupDir, _ := exportTestBackup(t)
+	dst := openStore(t, filepath.Join(t.TempDir(), "dst.jsonl"), 64)
+	_, err := Import(context.Background(), dst, backupDir, ImportOptions{})
+	if err == nil || !strings.Contains(err.Error(), "dimension 32") {
+		t.Fatalf("expected a dimension mismatch, got %v", err)
+	}
+}
+
+func TestExportRemovesStaleManifest(t *testing.T) {
+	backupDir, src := exportTestBackup(t)
+
+	// An export that fails part way leaves no manifest from the earlier one
+	if _, err := Export(context.Background(), src, backupDir, ExportOptions{Namespaces: []string{"docs", "missing"}}); err == nil {
+		t.Fatal("expected exporting a missing namespace to fail")
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, ManifestFile)); !os.IsNotExist(err) {
+		t.Errorf("stale manifest kept after a failed export: %v", err)
+	}
+	if _, err := ReadManifest(backupDir); err == nil {
+		t.Error("an incomplete backup was read as complete")
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/search"
+)
+
+var (
+	catNamespace string
+	catJSON      bool
+)
+
+// catCmd represents the cat command
+var catCmd = &cobra.Command{
+	Use:   "cat <path>",
+	Short: "Print a file reassembled from its indexed chunks",
+	Long: `Fetch every chunk of an ingested file, order them by chunk_index and print
+the file stitched back together. The path is the source_file recorded at
+ingestion, relative to the ingested folder.
+
+A warning is printed to stderr when chunks are missing compared with the total_chunks recorded at ingestion.
+
+Examples:
+  prj-start cat document/chunker.go
+  prj-start cat notes/deploy-checklist.md --namespace notes
+  prj-start cat api/README.md --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCat,
+}
+
+func init() {
+	rootCmd.AddCommand(catCmd)
+	catCmd.Flags().StringVarP(&catNamespace, "namespace", "n", "", "namespace holding the file (default is derived from the path)")
+	catCmd.Flags().BoolVar(&catJSON, "json", false, "print the file and its completeness as JSON")
+}
+
+func runCat(cmd *cobra.Command, args []string) error {
+	_, store, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+
+	file, err := search.NewService(store).GetFile(context.Background(), catNamespace, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to fetch file: %w", err)
+	}
+	if file == nil {
+		return fmt.Errorf("no chunks of '%s' are indexed", args[0])
+	}
+
+	if catJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(file.Content)
+	}
+
+	if !file.Complete {
+		logger.LogWarning(fmt.Sprintf("Indexed copy of %s is incomplete: %d of %d chunks, missing %v",
+			file.Source, file.Chunks, file.TotalChunks, file.Missing))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vectortest"
+)
+
+// fakeUpstashCmd represents the fake-upstash command
+var fakeUpstashCmd = &cobra.Command{
+	Use:   "fake-upstash",
+	Short: "Run a fake Upstash Vector server for demos and testing",
+	Long: `Serve a fake of the Upstash Vector REST API backed by the local store.
+Data is embedded with the deterministic hashing embedder, so search quality
+is lexical, but every command works end to end without credentials.
+
+The index lives in a temporary directory and is discarded on exit unless
+--path is given.
+
+Examples:
+  prj-start fake-upstash
+  prj-start fake-upstash --addr 127.0.0.1:8090 --token demo --path ./demo-index.jsonl
+
+Then point prj-start at it from another shell:
+  UPSTASH_VECTOR_REST_URL=http://127.0.0.1:8090 UPSTASH_VECTOR_REST_TOKEN=demo prj-start ingest`,
+	Args: cobra.NoArgs,
+	RunE: runFakeUpstash,
+}
+
+var (
+	fakeUpstashAddr       string
+	fakeUpstashToken      string
+	fakeUpstashDimension  int
+	fakeUpstashSimilarity string
+	fakeUpstashPath       string
+)
+
+func init() {
+	rootCmd.AddCommand(fakeUpstashCmd)
+
+	fakeUpstashCmd.Flags().StringVar(&fakeUpstashAddr, "addr", "127.0.0.1:8090", "address to listen on")
+	fakeUpstashCmd.Flags().StringVar(&fakeUpstashToken, "token", vectortest.DefaultToken, "bearer token clients must send")
+	fakeUpstashCmd.Flags().IntVar(&fakeUpstashDimension, "dimension", 0, "embedding dimension (default 256)")
+	fakeUpstashCmd.Flags().StringVar(&fakeUpstashSimilarity, "similarity", "COSINE", "COSINE, DOT_PRODUCT or EUCLIDEAN")
+	fakeUpstashCmd.Flags().StringVar(&fakeUpstashPath, "path", "", "journal file to keep the index in (default is a temporary directory)")
+}
+
+func runFakeUpstash(cmd *cobra.Command, args []string) error {
+	handler, err := vectortest.NewHandler(vectortest.Options{
+		Token:      fakeUpstashToken,
+		Dimension:  fakeUpstashDimension,
+		Similarity: fakeUpstashSimilarity,
+		Path:       fakeUpstashPath,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create fake index: %w", err)
+	}
+	defer handler.Close()
+
+	listener, err := net.Listen("tcp", fakeUpstashAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", fakeUpstashAddr, err)
+	}
+	server := &http.Server{Handler: handler}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.LogSuccess(fmt.Sprintf("Fake Upstash Vector listening on http://%s", listener.Addr()))
+	fmt.Printf("UPSTASH_VECTOR_REST_URL=http://%s\n", listener.Addr())
+	fmt.Printf("UPSTASH_VECTOR_REST_TOKEN=%s\n", fakeUpstashToken)
+
+	// The deferred handler.Close runs only after requests have drained
+	if err := serveHTTP(ctx, "Fake Upstash Vector", server, listener, 5*time.Second, nil); err != nil {
+		return err
+	}
+	logger.LogInfo("Fake Upstash Vector stopped")
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/typicalfo/prj-start/logger"
+)
+
+// serveHTTP serves name on listener until ctx is done, then shuts down
+// gracefully: requests in flight get timeout to finish. When they do not,
+// abort is called to end long-lived streams and the remaining connections are
+// closed. It returns only once the drain is over.
+func serveHTTP(ctx context.Context, name string, httpServer *http.Server, listener net.Listener, timeout time.Duration, abort func()) error {
+	serveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-serveCtx.Done()
+		if ctx.Err() != nil {
+			logger.LogInfo(fmt.Sprintf("Shutting down %s", name))
+		}
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), timeout)
+		defer cancelShutdown()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			if abort != nil {
+				abort()
+			}
+			httpServer.Close()
+		}
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		cancel()
+		<-done
+		return err
+	}
+	<-done
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/typicalfo/prj-start/logger"
+)
+
+// startServeHTTP runs serveHTTP in the background, returning its address and
+// a channel receiving its result
+func startServeHTTP(t *testing.T, ctx context.Context, handler http.Handler, timeout time.Duration, abort func()) (string, <-chan error) {
+	t.Helper()
+	logger.SetOutput(io.Discard)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- serveHTTP(ctx, "test server", &http.Server{Handler: handler}, listener, timeout, abort)
+	}()
+	return listener.Addr().String(), result
+}
+
+func TestServeHTTPDrainsInFlightRequests(t *testing.T) {
+	started, finished := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		close(finished)
+		w.Write([]byte("done"))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	aborted := false
+	addr, result := startServeHTTP(t, ctx, handler, 5*time.Second, func() { aborted = true })
+
+	response := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+	<-started
+	cancel()
+
+	if err := <-result; err != nil {
+		t.Fatalf("serveHTTP: %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("serveHTTP returned before the in-flight request finished")
+	}
+	if got := <-response; got != "done" {
+		t.Errorf("in-flight request got %q", got)
+	}
+	if aborted {
+		t.Error("abort was called although the drain finished in time")
+	}
+}
+
+func TestServeHTTPAbortsAfterTimeout(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, result := startServeHTTP(t, ctx, handler, 100*time.Millisecond, func() { close(release) })
+
+	go http.Get("http://" + addr)
+	<-started
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("serveHTTP: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveHTTP did not return after the shutdown timeout")
+	}
+	select {
+	case <-release:
+	default:
+		t.Error("abort was not called after the shutdown timeout")
+	}
+}
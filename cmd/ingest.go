@@ -6,12 +6,14 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/typicalfo/prj-start/config"
 	"github.com/typicalfo/prj-start/processor"
 )
 
 var (
-	ingestFolder string
+	ingestFolder         string
+	ingestContextHeaders bool
+	ingestParents        string
+	ingestSparse         bool
 )
 
 // ingestCmd represents the ingest command
@@ -25,13 +27,19 @@ and upserts them with rich metadata for enhanced search and retrieval.
 Examples:
   prj-start ingest                    # Ingest from current directory
   prj-start ingest --folder ./docs    # Ingest from specific folder
-  prj-start ingest -f ./docs -v       # Ingest with verbose output`,
+  prj-start ingest -f ./docs -v       # Ingest with verbose output
+  prj-start ingest --context-headers  # Embed file/recipe/symbol context with each chunk
+  prj-start ingest --parents section  # Add file outline and section parent records
+  prj-start ingest --sparse           # Add BM25 sparse vectors for hybrid indexes`,
 	RunE: runIngest,
 }
 
 func init() {
 	rootCmd.AddCommand(ingestCmd)
 	ingestCmd.Flags().StringVarP(&ingestFolder, "folder", "f", "", "folder to scan for documents (default is current directory)")
+	ingestCmd.Flags().StringVar(&ingestParents, "parents", "", "parent records for small-to-big retrieval: none, file or section")
+	ingestCmd.Flags().BoolVar(&ingestSparse, "sparse", false, "compute BM25 sparse vectors for hybrid indexes (requires an embedding provider on Upstash)")
+	ingestCmd.Flags().BoolVar(&ingestContextHeaders, "context-headers", false, "embed a header with file path, recipe and symbol in front of each chunk")
 }
 
 func runIngest(cmd *cobra.Command, args []string) error {
@@ -46,14 +54,24 @@ func runIngest(cmd *cobra.Command, args []string) error {
 	}
 
 	// Load configuration
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w\n\nUse 'prj-start init' to set up your configuration", err)
 	}
 
-	// Check if Upstash configuration is complete
-	if !cfg.HasUpstashConfig() {
-		return fmt.Errorf("Upstash configuration is incomplete\n\nUse 'prj-start init' to set up your configuration")
+	if cmd.Flags().Changed("context-headers") {
+		cfg.ContextHeaders = ingestContextHeaders
+	}
+	if cmd.Flags().Changed("parents") {
+		cfg.ParentChunks = ingestParents
+	}
+	if cmd.Flags().Changed("sparse") {
+		cfg.SparseVectors = ingestSparse
+	}
+
+	// Check if the vector store configuration is complete
+	if !cfg.HasStoreConfig() {
+		return fmt.Errorf("Upstash configuration is incomplete\n\nUse 'prj-start init' to set up your configuration, or set store: local")
 	}
 
 	// Validate folder
@@ -27,7 +27,13 @@ The configuration will be saved to:
 - %LOCALAPPDATA%/prj-start/config.yaml (Windows)
 - Or ./prj-start.yaml if no home directory is available
 
-You can also specify a custom config file with --config flag.`,
+You can also specify a custom config file with --config flag.
+
+With --profile, a named connection profile is added to the existing
+configuration instead, leaving the other profiles and settings untouched:
+
+  prj-start init --profile staging
+  prj-start --profile staging search "middleware"`,
 	RunE: runInit,
 }
 
@@ -50,8 +56,16 @@ func runInit(cmd *cobra.Command, args []string) error {
 		targetPath = config.GetDefaultConfigPath()
 	}
 
+	if profileName != "" {
+		return runInitProfile(reader, targetPath, profileName)
+	}
+
 	// Check if config already exists
-	if _, err := os.Stat(targetPath); err == nil && !force {
+	var existing *config.Config
+	if _, err := os.Stat(targetPath); err == nil {
+		existing, _ = config.LoadFile(targetPath)
+	}
+	if existing != nil && !force {
 		fmt.Printf("Configuration file already exists at: %s\n", targetPath)
 		fmt.Print("Do you want to overwrite it? (y/N): ")
 		response, _ := reader.ReadString('\n')
@@ -71,6 +85,11 @@ func runInit(cmd *cobra.Command, args []string) error {
 		BatchSize:        10,
 		LogLevel:         "info",
 	}
+	if existing != nil {
+		// Profiles are managed with --profile and survive a re-run of init
+		cfg.Profiles = existing.Profiles
+		cfg.DefaultProfile = existing.DefaultProfile
+	}
 
 	// Get Upstash configuration
 	fmt.Println("📋 Upstash Vector Configuration")
@@ -118,6 +137,83 @@ func runInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runInitProfile adds or replaces one profile in the config file, keeping
+// everything else in it as written. A missing file is created with just the
+// profile, so the usual defaults still apply to every other setting.
+func runInitProfile(reader *bufio.Reader, targetPath, name string) error {
+	cfg := &config.Config{}
+	if _, err := os.Stat(targetPath); err == nil {
+		existing, err := config.LoadFile(targetPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", targetPath, err)
+		}
+		cfg = existing
+	}
+
+	if _, ok := cfg.Profiles[name]; ok && !force {
+		fmt.Printf("Profile '%s' already exists in: %s\n", name, targetPath)
+		fmt.Print("Do you want to overwrite it? (y/N): ")
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Configuration setup cancelled.")
+			return nil
+		}
+	}
+
+	fmt.Printf("Profile '%s' will be saved to: %s\n", name, targetPath)
+	fmt.Println()
+
+	var profile config.Profile
+	profile.Store = strings.ToLower(promptForInput(reader, "Vector store (upstash or local)", config.StoreUpstash, true))
+	switch profile.Store {
+	case config.StoreUpstash:
+		fmt.Println("You can get these values from: https://console.upstash.com")
+		profile.Upstash.URL = promptForInput(reader, "Upstash Vector REST URL", "https://your-vector-url.upstash.io", true)
+		profile.Upstash.Token = promptForInput(reader, "Upstash Vector REST Token", "", true)
+		profile.Upstash.IndexURL = promptForInput(reader, "Upstash Vector Index URL (optional)", "", false)
+	case config.StoreLocal:
+		profile.Local.Path = promptForInput(reader, "Local store path", fmt.Sprintf("./.prj-start-%s.jsonl", name), true)
+	default:
+		return fmt.Errorf("unknown store %q (use upstash or local)", profile.Store)
+	}
+
+	makeDefault := false
+	if cfg.DefaultProfile != name {
+		suggestion := "n"
+		if cfg.DefaultProfile == "" {
+			suggestion = "y"
+		}
+		response := strings.ToLower(promptForInput(reader, fmt.Sprintf("Make '%s' the default profile? (y/n)", name), suggestion, false))
+		makeDefault = response == "y" || response == "yes"
+	}
+
+	if err := config.SaveProfile(targetPath, name, profile, makeDefault); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]config.Profile)
+	}
+	cfg.Profiles[name] = profile
+	if makeDefault {
+		cfg.DefaultProfile = name
+	}
+
+	fmt.Println()
+	fmt.Printf("✅ Profile '%s' saved to %s\n", name, targetPath)
+	fmt.Printf("   Profiles: %s (default: %s)\n", strings.Join(cfg.ProfileNames(), ", "), defaultProfileLabel(cfg))
+	fmt.Println()
+	fmt.Printf("💡 Use it with: prj-start --profile %s <command>\n", name)
+	return nil
+}
+
+func defaultProfileLabel(cfg *config.Config) string {
+	if cfg.DefaultProfile == "" {
+		return "none"
+	}
+	return cfg.DefaultProfile
+}
+
 func promptForInput(reader *bufio.Reader, prompt, defaultValue string, required bool) string {
 	for {
 		if defaultValue != "" {
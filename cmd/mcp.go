@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/spf13/cobra"
 	"github.com/typicalfo/prj-start/config"
-	"github.com/upstash/vector-go"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/processor"
+	"github.com/typicalfo/prj-start/search"
+	"github.com/typicalfo/prj-start/vector"
 )
 
 // mcpCmd represents the mcp command
@@ -24,53 +28,127 @@ The MCP server provides:
 - Metadata-based filtering
 - Real-time document retrieval
 
-This command uses your existing Upstash Vector configuration.
+This command uses your existing Upstash Vector configuration, or the
+embedded local store when the config sets store: local.
+
+By default the server speaks MCP over stdio, one process per agent. With
+--http it serves the streamable HTTP transport on /mcp and the legacy SSE
+transport on /sse, so a whole team can share one server, plus /healthz and
+/readyz endpoints for probes.
+
+The server is read-only unless ingest roots are given with --ingest-root or
+mcp_ingest_roots in the config. Then agents can also call ingest_path (files
+and folders under a root), ingest_text (notes with a title and tags) and
+remove_source.
 
 Examples:
   prj-start mcp                    # Start MCP server
-  prj-start mcp --debug           # Start with debug logging`,
+  prj-start mcp --debug           # Start with debug logging
+  prj-start mcp --http :8080       # Serve MCP over HTTP on 127.0.0.1:8080
+  prj-start mcp --http 0.0.0.0:8080 --token $TOKEN  # Serve on every interface
+  prj-start mcp --ingest-root ./dev-docs  # Let agents ingest files and notes`,
 	RunE: runMCP,
 }
 
 var (
-	debug bool
+	debug          bool
+	mcpHTTP        string
+	mcpToken       string
+	mcpIngestRoots []string
 )
 
 func init() {
 	rootCmd.AddCommand(mcpCmd)
 	mcpCmd.Flags().BoolVar(&debug, "debug", false, "enable debug logging")
+	mcpCmd.Flags().StringSliceVar(&mcpIngestRoots, "ingest-root", nil, "enable the ingest tools; ingest_path may read under this folder (repeatable)")
+	mcpCmd.Flags().StringVar(&mcpHTTP, "http", "", "serve streamable HTTP and SSE on this address instead of stdio, e.g. :8080 (host defaults to 127.0.0.1)")
+	mcpCmd.Flags().StringVar(&mcpToken, "token", "", "bearer token HTTP clients must send (or MCP_AUTH_TOKEN); required off loopback or with ingest tools")
 }
 
 func runMCP(cmd *cobra.Command, args []string) error {
 	// Load configuration
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	// Check Upstash configuration
-	if !cfg.HasUpstashConfig() {
-		return fmt.Errorf("Upstash configuration incomplete\n\nUse 'prj-start init' to set up your configuration")
+	// Check vector store configuration
+	if !cfg.HasStoreConfig() {
+		return fmt.Errorf("Upstash configuration incomplete\n\nUse 'prj-start init' to set up your configuration, or set store: local")
+	}
+
+	// In stdio mode stdout carries the MCP protocol; keep log output on stderr
+	logger.SetOutput(os.Stderr)
+	if !debug && mcpHTTP == "" {
+		logger.SetLogLevel("warn")
 	}
 
 	if debug {
 		log.Printf("Loaded config: URL=%s, Token=%s", cfg.Upstash.URL, cfg.Upstash.Token)
 	}
 
-	// Create vector client
-	vectorClient := createVectorClient(cfg)
+	// Create vector store
+	vectorClient, err := createVectorStore(cfg)
+	if err != nil {
+		return err
+	}
 
 	// Create MCP server
 	server := createMCPServer()
 
+	// Structured filters are validated against the configured metadata schema
+	schema, err := processor.MetadataSchema(cfg)
+	if err != nil {
+		return err
+	}
+
 	// Add tools
-	addVectorQueryTool(server, vectorClient)
-	addMetadataQueryTool(server, vectorClient)
-	addListNamespacesTool(server, vectorClient)
-	addGetDocumentTool(server, vectorClient)
+	searcher := search.NewService(vectorClient)
+	searcher.SetMetadataSchema(schema)
+	addVectorQueryTool(server, searcher)
+	addMetadataQueryTool(server, searcher)
+	addListNamespacesTool(server, searcher)
+	addGetDocumentTool(server, searcher)
+	addGetFileTool(server, searcher)
+
+	// Indexed files and recipes are listed as resources once the index is
+	// scanned; the scan runs in the background so startup stays fast
+	catalog := newResourceCatalog(server, searcher)
+	go func() {
+		if err := catalog.Refresh(context.Background()); err != nil {
+			logger.LogWarning(fmt.Sprintf("Failed to list indexed files as resources: %v", err))
+		}
+	}()
+
+	// The ingest tools write to the index, so they are only offered when
+	// ingest roots are configured
+	if cmd.Flags().Changed("ingest-root") {
+		cfg.MCPIngestRoots = mcpIngestRoots
+	}
+	if len(cfg.MCPIngestRoots) > 0 {
+		roots, err := ingestRoots(cfg.MCPIngestRoots)
+		if err != nil {
+			return err
+		}
+		addIngestPathTool(server, cfg, vectorClient, roots, catalog)
+		addIngestTextTool(server, cfg, vectorClient, catalog)
+		addRemoveSourceTool(server, cfg, vectorClient, catalog)
+	}
 
 	// Add resources
 	addHelpResource(server)
+	addDocumentResourceTemplates(server, searcher)
+
+	if mcpHTTP != "" {
+		if cmd.Flags().Changed("token") {
+			cfg.MCPAuthToken = mcpToken
+		}
+		addr := mcpHTTPAddr(mcpHTTP)
+		if err := checkMCPHTTPAuth(addr, cfg.MCPAuthToken, len(cfg.MCPIngestRoots) > 0); err != nil {
+			return err
+		}
+		return serveMCPHTTP(server, vectorClient, addr, cfg.MCPAuthToken)
+	}
 
 	// Start server with stdio transport
 	log.Println("Starting MCP server...")
@@ -92,15 +170,17 @@ func createMCPServer() *mcp.Server {
 	}, nil)
 }
 
-func createVectorClient(cfg *config.Config) *vector.Index {
-	if cfg.Upstash.URL != "" && cfg.Upstash.Token != "" {
-		if debug {
+func createVectorStore(cfg *config.Config) (vector.VectorStore, error) {
+	if debug {
+		if cfg.UsesLocalStore() {
+			log.Printf("Opening local vector store: %s", cfg.Local.StorePath())
+		} else {
 			log.Printf("Creating vector client with URL: %s", cfg.Upstash.URL)
 		}
-		return vector.NewIndex(cfg.Upstash.URL, cfg.Upstash.Token)
 	}
-	if debug {
-		log.Println("Creating vector client from environment")
+	store, err := vector.OpenStore(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vector store: %w", err)
 	}
-	return vector.NewIndexFromEnv()
+	return store, nil
 }
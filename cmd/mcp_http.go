@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// How long a shutdown waits for in-flight requests before sessions are closed
+const mcpShutdownTimeout = 10 * time.Second
+
+// How long a readiness probe waits for the vector store
+const mcpReadyTimeout = 5 * time.Second
+
+// newMCPHTTPHandler routes the MCP transports and health endpoints:
+//
+//	/mcp      streamable HTTP transport
+//	/sse      legacy HTTP+SSE transport
+//	/healthz  liveness, always 200 while the process serves requests
+//	/readyz   readiness, 200 only when the vector store answers
+//
+// When token is set, the transports require it as a bearer token; the health
+// endpoints stay open for probes.
+func newMCPHTTPHandler(server *mcp.Server, store vector.VectorStore, token string) http.Handler {
+	getServer := func(*http.Request) *mcp.Server { return server }
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", requireBearer(token, mcp.NewStreamableHTTPHandler(getServer, nil)))
+	mux.Handle("/sse", requireBearer(token, mcp.NewSSEHandler(getServer)))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, map[string]interface{}{
+			"status":   "ok",
+			"sessions": countSessions(server),
+		})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), mcpReadyTimeout)
+		defer cancel()
+		if _, err := store.Info(ctx); err != nil {
+			writeHealth(w, http.StatusServiceUnavailable, map[string]interface{}{
+				"status": "unavailable",
+				"error":  err.Error(),
+			})
+			return
+		}
+		writeHealth(w, http.StatusOK, map[string]interface{}{"status": "ready"})
+	})
+	return mux
+}
+
+// requireBearer rejects requests without "Authorization: Bearer <token>".
+// An empty token disables the check.
+func requireBearer(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="prj-start"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// mcpHTTPAddr binds addresses without a host, such as ":8080", to loopback,
+// so the server is not exposed on every interface by accident
+func mcpHTTPAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// isLoopbackAddr reports whether addr only accepts local connections
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkMCPHTTPAuth requires a token for servers reachable from other hosts
+// and for servers whose tools write to the index
+func checkMCPHTTPAuth(addr, token string, ingest bool) error {
+	switch {
+	case token != "":
+		return nil
+	case !isLoopbackAddr(addr):
+		return fmt.Errorf("serving MCP over HTTP on %s requires a bearer token: set --token or MCP_AUTH_TOKEN, or bind to 127.0.0.1", addr)
+	case ingest:
+		return fmt.Errorf("serving the MCP ingest tools over HTTP requires a bearer token: set --token or MCP_AUTH_TOKEN")
+	}
+	return nil
+}
+
+// serveMCPHTTP serves the MCP server on addr until SIGINT or SIGTERM. On
+// shutdown in-flight tool calls get the shutdown timeout to finish; sessions
+// still open after it, such as idle SSE streams, are then closed.
+func serveMCPHTTP(server *mcp.Server, store vector.VectorStore, addr, token string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	httpServer := &http.Server{
+		Handler:           newMCPHTTPHandler(server, store, token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.LogSuccess(fmt.Sprintf("MCP server listening on http://%s/mcp (SSE: /sse)", listener.Addr()))
+	err = serveHTTP(ctx, "MCP server", httpServer, listener, mcpShutdownTimeout, func() {
+		for session := range server.Sessions() {
+			session.Close()
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("MCP server failed: %w", err)
+	}
+	logger.LogInfo("MCP server stopped")
+	return nil
+}
+
+func countSessions(server *mcp.Server) int {
+	count := 0
+	for range server.Sessions() {
+		count++
+	}
+	return count
+}
+
+func writeHealth(w http.ResponseWriter, status int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
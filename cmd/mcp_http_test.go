@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vectortest"
+)
+
+func TestMCPHTTPRequiresBearerToken(t *testing.T) {
+	logger.SetOutput(io.Discard)
+	srv, err := vectortest.NewServer(vectortest.Options{})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.Close()
+	handler := newMCPHTTPHandler(createMCPServer(), srv.Store(), "s3cret")
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+	tests := []struct {
+		name          string
+		method, path  string
+		authorization string
+		want          int
+	}{
+		{"mcp without token", http.MethodPost, "/mcp", "", http.StatusUnauthorized},
+		{"mcp with wrong token", http.MethodPost, "/mcp", "Bearer nope", http.StatusUnauthorized},
+		{"mcp with basic auth", http.MethodPost, "/mcp", "Basic czNjcmV0", http.StatusUnauthorized},
+		{"mcp with token", http.MethodPost, "/mcp", "Bearer s3cret", http.StatusOK},
+		{"sse without token", http.MethodGet, "/sse", "", http.StatusUnauthorized},
+		{"healthz without token", http.MethodGet, "/healthz", "", http.StatusOK},
+		{"readyz without token", http.MethodGet, "/readyz", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(initialize))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json, text/event-stream")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("got status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestMCPHTTPAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		want     string
+		loopback bool
+	}{
+		{":8080", "127.0.0.1:8080", true},
+		{"localhost:8080", "localhost:8080", true},
+		{"127.0.0.1:8080", "127.0.0.1:8080", true},
+		{"[::1]:8080", "[::1]:8080", true},
+		{"0.0.0.0:8080", "0.0.0.0:8080", false},
+		{"[::]:8080", "[::]:8080", false},
+		{"10.0.0.5:8080", "10.0.0.5:8080", false},
+		{"example.com:8080", "example.com:8080", false},
+	}
+	for _, tt := range tests {
+		got := mcpHTTPAddr(tt.addr)
+		if got != tt.want {
+			t.Errorf("mcpHTTPAddr(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+		if loopback := isLoopbackAddr(got); loopback != tt.loopback {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", got, loopback, tt.loopback)
+		}
+	}
+}
+
+func TestCheckMCPHTTPAuth(t *testing.T) {
+	tests := []struct {
+		addr    string
+		token   string
+		ingest  bool
+		wantErr bool
+	}{
+		{"127.0.0.1:8080", "", false, false},
+		{"127.0.0.1:8080", "", true, true},
+		{"127.0.0.1:8080", "s3cret", true, false},
+		{"0.0.0.0:8080", "", false, true},
+		{"0.0.0.0:8080", "s3cret", true, false},
+	}
+	for _, tt := range tests {
+		err := checkMCPHTTPAuth(tt.addr, tt.token, tt.ingest)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkMCPHTTPAuth(%q, %q, %v) = %v, want error %v", tt.addr, tt.token, tt.ingest, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/processor"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// defaultNotesNamespace holds notes ingested with ingest_text
+const defaultNotesNamespace = "notes"
+
+// IngestPathInput represents input for the ingest path tool
+type IngestPathInput struct {
+	Path string `json:"path" jsonschema:"file or folder to ingest, absolute or relative to an allowed root"`
+}
+
+// IngestPathOutput represents output for the ingest path tool
+type IngestPathOutput struct {
+	Root    string   `json:"root"`
+	Files   int      `json:"files"`
+	Records int      `json:"records"`
+	Sources []string `json:"sources"`
+}
+
+// IngestTextInput represents input for the ingest text tool
+type IngestTextInput struct {
+	Title     string   `json:"title" jsonschema:"title of the note, used as its heading and source name"`
+	Text      string   `json:"text" jsonschema:"note content, Markdown is chunked by heading"`
+	Tags      []string `json:"tags,omitempty" jsonschema:"tags stored in the tags metadata field"`
+	Namespace string   `json:"namespace,omitempty" jsonschema:"namespace to store the note in (default: notes)"`
+}
+
+// IngestTextOutput represents output for the ingest text tool
+type IngestTextOutput struct {
+	Source    string `json:"source"`
+	Namespace string `json:"namespace"`
+	Records   int    `json:"records"`
+}
+
+// RemoveSourceInput represents input for the remove source tool
+type RemoveSourceInput struct {
+	Source string `json:"source" jsonschema:"source_file of the records to delete, e.g. notes/deploy-checklist.md"`
+}
+
+// RemoveSourceOutput represents output for the remove source tool
+type RemoveSourceOutput struct {
+	Source  string `json:"source"`
+	Deleted int    `json:"deleted"`
+}
+
+// ingestRoots resolves the folders ingest_path may read from to absolute
+// paths without symlinks, so containment checks cannot be escaped
+func ingestRoots(paths []string) ([]string, error) {
+	roots := make([]string, 0, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("ingest root '%s': %w", path, err)
+		}
+		resolved, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return nil, fmt.Errorf("ingest root '%s': %w", path, err)
+		}
+		roots = append(roots, resolved)
+	}
+	return roots, nil
+}
+
+// resolveIngestPath returns the allowed root containing path and the resolved
+// path. Relative paths are tried against each root in order.
+func resolveIngestPath(roots []string, path string) (string, string, error) {
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		candidates = candidates[:0]
+		for _, root := range roots {
+			candidates = append(candidates, filepath.Join(root, path))
+		}
+	}
+
+	for _, candidate := range candidates {
+		resolved, err := filepath.EvalSymlinks(candidate)
+		if err != nil {
+			continue
+		}
+		for _, root := range roots {
+			rel, err := filepath.Rel(root, resolved)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return root, resolved, nil
+			}
+		}
+		return "", "", fmt.Errorf("'%s' is outside the allowed ingest roots", path)
+	}
+	return "", "", fmt.Errorf("'%s' does not exist under the allowed ingest roots", path)
+}
+
+var noteSlugRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// noteSlug turns a note title into a file name
+func noteSlug(title string) string {
+	slug := strings.Trim(noteSlugRegex.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if slug == "" {
+		return "note"
+	}
+	return slug
+}
+
+// progressReporter forwards upsert progress to the client as MCP progress
+// notifications, when the request carries a progress token
+func progressReporter(ctx context.Context, req *mcp.CallToolRequest, message string) func(processed, total int) {
+	token := req.Params.GetProgressToken()
+	if token == nil || req.Session == nil {
+		return nil
+	}
+	return func(processed, total int) {
+		req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(processed),
+			Total:         float64(total),
+			Message:       message,
+		})
+	}
+}
+
+// refreshCatalog updates the listed resources after the index changed
+func refreshCatalog(ctx context.Context, catalog *resourceCatalog) {
+	if catalog == nil {
+		return
+	}
+	if err := catalog.Refresh(ctx); err != nil {
+		logger.LogWarning(fmt.Sprintf("Failed to refresh resource list: %v", err))
+	}
+}
+
+func ingestToolError(format string, err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf(format, err)}},
+		IsError: true,
+	}
+}
+
+// addIngestPathTool adds the ingest path tool to the MCP server
+func addIngestPathTool(server *mcp.Server, cfg *config.Config, store vector.VectorStore, roots []string, catalog *resourceCatalog) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "ingest_path",
+		Description: fmt.Sprintf("Ingest a file or folder into the vector index. Paths must be under an allowed root: %s", strings.Join(roots, ", ")),
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input IngestPathInput) (
+		*mcp.CallToolResult,
+		IngestPathOutput,
+		error,
+	) {
+		root, path, err := resolveIngestPath(roots, input.Path)
+		if err != nil {
+			return ingestToolError("Ingest failed: %v", err), IngestPathOutput{}, err
+		}
+
+		reader := document.NewReader(root)
+		if cfg.SkipFiles != nil {
+			reader.SetSkipFiles(cfg.SkipFiles)
+		}
+		var documents []document.FileInfo
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			documents, err = reader.ReadDocumentsUnder(path)
+		} else if err == nil {
+			var doc document.FileInfo
+			doc, err = reader.ReadDocument(path)
+			documents = []document.FileInfo{doc}
+		}
+		if err != nil {
+			return ingestToolError("Ingest failed: %v", err), IngestPathOutput{}, err
+		}
+
+		upserter, err := processor.NewUpserter(cfg, store)
+		if err != nil {
+			return ingestToolError("Ingest failed: %v", err), IngestPathOutput{}, err
+		}
+		records := 0
+		report := progressReporter(ctx, req, fmt.Sprintf("Ingesting %s", input.Path))
+		upserter.SetProgress(func(processed, total int) {
+			records = processed
+			if report != nil {
+				report(processed, total)
+			}
+		})
+		if len(documents) > 0 {
+			if err := upserter.UpsertAllDocuments(ctx, documents); err != nil {
+				return ingestToolError("Ingest failed: %v", err), IngestPathOutput{}, err
+			}
+			// Drop chunks of an earlier version that were not written again
+			if _, err := upserter.RemoveStale(ctx); err != nil {
+				return ingestToolError("Ingest failed: %v", err), IngestPathOutput{}, err
+			}
+		}
+
+		output := IngestPathOutput{
+			Root:    root,
+			Files:   len(documents),
+			Records: records,
+			Sources: make([]string, len(documents)),
+		}
+		for i, doc := range documents {
+			output.Sources[i] = doc.RelativePath
+		}
+		sort.Strings(output.Sources)
+
+		refreshCatalog(ctx, catalog)
+
+		if debug {
+			log.Printf("Ingested %s: %d files, %d records", path, output.Files, output.Records)
+		}
+
+		return nil, output, nil
+	})
+}
+
+// addIngestTextTool adds the ingest text tool to the MCP server
+func addIngestTextTool(server *mcp.Server, cfg *config.Config, store vector.VectorStore, catalog *resourceCatalog) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "ingest_text",
+		Description: "Save a note into the vector index so it can be searched later. Ingesting a note with the same title and namespace replaces it.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input IngestTextInput) (
+		*mcp.CallToolResult,
+		IngestTextOutput,
+		error,
+	) {
+		if strings.TrimSpace(input.Title) == "" || strings.TrimSpace(input.Text) == "" {
+			err := fmt.Errorf("title and text are required")
+			return ingestToolError("Ingest failed: %v", err), IngestTextOutput{}, err
+		}
+		namespace := input.Namespace
+		if namespace == "" {
+			namespace = defaultNotesNamespace
+		}
+		if strings.ContainsAny(namespace, `/\`) || strings.HasPrefix(namespace, ".") {
+			err := fmt.Errorf("invalid namespace '%s'", namespace)
+			return ingestToolError("Ingest failed: %v", err), IngestTextOutput{}, err
+		}
+
+		// The note is stored like a Markdown file in a folder named after the
+		// namespace, which is how the upserter derives namespaces
+		source := path.Join(namespace, noteSlug(input.Title)+".md")
+		content := fmt.Sprintf("# %s\n\n%s", strings.TrimSpace(input.Title), strings.TrimSpace(input.Text))
+		doc := document.FileInfo{
+			Path:         source,
+			RelativePath: source,
+			Topic:        namespace,
+			Extension:    ".md",
+			Content:      content,
+			Size:         int64(len(content)),
+		}
+		if len(input.Tags) > 0 {
+			doc.Metadata = document.Metadata{"tags": input.Tags}
+		}
+
+		upserter, err := processor.NewUpserter(cfg, store)
+		if err != nil {
+			return ingestToolError("Ingest failed: %v", err), IngestTextOutput{}, err
+		}
+		records := 0
+		upserter.SetProgress(func(processed, total int) { records = processed })
+		if err := upserter.UpsertAllDocuments(ctx, []document.FileInfo{doc}); err != nil {
+			return ingestToolError("Ingest failed: %v", err), IngestTextOutput{}, err
+		}
+		// Drop the chunks of an earlier version, which may have had more
+		// sections, only once the new version is stored
+		if _, err := upserter.RemoveStale(ctx); err != nil {
+			return ingestToolError("Ingest failed: %v", err), IngestTextOutput{}, err
+		}
+
+		refreshCatalog(ctx, catalog)
+
+		if debug {
+			log.Printf("Ingested note %s: %d records", source, records)
+		}
+
+		return nil, IngestTextOutput{Source: source, Namespace: namespace, Records: records}, nil
+	})
+}
+
+// addRemoveSourceTool adds the remove source tool to the MCP server
+func addRemoveSourceTool(server *mcp.Server, cfg *config.Config, store vector.VectorStore, catalog *resourceCatalog) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "remove_source",
+		Description: "Delete every record ingested from a file or note, identified by its source_file metadata",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input RemoveSourceInput) (
+		*mcp.CallToolResult,
+		RemoveSourceOutput,
+		error,
+	) {
+		if input.Source == "" {
+			err := fmt.Errorf("source is required")
+			return ingestToolError("Remove failed: %v", err), RemoveSourceOutput{}, err
+		}
+		upserter, err := processor.NewUpserter(cfg, store)
+		if err != nil {
+			return ingestToolError("Remove failed: %v", err), RemoveSourceOutput{}, err
+		}
+		deleted, err := upserter.RemoveSource(ctx, input.Source)
+		if err != nil {
+			return ingestToolError("Remove failed: %v", err), RemoveSourceOutput{}, err
+		}
+
+		refreshCatalog(ctx, catalog)
+
+		if debug {
+			log.Printf("Removed %d records of %s", deleted, input.Source)
+		}
+
+		return nil, RemoveSourceOutput{Source: input.Source, Deleted: deleted}, nil
+	})
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// failingStore fails every upsert while fail is set
+type failingStore struct {
+	vector.VectorStore
+	fail bool
+}
+
+func (s *failingStore) UpsertBatch(ctx context.Context, documents []vector.Document, namespace string) error {
+	if s.fail {
+		return errors.New("upsert failed")
+	}
+	return s.VectorStore.UpsertBatch(ctx, documents, namespace)
+}
+
+// newIngestSession serves the ingest tools backed by a fake index whose
+// upserts can be made to fail
+func newIngestSession(t *testing.T, roots ...string) (*mcp.ClientSession, *failingStore) {
+	t.Helper()
+	store := &failingStore{VectorStore: newFakeClient(t)}
+	resolved, err := ingestRoots(roots)
+	if err != nil {
+		t.Fatalf("ingestRoots: %v", err)
+	}
+	cfg := &config.Config{BatchSize: 10}
+	session := serveTestTools(t, store, func(server *mcp.Server, store vector.VectorStore) {
+		addIngestPathTool(server, cfg, store, resolved, nil)
+		addIngestTextTool(server, cfg, store, nil)
+	})
+	return session, store
+}
+
+// sourceRecords returns the sorted IDs of the records of a source file
+func sourceRecords(t *testing.T, store vector.VectorStore, source string) []string {
+	t.Helper()
+	page, err := store.Range(context.Background(), vector.NamespaceForPath(source), vector.RangeRequest{Limit: 1000, IncludeMetadata: true})
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	var ids []string
+	for _, record := range page.Records {
+		if document.Metadata(record.Metadata).String("source_file") == source {
+			ids = append(ids, record.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestIngestTextReplacesNote(t *testing.T) {
+	session, store := newIngestSession(t)
+	long := "Intro.\n\n## Build\n\nRun make.\n\n## Deploy\n\nRun the pipeline.\n\n## Verify\n\nCheck the dashboard.\n"
+
+	var first IngestTextOutput
+	if result := callTool(t, session, "ingest_text", map[string]interface{}{"title": "Deploy checklist", "text": long}, &first); result.IsError {
+		t.Fatalf("ingest_text failed: %+v", result.Content)
+	}
+	if first.Source != "notes/deploy-checklist.md" || first.Namespace != "notes" {
+		t.Fatalf("unexpected note source %+v", first)
+	}
+	before := sourceRecords(t, store, first.Source)
+	if len(before) < 2 {
+		t.Fatalf("expected several chunks, got %v", before)
+	}
+
+	// A failed upsert keeps the stored version
+	store.fail = true
+	if result := callTool(t, session, "ingest_text", map[string]interface{}{"title": "Deploy checklist", "text": "Short."}, nil); !result.IsError {
+		t.Fatal("expected the ingest to fail")
+	}
+	if got := sourceRecords(t, store, first.Source); len(got) != len(before) {
+		t.Fatalf("failed ingest changed the note: %v, was %v", got, before)
+	}
+
+	// A shorter version replaces every chunk of the longer one
+	store.fail = false
+	var second IngestTextOutput
+	if result := callTool(t, session, "ingest_text", map[string]interface{}{"title": "Deploy checklist", "text": "Short."}, &second); result.IsError {
+		t.Fatalf("ingest_text failed: %+v", result.Content)
+	}
+	if got := sourceRecords(t, store, first.Source); len(got) != second.Records {
+		t.Errorf("expected only the %d new records, got %v", second.Records, got)
+	}
+}
+
+func TestIngestPathRemovesStaleChunks(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "guides", "setup.md")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("# Setup\n\nIntro.\n\n## Install\n\nRun go install.\n\n## Configure\n\nEdit the config.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session, store := newIngestSession(t, root)
+
+	var output IngestPathOutput
+	if result := callTool(t, session, "ingest_path", map[string]interface{}{"path": "guides"}, &output); result.IsError {
+		t.Fatalf("ingest_path failed: %+v", result.Content)
+	}
+	source := filepath.Join("guides", "setup.md")
+	if got := sourceRecords(t, store, source); len(got) != output.Records || len(got) < 2 {
+		t.Fatalf("expected %d records of %s, got %v", output.Records, source, got)
+	}
+
+	if err := os.WriteFile(file, []byte("# Setup\n\nRun go install.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if result := callTool(t, session, "ingest_path", map[string]interface{}{"path": "guides/setup.md"}, &output); result.IsError {
+		t.Fatalf("ingest_path failed: %+v", result.Content)
+	}
+	if got := sourceRecords(t, store, source); len(got) != output.Records {
+		t.Errorf("expected only the %d new records, got %v", output.Records, got)
+	}
+}
+
+func TestIngestPathSkipsSymlinks(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	secret := filepath.Join(outside, "secret.md")
+	if err := os.WriteFile(secret, []byte("# Secret\n\nDo not ingest.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	docs := filepath.Join(root, "docs")
+	if err := os.MkdirAll(docs, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(docs, "readme.md"), []byte("# Readme\n\nHello.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(docs, "linked.md")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	session, _ := newIngestSession(t, root)
+
+	var output IngestPathOutput
+	if result := callTool(t, session, "ingest_path", map[string]interface{}{"path": "docs"}, &output); result.IsError {
+		t.Fatalf("ingest_path failed: %+v", result.Content)
+	}
+	if len(output.Sources) != 1 || output.Sources[0] != filepath.Join("docs", "readme.md") {
+		t.Errorf("expected only docs/readme.md, got %v", output.Sources)
+	}
+
+	if result := callTool(t, session, "ingest_path", map[string]interface{}{"path": "docs/linked.md"}, nil); !result.IsError {
+		t.Error("expected a link to a file outside the roots to be rejected")
+	}
+}
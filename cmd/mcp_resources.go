@@ -2,8 +2,14 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/search"
 )
 
 // addHelpResource adds a help resource to the MCP server
@@ -25,14 +31,28 @@ Query documents using natural language semantic search.
 - query (required): Natural language query to search for
 - topK (optional): Maximum number of results (default: 5)
 - namespace (optional): Namespace to search within
+- filter (optional): Metadata filter expression
+- where (optional): Structured filter, see below
 - includeMetadata (optional): Include document metadata in results
 - includeData (optional): Include document content in results
+- includeParent (optional): Attach the enclosing section or file outline of each result
+- fusion (optional): Hybrid indexes only - RRF (default) or DBSF score fusion
+- sparseWeighting (optional): Hybrid indexes only - IDF (default) or NONE weighting of sparse terms
+- expand (optional): Add this many neighboring chunks on each side of each hit.
+  Hits of the same file that overlap or touch are merged into one result,
+  listing the merged hit IDs in hits and the chunk indexes in chunks
+- tokenBudget (optional): Keep the best results whose content fits this many
+  estimated tokens; a passage that does not fit falls back to its best hit.
+  expand and tokenBudget always include document content
 
 ### metadata_query
-Query documents using metadata filters.
-- filter (optional): Metadata filter expression
-- topK (optional): Maximum number of results (default: 10)
-- namespace (optional): Namespace to search within
+List documents matching a metadata filter, without a query vector.
+- filter (optional): Metadata filter expression (empty matches everything)
+- where (optional): Structured filter, see below
+- topK (optional): Maximum number of results per page (default: 10)
+- cursor (optional): nextCursor from the previous page
+- orderBy (optional): Keys to sort by, "-key" for descending (default: source_file, chunk_index)
+- namespace, namespaces (optional): Namespaces to search within
 - includeMetadata (optional): Include document metadata in results
 - includeData (optional): Include document content in results
 
@@ -47,11 +67,47 @@ Retrieve a specific document by ID.
 - includeMetadata (optional): Include document metadata in result
 - includeData (optional): Include document content in result
 
+### get_file
+Reassemble a whole file from its chunks, in chunk_index order.
+- path (required): source_file of the file, e.g. api/main.go
+- namespace (optional): Namespace holding the file (default: derived from the path)
+The result reports complete=false, with the missing chunk indexes, when the
+index holds fewer chunks than total_chunks recorded at ingestion.
+
+### ingest_path, ingest_text, remove_source
+Only available when the server was started with --ingest-root.
+- ingest_path: path (required) - file or folder under an allowed root
+- ingest_text: title and text (required), tags, namespace (default: notes)
+- remove_source: source (required) - source_file of the records to delete
+Long ingests send progress notifications when the request has a progress token.
+
+## Structured Filters
+
+vector_query and metadata_query accept a structured filter in where, combined
+with filter using AND. A condition is {field, op, value}; a group is
+{and: [...]} or {or: [...]}, and groups nest:
+  {"and": [{"field": "extension", "op": "=", "value": ".go"},
+           {"field": "tags", "op": "contains", "value": "auth"}]}
+Operators: =, !=, <, <=, >, >=, glob, not glob, in, not in, contains,
+not contains, exists, not exists. Fields must be metadata fields listed
+below, numbers are given without quotes, in takes a list, and string arrays
+(tags, imports, ...) use contains. Invalid filters are rejected with the
+reason; results echo the compiled filter.
+
+## Resources
+
+- vector://file/{path}: a file reassembled from its chunks
+- vector://recipe/{name}: the file list and README of a recipe
+- vector://{namespace}/doc/{id}: a single record by ID
+
 ## Usage Examples
 
 1. Search for Go Fiber examples:
    vector_query(query="Go Fiber routing examples", topK=3)
 
+   With surrounding code, packed into about 2000 tokens:
+   vector_query(query="Go Fiber routing examples", topK=5, expand=1, tokenBudget=2000)
+
 2. Filter by project type:
    metadata_query(filter="project_type = 'go-fiber-recipes'")
 
@@ -61,6 +117,9 @@ Retrieve a specific document by ID.
 4. Get specific document:
    get_document(id="doc-123", includeData=true)
 
+5. Read a whole file:
+   get_file(path="go-fiber-recipes/404-handler/main.go")
+
 ## Metadata Fields
 
 Documents contain the following metadata fields:
@@ -73,6 +132,14 @@ Documents contain the following metadata fields:
 - extension: File extension
 - chunk_index: Chunk number in document
 - total_chunks: Total chunks in document
+- start_line, end_line: 1-based line range of the chunk in its file
+- start_byte, end_byte: Byte offsets of the chunk in its file
+
+When ingested with parent records, chunks carry parent_id and file_id
+metadata and parent records have level "file" or "section".
+
+Results include a citation such as "go-fiber-recipes/404-handler/main.go:12-30"
+pointing at the lines the chunk came from.
 
 ## Tips
 
@@ -91,3 +158,217 @@ Documents contain the following metadata fields:
 		}, nil
 	})
 }
+
+// maxCatalogFiles caps the file resources listed, so a huge index does not
+// flood clients; every file stays readable through the template
+const maxCatalogFiles = 1000
+
+// addDocumentResourceTemplates adds templates for reading single records,
+// whole files and recipes from the index
+func addDocumentResourceTemplates(server *mcp.Server, searcher *search.Service) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "vector://{namespace}/doc/{id}",
+		Name:        "Indexed record",
+		Description: "A single record (chunk or parent outline) by namespace and ID",
+		MIMEType:    "text/plain",
+	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		namespace, id, ok := parseDocURI(req.Params.URI)
+		if !ok {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+		doc, err := searcher.Get(ctx, namespace, id, false, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", req.Params.URI, err)
+		}
+		if doc == nil {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+		return textResource(req.Params.URI, doc.Data), nil
+	})
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "vector://file/{+path}",
+		Name:        "Indexed file",
+		Description: "A file reassembled from its chunks, in order, by its source path",
+		MIMEType:    "text/plain",
+	}, readFileResource(searcher))
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "vector://recipe/{name}",
+		Name:        "Recipe",
+		Description: "The file list and README of a recipe folder",
+		MIMEType:    "text/markdown",
+	}, readRecipeResource(searcher))
+}
+
+// parseDocURI splits vector://{namespace}/doc/{id}
+func parseDocURI(uri string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(uri, "vector://")
+	if !ok {
+		return "", "", false
+	}
+	namespace, id, ok := strings.Cut(rest, "/doc/")
+	if !ok || namespace == "" || id == "" {
+		return "", "", false
+	}
+	return resourcePath(namespace, ""), resourcePath(id, ""), true
+}
+
+// resourceURI appends path to prefix with every segment percent-encoded, so
+// names containing % or control characters still form a valid URI
+func resourceURI(prefix, path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return prefix + strings.Join(segments, "/")
+}
+
+// resourcePath returns the part of a URI after prefix, decoding the segments
+// encoded by resourceURI. A segment that is not valid encoding is kept as is.
+func resourcePath(uri, prefix string) string {
+	segments := strings.Split(strings.TrimPrefix(uri, prefix), "/")
+	for i, segment := range segments {
+		if decoded, err := url.PathUnescape(segment); err == nil {
+			segments[i] = decoded
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func textResource(uri, text string) *mcp.ReadResourceResult {
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{URI: uri, Text: text}},
+	}
+}
+
+// formatRecipe renders a recipe as Markdown: file list, then README
+func formatRecipe(recipe *search.Recipe) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Recipe: %s\n\nNamespace: %s\n\n## Files\n\n", recipe.Name, recipe.Namespace)
+	for _, file := range recipe.Files {
+		fmt.Fprintf(&b, "- %s (%d chunks)\n", resourceURI("vector://file/", file.Path), file.Chunks)
+	}
+	if recipe.Readme != "" {
+		fmt.Fprintf(&b, "\n## README\n\n%s\n", recipe.Readme)
+	}
+	return b.String()
+}
+
+// resourceCatalog lists indexed files and recipes as concrete resources, so
+// clients can browse them with resources/list. Templates cover the rest.
+type resourceCatalog struct {
+	server   *mcp.Server
+	searcher *search.Service
+
+	mu   sync.Mutex
+	uris map[string]bool
+}
+
+func newResourceCatalog(server *mcp.Server, searcher *search.Service) *resourceCatalog {
+	return &resourceCatalog{server: server, searcher: searcher, uris: make(map[string]bool)}
+}
+
+// Refresh rescans the index, adding resources for new files and recipes and
+// removing those that are gone. Clients are notified of the changes.
+func (c *resourceCatalog) Refresh(ctx context.Context) error {
+	sources, err := c.searcher.ListSources(ctx)
+	if err != nil {
+		return err
+	}
+	if len(sources) > maxCatalogFiles {
+		logger.LogWarning(fmt.Sprintf("Listing %d of %d indexed files as MCP resources", maxCatalogFiles, len(sources)))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := make(map[string]bool)
+	recipes := make(map[string]int)
+	for i, source := range sources {
+		if source.RecipeName != "" {
+			recipes[source.RecipeName]++
+		}
+		if i >= maxCatalogFiles {
+			continue
+		}
+		uri := resourceURI("vector://file/", source.Path)
+		if !validResourceURI(uri) {
+			continue
+		}
+		current[uri] = true
+		if !c.uris[uri] {
+			c.server.AddResource(&mcp.Resource{
+				URI:         uri,
+				Name:        source.Path,
+				Description: fmt.Sprintf("Indexed file in namespace %s (%d chunks)", source.Namespace, source.Chunks),
+				MIMEType:    "text/plain",
+			}, readFileResource(c.searcher))
+		}
+	}
+	for name, files := range recipes {
+		uri := "vector://recipe/" + url.PathEscape(name)
+		if !validResourceURI(uri) {
+			continue
+		}
+		current[uri] = true
+		if !c.uris[uri] {
+			c.server.AddResource(&mcp.Resource{
+				URI:         uri,
+				Name:        "Recipe " + name,
+				Description: fmt.Sprintf("File list and README of recipe %s (%d files)", name, files),
+				MIMEType:    "text/markdown",
+			}, readRecipeResource(c.searcher))
+		}
+	}
+
+	var removed []string
+	for uri := range c.uris {
+		if !current[uri] {
+			removed = append(removed, uri)
+		}
+	}
+	if len(removed) > 0 {
+		c.server.RemoveResources(removed...)
+	}
+	c.uris = current
+	return nil
+}
+
+// validResourceURI reports whether the server accepts uri; AddResource panics
+// on URIs that do not parse, so those are logged and left out of the list
+func validResourceURI(uri string) bool {
+	if _, err := url.Parse(uri); err != nil {
+		logger.LogWarning(fmt.Sprintf("Skipping MCP resource: %v", err))
+		return false
+	}
+	return true
+}
+
+// readFileResource serves vector://file/{path} URIs
+func readFileResource(searcher *search.Service) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		file, err := searcher.GetFile(ctx, "", resourcePath(req.Params.URI, "vector://file/"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", req.Params.URI, err)
+		}
+		if file == nil {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+		return textResource(req.Params.URI, file.Content), nil
+	}
+}
+
+// readRecipeResource serves vector://recipe/{name} URIs
+func readRecipeResource(searcher *search.Service) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		recipe, err := searcher.GetRecipe(ctx, resourcePath(req.Params.URI, "vector://recipe/"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", req.Params.URI, err)
+		}
+		if recipe == nil {
+			return nil, mcp.ResourceNotFoundError(req.Params.URI)
+		}
+		return textResource(req.Params.URI, formatRecipe(recipe)), nil
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/search"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+func TestResourceURIRoundTrip(t *testing.T) {
+	for _, path := range []string{"api/main.go", "docs/100%.md", "docs/a%20b.go", "odd/tab\tname.go", "with space/é.md"} {
+		uri := resourceURI("vector://file/", path)
+		if got := resourcePath(uri, "vector://file/"); got != path {
+			t.Errorf("%q encoded as %s decodes to %q", path, uri, got)
+		}
+	}
+}
+
+func TestResourceCatalogEscapesPaths(t *testing.T) {
+	client := newFakeClient(t)
+	ctx := context.Background()
+	paths := []string{"docs/100%.md", "docs/a%20b.go", "docs/ctrl\x01.md"}
+	var files []document.FileInfo
+	for _, path := range paths {
+		content := "# " + path + "\n\nBody of " + path + ".\n"
+		files = append(files, document.FileInfo{Path: path, RelativePath: path, Extension: ".md", Content: content, Size: int64(len(content))})
+	}
+	if err := vector.NewUpserter(client, 10).UpsertAllDocuments(ctx, files); err != nil {
+		t.Fatalf("UpsertAllDocuments: %v", err)
+	}
+
+	var catalog *resourceCatalog
+	session := serveTestTools(t, client, func(server *mcp.Server, store vector.VectorStore) {
+		searcher := search.NewService(store)
+		addDocumentResourceTemplates(server, searcher)
+		catalog = newResourceCatalog(server, searcher)
+	})
+	if err := catalog.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	listed, err := session.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	uris := make(map[string]bool)
+	for _, resource := range listed.Resources {
+		uris[resource.URI] = true
+	}
+	for _, path := range paths {
+		uri := resourceURI("vector://file/", path)
+		if !uris[uri] {
+			t.Errorf("%s is not listed", uri)
+			continue
+		}
+		result, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+		if err != nil {
+			t.Errorf("ReadResource(%s): %v", uri, err)
+			continue
+		}
+		if want := "Body of " + path + "."; len(result.Contents) != 1 || !strings.Contains(result.Contents[0].Text, want) {
+			t.Errorf("%s returned the wrong file: %+v", uri, result.Contents)
+		}
+	}
+}
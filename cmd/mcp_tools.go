@@ -2,44 +2,61 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
-	"github.com/upstash/vector-go"
+	"github.com/typicalfo/prj-start/search"
+	"github.com/typicalfo/prj-start/vector"
 )
 
 // VectorQueryInput represents input for vector query tool
 type VectorQueryInput struct {
-	Query           string `json:"query" jsonschema:"natural language query to search for"`
-	TopK            int    `json:"topK,omitempty" jsonschema:"maximum number of results to return"`
-	Namespace       string `json:"namespace,omitempty" jsonschema:"namespace to search within"`
-	IncludeMetadata bool   `json:"includeMetadata,omitempty" jsonschema:"include document metadata in results"`
-	IncludeData     bool   `json:"includeData,omitempty" jsonschema:"include document content in results"`
+	Query           string                 `json:"query" jsonschema:"natural language query to search for"`
+	TopK            int                    `json:"topK,omitempty" jsonschema:"maximum number of results to return"`
+	Namespace       string                 `json:"namespace,omitempty" jsonschema:"namespace to search within"`
+	Namespaces      []string               `json:"namespaces,omitempty" jsonschema:"several namespaces to search, or [\"*\"] for all"`
+	Filter          string                 `json:"filter,omitempty" jsonschema:"metadata filter expression applied to the search"`
+	Where           map[string]interface{} `json:"where,omitempty" jsonschema:"structured filter validated against the metadata schema: {field, op, value} or {and: [...]} / {or: [...]}, combined with filter using AND"`
+	MinScore        float64                `json:"minScore,omitempty" jsonschema:"drop results scoring below this threshold"`
+	IncludeMetadata bool                   `json:"includeMetadata,omitempty" jsonschema:"include document metadata in results"`
+	IncludeData     bool                   `json:"includeData,omitempty" jsonschema:"include document content in results"`
+	IncludeParent   bool                   `json:"includeParent,omitempty" jsonschema:"attach the enclosing section or file outline of each result"`
+	Fusion          string                 `json:"fusion,omitempty" jsonschema:"hybrid indexes: how dense and sparse scores are fused, RRF (default) or DBSF"`
+	SparseWeighting string                 `json:"sparseWeighting,omitempty" jsonschema:"hybrid indexes: weighting of sparse query terms, IDF (default) or NONE"`
+	Expand          int                    `json:"expand,omitempty" jsonschema:"add this many neighboring chunks on each side of a hit and merge hits of the same file"`
+	TokenBudget     int                    `json:"tokenBudget,omitempty" jsonschema:"return the best results whose content fits this many estimated tokens"`
 }
 
 // VectorQueryOutput represents output for vector query tool
 type VectorQueryOutput struct {
-	Results []QueryResult `json:"results"`
-	Query   string        `json:"query"`
-	Count   int           `json:"count"`
-}
-
-// QueryResult represents a single query result
-type QueryResult struct {
-	ID       string                 `json:"id"`
-	Score    float64                `json:"score"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
-	Data     string                 `json:"data,omitempty"`
+	Results []search.Result `json:"results"`
+	Query   string          `json:"query"`
+	Filter  string          `json:"filter,omitempty"`
+	Count   int             `json:"count"`
 }
 
 // MetadataQueryInput represents input for metadata query tool
 type MetadataQueryInput struct {
-	Filter          string `json:"filter,omitempty" jsonschema:"metadata filter expression"`
-	TopK            int    `json:"topK,omitempty" jsonschema:"maximum number of results to return"`
-	Namespace       string `json:"namespace,omitempty" jsonschema:"namespace to search within"`
-	IncludeMetadata bool   `json:"includeMetadata,omitempty" jsonschema:"include document metadata in results"`
-	IncludeData     bool   `json:"includeData,omitempty" jsonschema:"include document content in results"`
+	Filter          string                 `json:"filter,omitempty" jsonschema:"metadata filter expression, e.g. source_file = 'api/main.go' (empty matches every record)"`
+	Where           map[string]interface{} `json:"where,omitempty" jsonschema:"structured filter validated against the metadata schema: {field, op, value} or {and: [...]} / {or: [...]}, combined with filter using AND"`
+	TopK            int                    `json:"topK,omitempty" jsonschema:"maximum number of results per page (default: 10)"`
+	Cursor          string                 `json:"cursor,omitempty" jsonschema:"nextCursor of the previous page"`
+	OrderBy         []string               `json:"orderBy,omitempty" jsonschema:"metadata keys to sort by, prefix with - for descending (default: source_file, chunk_index)"`
+	Namespace       string                 `json:"namespace,omitempty" jsonschema:"namespace to search within"`
+	Namespaces      []string               `json:"namespaces,omitempty" jsonschema:"several namespaces to search, or [\"*\"] for all"`
+	IncludeMetadata bool                   `json:"includeMetadata,omitempty" jsonschema:"include document metadata in results"`
+	IncludeData     bool                   `json:"includeData,omitempty" jsonschema:"include document content in results"`
+}
+
+// MetadataQueryOutput represents output for metadata query tool
+type MetadataQueryOutput struct {
+	Results    []search.Result `json:"results"`
+	Filter     string          `json:"filter"`
+	Count      int             `json:"count"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"nextCursor,omitempty"`
 }
 
 // ListNamespacesInput represents input for list namespaces tool
@@ -64,46 +81,74 @@ type GetDocumentInput struct {
 // GetDocumentOutput represents output for get document tool
 type GetDocumentOutput struct {
 	ID       string                 `json:"id"`
+	Citation string                 `json:"citation,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 	Data     string                 `json:"data,omitempty"`
 }
 
+// GetFileInput represents input for get file tool
+type GetFileInput struct {
+	Path      string `json:"path" jsonschema:"source_file of the file, e.g. api/main.go"`
+	Namespace string `json:"namespace,omitempty" jsonschema:"namespace holding the file (default: derived from the path)"`
+}
+
+// compileToolFilter validates a tool's structured filter and combines it with
+// its filter expression
+func compileToolFilter(searcher *search.Service, filter string, where map[string]interface{}) (string, error) {
+	if len(where) == 0 {
+		return filter, nil
+	}
+	data, err := json.Marshal(where)
+	if err != nil {
+		return "", fmt.Errorf("invalid structured filter: %w", err)
+	}
+	spec, err := vector.ParseFilterSpec(data)
+	if err != nil {
+		return "", err
+	}
+	return searcher.CompileFilter(filter, spec)
+}
+
+// namespacesFor combines the single and multi-namespace tool inputs
+func namespacesFor(namespace string, namespaces []string) []string {
+	if len(namespaces) > 0 {
+		return namespaces
+	}
+	return []string{namespace}
+}
+
 // addVectorQueryTool adds the vector query tool to the MCP server
-func addVectorQueryTool(server *mcp.Server, vectorClient *vector.Index) {
+func addVectorQueryTool(server *mcp.Server, searcher *search.Service) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "vector_query",
-		Description: "Query documents using natural language semantic search",
+		Description: "Query documents using natural language semantic search. Set expand and tokenBudget to get whole passages around the hits, packed to fit your context.",
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input VectorQueryInput) (
 		*mcp.CallToolResult,
 		VectorQueryOutput,
 		error,
 	) {
-		// Set defaults
-		if input.TopK <= 0 {
-			input.TopK = 5
-		}
-
-		// Perform query using raw data (automatic embedding)
-		var results []vector.VectorScore
-		var err error
-
-		if input.Namespace != "" {
-			namespaceClient := vectorClient.Namespace(input.Namespace)
-			results, err = namespaceClient.QueryData(vector.QueryData{
-				Data:            input.Query,
-				TopK:            input.TopK,
-				IncludeMetadata: input.IncludeMetadata,
-				IncludeData:     input.IncludeData,
-			})
-		} else {
-			results, err = vectorClient.QueryData(vector.QueryData{
-				Data:            input.Query,
-				TopK:            input.TopK,
-				IncludeMetadata: input.IncludeMetadata,
-				IncludeData:     input.IncludeData,
-			})
+		filter, err := compileToolFilter(searcher, input.Filter, input.Where)
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Invalid filter: %v", err)}},
+				IsError: true,
+			}, VectorQueryOutput{}, err
 		}
 
+		results, err := searcher.Search(ctx, search.Request{
+			Query:           input.Query,
+			Filter:          filter,
+			Namespaces:      namespacesFor(input.Namespace, input.Namespaces),
+			TopK:            input.TopK,
+			MinScore:        input.MinScore,
+			IncludeMetadata: input.IncludeMetadata,
+			IncludeData:     input.IncludeData,
+			IncludeParent:   input.IncludeParent,
+			Fusion:          input.Fusion,
+			SparseWeighting: input.SparseWeighting,
+			Expand:          input.Expand,
+			TokenBudget:     input.TokenBudget,
+		})
 		if err != nil {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Query failed: %v", err)}},
@@ -111,25 +156,15 @@ func addVectorQueryTool(server *mcp.Server, vectorClient *vector.Index) {
 			}, VectorQueryOutput{}, err
 		}
 
-		// Convert results
-		queryResults := make([]QueryResult, len(results))
-		for i, result := range results {
-			queryResults[i] = QueryResult{
-				ID:       result.Id,
-				Score:    float64(result.Score),
-				Metadata: result.Metadata,
-				Data:     result.Data,
-			}
-		}
-
 		output := VectorQueryOutput{
-			Results: queryResults,
+			Results: results,
 			Query:   input.Query,
-			Count:   len(queryResults),
+			Filter:  filter,
+			Count:   len(results),
 		}
 
 		if debug {
-			log.Printf("Vector query: %s returned %d results", input.Query, len(queryResults))
+			log.Printf("Vector query: %s returned %d results", input.Query, len(results))
 		}
 
 		return nil, output, nil
@@ -137,87 +172,49 @@ func addVectorQueryTool(server *mcp.Server, vectorClient *vector.Index) {
 }
 
 // addMetadataQueryTool adds the metadata filter query tool to the MCP server
-func addMetadataQueryTool(server *mcp.Server, vectorClient *vector.Index) {
+func addMetadataQueryTool(server *mcp.Server, searcher *search.Service) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "metadata_query",
-		Description: "Query documents using metadata filters",
+		Description: "List documents matching a metadata filter, without semantic ranking. Results are sorted deterministically and paged with nextCursor.",
 	}, func(ctx context.Context, req *mcp.CallToolRequest, input MetadataQueryInput) (
 		*mcp.CallToolResult,
-		VectorQueryOutput,
+		MetadataQueryOutput,
 		error,
 	) {
-		// Set defaults
-		if input.TopK <= 0 {
-			input.TopK = 10
-		}
-
-		// Perform query with metadata filter
-		var results []vector.VectorScore
-		var err error
-
-		if input.Namespace != "" {
-			namespaceClient := vectorClient.Namespace(input.Namespace)
-			if input.Filter != "" {
-				results, err = namespaceClient.Query(vector.Query{
-					Vector:          []float32{0.0, 0.0}, // Dummy vector
-					TopK:            input.TopK,
-					IncludeMetadata: input.IncludeMetadata,
-					IncludeData:     input.IncludeData,
-					Filter:          input.Filter,
-				})
-			} else {
-				results, err = namespaceClient.Query(vector.Query{
-					Vector:          []float32{0.0, 0.0}, // Dummy vector
-					TopK:            input.TopK,
-					IncludeMetadata: input.IncludeMetadata,
-					IncludeData:     input.IncludeData,
-				})
-			}
-		} else {
-			if input.Filter != "" {
-				results, err = vectorClient.Query(vector.Query{
-					Vector:          []float32{0.0, 0.0}, // Dummy vector
-					TopK:            input.TopK,
-					IncludeMetadata: input.IncludeMetadata,
-					IncludeData:     input.IncludeData,
-					Filter:          input.Filter,
-				})
-			} else {
-				results, err = vectorClient.Query(vector.Query{
-					Vector:          []float32{0.0, 0.0}, // Dummy vector
-					TopK:            input.TopK,
-					IncludeMetadata: input.IncludeMetadata,
-					IncludeData:     input.IncludeData,
-				})
-			}
+		filter, err := compileToolFilter(searcher, input.Filter, input.Where)
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Invalid filter: %v", err)}},
+				IsError: true,
+			}, MetadataQueryOutput{}, err
 		}
 
+		response, err := searcher.QueryMetadata(ctx, search.MetadataRequest{
+			Filter:          filter,
+			Namespaces:      namespacesFor(input.Namespace, input.Namespaces),
+			Limit:           input.TopK,
+			Cursor:          input.Cursor,
+			OrderBy:         input.OrderBy,
+			IncludeMetadata: input.IncludeMetadata,
+			IncludeData:     input.IncludeData,
+		})
 		if err != nil {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Metadata query failed: %v", err)}},
 				IsError: true,
-			}, VectorQueryOutput{}, err
+			}, MetadataQueryOutput{}, err
 		}
 
-		// Convert results
-		queryResults := make([]QueryResult, len(results))
-		for i, result := range results {
-			queryResults[i] = QueryResult{
-				ID:       result.Id,
-				Score:    float64(result.Score),
-				Metadata: result.Metadata,
-				Data:     result.Data,
-			}
-		}
-
-		output := VectorQueryOutput{
-			Results: queryResults,
-			Query:   fmt.Sprintf("metadata filter: %s", input.Filter),
-			Count:   len(queryResults),
+		output := MetadataQueryOutput{
+			Results:    response.Results,
+			Filter:     filter,
+			Count:      len(response.Results),
+			Total:      response.Total,
+			NextCursor: response.NextCursor,
 		}
 
 		if debug {
-			log.Printf("Metadata query: %s returned %d results", input.Filter, len(queryResults))
+			log.Printf("Metadata query: %s matched %d of %d scanned records", filter, response.Total, response.Scanned)
 		}
 
 		return nil, output, nil
@@ -225,7 +222,7 @@ func addMetadataQueryTool(server *mcp.Server, vectorClient *vector.Index) {
 }
 
 // addListNamespacesTool adds the list namespaces tool to the MCP server
-func addListNamespacesTool(server *mcp.Server, vectorClient *vector.Index) {
+func addListNamespacesTool(server *mcp.Server, searcher *search.Service) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "list_namespaces",
 		Description: "List all available namespaces in the vector database",
@@ -234,7 +231,7 @@ func addListNamespacesTool(server *mcp.Server, vectorClient *vector.Index) {
 		ListNamespacesOutput,
 		error,
 	) {
-		namespaces, err := vectorClient.ListNamespaces()
+		namespaces, err := searcher.Store().ListNamespaces(ctx)
 		if err != nil {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Failed to list namespaces: %v", err)}},
@@ -256,7 +253,7 @@ func addListNamespacesTool(server *mcp.Server, vectorClient *vector.Index) {
 }
 
 // addGetDocumentTool adds the get document tool to the MCP server
-func addGetDocumentTool(server *mcp.Server, vectorClient *vector.Index) {
+func addGetDocumentTool(server *mcp.Server, searcher *search.Service) {
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "get_document",
 		Description: "Retrieve a specific document by ID",
@@ -266,24 +263,7 @@ func addGetDocumentTool(server *mcp.Server, vectorClient *vector.Index) {
 		error,
 	) {
 		// Fetch the document
-		var vectors []vector.Vector
-		var err error
-
-		if input.Namespace != "" {
-			namespaceClient := vectorClient.Namespace(input.Namespace)
-			vectors, err = namespaceClient.Fetch(vector.Fetch{
-				Ids:             []string{input.ID},
-				IncludeMetadata: input.IncludeMetadata,
-				IncludeData:     input.IncludeData,
-			})
-		} else {
-			vectors, err = vectorClient.Fetch(vector.Fetch{
-				Ids:             []string{input.ID},
-				IncludeMetadata: input.IncludeMetadata,
-				IncludeData:     input.IncludeData,
-			})
-		}
-
+		doc, err := searcher.Get(ctx, input.Namespace, input.ID, input.IncludeMetadata, input.IncludeData)
 		if err != nil {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Failed to fetch document: %v", err)}},
@@ -291,18 +271,18 @@ func addGetDocumentTool(server *mcp.Server, vectorClient *vector.Index) {
 			}, GetDocumentOutput{}, err
 		}
 
-		if len(vectors) == 0 {
+		if doc == nil {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Document with ID '%s' not found", input.ID)}},
 				IsError: true,
 			}, GetDocumentOutput{}, fmt.Errorf("document not found")
 		}
 
-		vector := vectors[0]
 		output := GetDocumentOutput{
-			ID:       vector.Id,
-			Metadata: vector.Metadata,
-			Data:     vector.Data,
+			ID:       doc.ID,
+			Citation: doc.Citation,
+			Metadata: doc.Metadata,
+			Data:     doc.Data,
 		}
 
 		if debug {
@@ -312,3 +292,36 @@ func addGetDocumentTool(server *mcp.Server, vectorClient *vector.Index) {
 		return nil, output, nil
 	})
 }
+
+// addGetFileTool adds the get file tool to the MCP server
+func addGetFileTool(server *mcp.Server, searcher *search.Service) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_file",
+		Description: "Reassemble a whole file from its indexed chunks and report whether the indexed copy is complete",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input GetFileInput) (
+		*mcp.CallToolResult,
+		search.File,
+		error,
+	) {
+		file, err := searcher.GetFile(ctx, input.Namespace, input.Path)
+		if err != nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Failed to fetch file: %v", err)}},
+				IsError: true,
+			}, search.File{}, err
+		}
+
+		if file == nil {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("File '%s' not found", input.Path)}},
+				IsError: true,
+			}, search.File{}, fmt.Errorf("file not found")
+		}
+
+		if debug {
+			log.Printf("Retrieved file: %s (%d of %d chunks)", file.Source, file.Chunks, file.TotalChunks)
+		}
+
+		return nil, *file, nil
+	})
+}
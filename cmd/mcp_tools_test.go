@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/search"
+	"github.com/typicalfo/prj-start/vector"
+	"github.com/typicalfo/prj-start/vectortest"
+)
+
+// newFakeClient returns an Upstash client talking to a fresh fake index
+func newFakeClient(t *testing.T) *vector.Client {
+	t.Helper()
+	logger.SetOutput(io.Discard)
+	srv, err := vectortest.NewServer(vectortest.Options{})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	client, err := vector.NewClient(srv.Config())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+// serveTestTools serves the tools added by register over an in-memory
+// transport, backed by store, and returns the connected client session
+func serveTestTools(t *testing.T, store vector.VectorStore, register func(server *mcp.Server, store vector.VectorStore)) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	server := createMCPServer()
+	register(server, store)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server Connect: %v", err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client Connect: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+// newTestSession serves the query tools backed by a fake index seeded with
+// one recipe file
+func newTestSession(t *testing.T) *mcp.ClientSession {
+	t.Helper()
+	client := newFakeClient(t)
+
+	content := "# 404 Handler\n\nServes a custom not found page.\n\n## Usage\n\nRun go run main.go and open /missing.\n"
+	upserter := vector.NewUpserter(client, 10)
+	err := upserter.UpsertAllDocuments(context.Background(), []document.FileInfo{{
+		Path:         "go-fiber-recipes/404-handler/README.md",
+		RelativePath: "go-fiber-recipes/404-handler/README.md",
+		Extension:    ".md",
+		Content:      content,
+		Size:         int64(len(content)),
+	}})
+	if err != nil {
+		t.Fatalf("UpsertAllDocuments: %v", err)
+	}
+
+	return serveTestTools(t, client, func(server *mcp.Server, store vector.VectorStore) {
+		searcher := search.NewService(store)
+		addVectorQueryTool(server, searcher)
+		addMetadataQueryTool(server, searcher)
+		addListNamespacesTool(server, searcher)
+		addGetDocumentTool(server, searcher)
+		addGetFileTool(server, searcher)
+	})
+}
+
+// callTool calls a tool and decodes its structured output into out
+func callTool(t *testing.T, session *mcp.ClientSession, name string, args map[string]interface{}, out interface{}) *mcp.CallToolResult {
+	t.Helper()
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if out != nil && !result.IsError {
+		data, err := json.Marshal(result.StructuredContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s: decoding output: %v", name, err)
+		}
+	}
+	return result
+}
+
+func TestVectorQueryTool(t *testing.T) {
+	session := newTestSession(t)
+
+	var output VectorQueryOutput
+	result := callTool(t, session, "vector_query", map[string]interface{}{
+		"query":       "custom not found page",
+		"namespace":   "404-handler",
+		"topK":        1,
+		"includeData": true,
+	}, &output)
+	if result.IsError {
+		t.Fatalf("vector_query failed: %v", result.Content)
+	}
+	if output.Count != 1 || output.Results[0].Citation == "" || output.Results[0].Data == "" {
+		t.Errorf("unexpected output: %+v", output)
+	}
+}
+
+func TestMetadataQueryToolWhere(t *testing.T) {
+	session := newTestSession(t)
+
+	var output MetadataQueryOutput
+	result := callTool(t, session, "metadata_query", map[string]interface{}{
+		"namespace": "404-handler",
+		"where":     map[string]interface{}{"field": "chunk_index", "op": ">=", "value": 0},
+	}, &output)
+	if result.IsError {
+		t.Fatalf("metadata_query failed: %v", result.Content)
+	}
+	if output.Filter != "chunk_index >= 0" || output.Total == 0 {
+		t.Errorf("unexpected output: %+v", output)
+	}
+
+	result = callTool(t, session, "metadata_query", map[string]interface{}{
+		"namespace": "404-handler",
+		"where":     map[string]interface{}{"field": "file_name", "op": "=", "value": "README.md"},
+	}, nil)
+	if !result.IsError {
+		t.Error("expected an unknown field to be rejected")
+	}
+}
+
+func TestMetadataQueryToolPages(t *testing.T) {
+	session := newTestSession(t)
+
+	var all MetadataQueryOutput
+	callTool(t, session, "metadata_query", map[string]interface{}{"namespace": "404-handler", "topK": 100}, &all)
+	if all.Total < 2 || all.NextCursor != "" {
+		t.Fatalf("expected several records on one page, got %+v", all)
+	}
+
+	var paged []string
+	cursor := ""
+	for page := 0; page <= all.Total; page++ {
+		args := map[string]interface{}{"namespace": "404-handler", "topK": 1, "orderBy": []string{"-chunk_index"}}
+		if cursor != "" {
+			args["cursor"] = cursor
+		}
+		var output MetadataQueryOutput
+		if result := callTool(t, session, "metadata_query", args, &output); result.IsError {
+			t.Fatalf("metadata_query failed: %v", result.Content)
+		}
+		if output.Count != 1 || output.Total != all.Total {
+			t.Fatalf("page %d: count %d, total %d", page, output.Count, output.Total)
+		}
+		paged = append(paged, output.Results[0].ID)
+		if output.NextCursor == "" {
+			break
+		}
+		cursor = output.NextCursor
+	}
+
+	if len(paged) != all.Total {
+		t.Fatalf("paged through %d records, want %d", len(paged), all.Total)
+	}
+	// Descending chunk_index reverses the default source_file, chunk_index order
+	for i, id := range paged {
+		if want := all.Results[len(all.Results)-1-i].ID; id != want {
+			t.Errorf("page %d returned %s, want %s", i, id, want)
+		}
+	}
+}
+
+func TestGetDocumentAndFileTools(t *testing.T) {
+	session := newTestSession(t)
+
+	var file search.File
+	result := callTool(t, session, "get_file", map[string]interface{}{"path": "go-fiber-recipes/404-handler/README.md"}, &file)
+	if result.IsError {
+		t.Fatalf("get_file failed: %v", result.Content)
+	}
+	if !file.Complete || file.Namespace != "404-handler" || file.Content == "" {
+		t.Errorf("unexpected file: %+v", file)
+	}
+
+	var doc GetDocumentOutput
+	result = callTool(t, session, "get_document", map[string]interface{}{
+		"id":          vector.DocumentID("go-fiber-recipes/404-handler/README.md", 0),
+		"namespace":   "404-handler",
+		"includeData": true,
+	}, &doc)
+	if result.IsError || doc.Data == "" {
+		t.Errorf("get_document failed: %+v %v", doc, result.Content)
+	}
+
+	result = callTool(t, session, "get_file", map[string]interface{}{"path": "missing.go"}, nil)
+	if !result.IsError {
+		t.Error("expected get_file to fail for a missing file")
+	}
+}
+
+func TestListNamespacesTool(t *testing.T) {
+	session := newTestSession(t)
+
+	var output ListNamespacesOutput
+	callTool(t, session, "list_namespaces", map[string]interface{}{}, &output)
+	found := false
+	for _, ns := range output.Namespaces {
+		found = found || ns == "404-handler"
+	}
+	if !found {
+		t.Errorf("404-handler missing from %v", output.Namespaces)
+	}
+}
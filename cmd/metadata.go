@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/processor"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// metadataCmd represents the metadata command
+var metadataCmd = &cobra.Command{
+	Use:   "metadata",
+	Short: "Inspect the metadata schema and migrate stored metadata",
+	Long: `Metadata keys have declared types: string, int, float, bool, string_array
+or object. The built-in keys are typed by default; declare your own, e.g.
+for plugin metadata, under metadata_schema in the config file:
+
+  metadata_schema:
+    priority: int
+    reviewed: bool
+    labels: string_array
+
+Typed values make numeric and array filters work, such as
+"file_size < 5000 AND imports CONTAINS 'net/http'".
+
+Examples:
+  prj-start metadata schema
+  prj-start metadata migrate --dry-run
+  prj-start metadata migrate --namespace handlers`,
+}
+
+var metadataSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the declared type of every metadata key",
+	Args:  cobra.NoArgs,
+	RunE:  runMetadataSchema,
+}
+
+var metadataMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Convert stored metadata to the declared types",
+	Long: `Rewrite the metadata of already-indexed records to match the schema, for
+example converting chunk_index "3" to 3 or dependencies "a,b" to ["a","b"]
+in indexes ingested before metadata was typed. Records keep their vectors
+and are not re-embedded; records that already match are left untouched.`,
+	Args: cobra.NoArgs,
+	RunE: runMetadataMigrate,
+}
+
+var (
+	metadataNamespaces []string
+	metadataDryRun     bool
+	metadataPageSize   int
+)
+
+func init() {
+	rootCmd.AddCommand(metadataCmd)
+	metadataCmd.AddCommand(metadataSchemaCmd)
+	metadataCmd.AddCommand(metadataMigrateCmd)
+
+	metadataMigrateCmd.Flags().StringSliceVarP(&metadataNamespaces, "namespace", "n", nil, "namespace to migrate, repeatable (default is all)")
+	metadataMigrateCmd.Flags().BoolVar(&metadataDryRun, "dry-run", false, "count the records that would change without writing")
+	metadataMigrateCmd.Flags().IntVar(&metadataPageSize, "page-size", 100, "records read and upserted per request")
+}
+
+func runMetadataSchema(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	schema, err := processor.MetadataSchema(cfg)
+	if err != nil {
+		return err
+	}
+	for _, key := range schema.Keys() {
+		fmt.Printf("%-24s %s\n", key, schema[key])
+	}
+	return nil
+}
+
+func runMetadataMigrate(cmd *cobra.Command, args []string) error {
+	cfg, store, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+	schema, err := processor.MetadataSchema(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	namespaces := metadataNamespaces
+	if len(namespaces) == 0 {
+		if namespaces, err = store.ListNamespaces(ctx); err != nil {
+			return fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		sort.Strings(namespaces)
+	}
+
+	fmt.Printf("%-32s %12s %12s\n", "NAMESPACE", "SCANNED", "UPDATED")
+	totalUpdated := 0
+	for _, namespace := range namespaces {
+		total, _ := namespaceVectorCount(ctx, store, namespace)
+		scanned, updated, err := vector.NormalizeNamespaceMetadata(ctx, store, namespace, schema, metadataPageSize, metadataDryRun, func(scanned, updated int) {
+			logger.LogProgress(scanned, total, fmt.Sprintf("Migrating %s", namespaceLabel(namespace)))
+		})
+		if err != nil {
+			return fmt.Errorf("namespace %s: %w", namespaceLabel(namespace), err)
+		}
+		fmt.Printf("%-32s %12d %12d\n", namespaceLabel(namespace), scanned, updated)
+		totalUpdated += updated
+	}
+
+	if metadataDryRun {
+		fmt.Printf("\n%d records would be updated (dry run)\n", totalUpdated)
+	} else {
+		fmt.Printf("\n%d records updated\n", totalUpdated)
+	}
+	return nil
+}
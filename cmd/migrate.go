@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/migrate"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Re-embed namespaces from one profile's index into another",
+	Long: `Copy the data and metadata of every namespace from the index of one
+profile into the index of another, letting the target embed the data again.
+Use it to move to a new embedding model, dimension or similarity function,
+or between Upstash and the local store. Records stored without data cannot
+be re-embedded and are skipped.
+
+Progress is checkpointed to a state file after every page, so an interrupted
+migration resumes where it stopped when run again. The migration finishes
+with a verification pass comparing vector counts per namespace.
+
+Profiles are defined in the config file:
+
+  profiles:
+    old:
+      store: upstash
+      upstash:
+        url: https://old-index.upstash.io
+        token: ...
+    new:
+      store: upstash
+      upstash:
+        url: https://new-index.upstash.io
+        token: ...
+
+Examples:
+  prj-start migrate --from old --to new
+  prj-start migrate --from old --to new --namespace handlers
+  prj-start migrate --from old --to new --verify-only`,
+	Args: cobra.NoArgs,
+	RunE: runMigrate,
+}
+
+var (
+	migrateFrom       string
+	migrateTo         string
+	migrateNamespaces []string
+	migratePageSize   int
+	migrateState      string
+	migrateRestart    bool
+	migrateVerifyOnly bool
+)
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", "", "profile to read from (required)")
+	migrateCmd.Flags().StringVar(&migrateTo, "to", "", "profile to write to (required)")
+	migrateCmd.Flags().StringSliceVarP(&migrateNamespaces, "namespace", "n", nil, "namespace to migrate, repeatable (default is all)")
+	migrateCmd.Flags().IntVar(&migratePageSize, "page-size", 100, "records read and upserted per request")
+	migrateCmd.Flags().StringVar(&migrateState, "state", "", "checkpoint file (default is .prj-start-migrate-<from>-<to>.json)")
+	migrateCmd.Flags().BoolVar(&migrateRestart, "restart", false, "ignore any checkpoint and start over")
+	migrateCmd.Flags().BoolVar(&migrateVerifyOnly, "verify-only", false, "only compare vector counts per namespace")
+	migrateCmd.MarkFlagRequired("from")
+	migrateCmd.MarkFlagRequired("to")
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	logger.SetOutput(os.Stderr)
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	sourceCfg, targetCfg, err := migrateProfiles(cfg, migrateFrom, migrateTo)
+	if err != nil {
+		return err
+	}
+	source, err := openProfileStore(sourceCfg, migrateFrom)
+	if err != nil {
+		return err
+	}
+	target, err := openProfileStore(targetCfg, migrateTo)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	statePath := migrateState
+	if statePath == "" {
+		statePath = fmt.Sprintf(".prj-start-migrate-%s-%s.json", migrateFrom, migrateTo)
+	}
+
+	if migrateRestart && !migrateVerifyOnly {
+		if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove checkpoint: %w", err)
+		}
+	}
+
+	// The checkpoint also records skipped records for the verification pass
+	state, err := migrate.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+	if !migrateVerifyOnly {
+		opts := migrate.Options{
+			Namespaces:    migrateNamespaces,
+			PageSize:      migratePageSize,
+			StateFile:     statePath,
+			SparseVectors: targetCfg.SparseVectors,
+		}
+		if targetCfg.SparseVectors {
+			stats, err := vector.LoadCorpusStats(targetCfg.SparseStatsPath())
+			if err != nil {
+				logger.LogWarning(fmt.Sprintf("%v; normalising sparse vectors over this migration only", err))
+			} else {
+				opts.CorpusStats = stats
+			}
+		}
+		logger.LogInfo(fmt.Sprintf("Migrating from profile '%s' to '%s' (checkpoint: %s)", migrateFrom, migrateTo, statePath))
+		state, err = migrate.Run(ctx, source, target, opts)
+		if err != nil {
+			return fmt.Errorf("migration failed (run again to resume): %w", err)
+		}
+	}
+
+	results, err := migrate.Verify(ctx, source, target, migrateNamespaces, state)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+
+	fmt.Printf("%-32s %12s %12s %12s  %s\n", "NAMESPACE", "SOURCE", "TARGET", "SKIPPED", "STATUS")
+	mismatches := 0
+	for _, v := range results {
+		status := "ok"
+		if !v.OK() {
+			status = "MISMATCH"
+			mismatches++
+		}
+		fmt.Printf("%-32s %12d %12d %12d  %s\n", namespaceLabel(v.Namespace), v.Source, v.Target, v.Skipped, status)
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("%d namespaces have fewer vectors in the target than expected (index stats can lag behind upserts; re-check with --verify-only)", mismatches)
+	}
+	logger.LogSuccess(fmt.Sprintf("Verified %d namespaces", len(results)))
+	return nil
+}
+
+// migrateProfiles returns the configs of the source and target profiles,
+// rejecting profiles that connect to the same index, which would overwrite
+// records while they are being read
+func migrateProfiles(cfg *config.Config, from, to string) (*config.Config, *config.Config, error) {
+	if from == to {
+		return nil, nil, fmt.Errorf("--from and --to must name different profiles")
+	}
+	sourceCfg, err := cfg.WithProfile(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetCfg, err := cfg.WithProfile(to)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sourceCfg.StoreLocation() == targetCfg.StoreLocation() {
+		return nil, nil, fmt.Errorf("profiles '%s' and '%s' both connect to %s; migrate between different indexes", from, to, sourceCfg.StoreLocation())
+	}
+	return sourceCfg, targetCfg, nil
+}
+
+// openProfileStore opens the vector store of a profile's config
+func openProfileStore(profileCfg *config.Config, name string) (vector.VectorStore, error) {
+	if err := profileCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("profile '%s': %w", name, err)
+	}
+	store, err := vector.OpenStore(profileCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store of profile '%s': %w", name, err)
+	}
+	return store, nil
+}
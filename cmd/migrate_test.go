@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/config"
+)
+
+func TestMigrateProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{Profiles: map[string]config.Profile{
+		"old":   {Upstash: config.UpstashConfig{URL: "https://old.upstash.io", Token: "old"}},
+		"alias": {Store: config.StoreUpstash, Upstash: config.UpstashConfig{URL: "https://old.upstash.io/", Token: "other"}},
+		"new":   {Upstash: config.UpstashConfig{URL: "https://new.upstash.io", Token: "new"}},
+		"local": {Store: config.StoreLocal, Local: config.LocalStoreConfig{Path: filepath.Join(dir, "vectors.jsonl")}},
+		"copy":  {Store: config.StoreLocal, Local: config.LocalStoreConfig{Path: filepath.Join(dir, ".", "vectors.jsonl"), Dimension: 64}},
+	}}
+
+	tests := []struct {
+		from, to string
+		want     string // substring of the error, empty for success
+	}{
+		{"old", "new", ""},
+		{"old", "local", ""},
+		{"old", "old", "different profiles"},
+		{"old", "alias", "both connect to"},
+		{"local", "copy", "both connect to"},
+		{"old", "missing", "not defined"},
+	}
+	for _, tt := range tests {
+		source, target, err := migrateProfiles(cfg, tt.from, tt.to)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s -> %s: %v", tt.from, tt.to, err)
+			} else if source.Upstash.URL != cfg.Profiles[tt.from].Upstash.URL || target.Store != cfg.Profiles[tt.to].Store {
+				t.Errorf("%s -> %s: wrong profile configs", tt.from, tt.to)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s -> %s: expected an error containing %q, got %v", tt.from, tt.to, tt.want, err)
+		}
+	}
+}
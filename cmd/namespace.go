@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// namespaceCmd represents the namespace command
+var namespaceCmd = &cobra.Command{
+	Use:   "namespace",
+	Short: "Inspect and manage vector namespaces",
+	Long: `Inspect and manage the namespaces of the configured vector store.
+
+Use "" to refer to the default namespace.
+
+Examples:
+  prj-start namespace list
+  prj-start namespace info 404-handler
+  prj-start namespace copy handlers handlers-v2
+  prj-start namespace copy handlers api-handlers --move
+  prj-start namespace reset 404-handler --yes
+  prj-start namespace delete old-recipes`,
+}
+
+var namespaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List namespaces with their vector counts",
+	Args:  cobra.NoArgs,
+	RunE:  runNamespaceList,
+}
+
+var namespaceInfoCmd = &cobra.Command{
+	Use:   "info <namespace>",
+	Short: "Show vector counts and index settings for a namespace",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runNamespaceInfo,
+}
+
+var namespaceDeleteCmd = &cobra.Command{
+	Use:   "delete <namespace>",
+	Short: "Delete a namespace and all of its vectors",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runNamespaceDelete,
+}
+
+var namespaceResetCmd = &cobra.Command{
+	Use:   "reset <namespace>",
+	Short: "Delete all vectors in a namespace but keep the namespace",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runNamespaceReset,
+}
+
+var namespaceCopyCmd = &cobra.Command{
+	Use:   "copy <source> <destination>",
+	Short: "Copy all vectors of a namespace into another namespace",
+	Long: `Copy all vectors, data and metadata of a namespace into another namespace
+without re-embedding. Upstash has no native rename, so --move copies and
+then deletes the source.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runNamespaceCopy,
+}
+
+var (
+	namespaceYes      bool
+	namespaceMove     bool
+	namespacePageSize int
+)
+
+func init() {
+	rootCmd.AddCommand(namespaceCmd)
+	namespaceCmd.AddCommand(namespaceListCmd)
+	namespaceCmd.AddCommand(namespaceInfoCmd)
+	namespaceCmd.AddCommand(namespaceDeleteCmd)
+	namespaceCmd.AddCommand(namespaceResetCmd)
+	namespaceCmd.AddCommand(namespaceCopyCmd)
+
+	namespaceDeleteCmd.Flags().BoolVarP(&namespaceYes, "yes", "y", false, "skip the confirmation prompt")
+	namespaceResetCmd.Flags().BoolVarP(&namespaceYes, "yes", "y", false, "skip the confirmation prompt")
+	namespaceCopyCmd.Flags().BoolVarP(&namespaceYes, "yes", "y", false, "skip the confirmation prompt when moving")
+	namespaceCopyCmd.Flags().BoolVar(&namespaceMove, "move", false, "delete the source namespace after copying (rename)")
+	namespaceCopyCmd.Flags().IntVar(&namespacePageSize, "page-size", 100, "vectors fetched and upserted per request")
+}
+
+func runNamespaceList(cmd *cobra.Command, args []string) error {
+	_, store, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+
+	info, err := store.Info(context.Background())
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(info.Namespaces))
+	for name := range info.Namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("%-32s %12s %12s\n", "NAMESPACE", "VECTORS", "PENDING")
+	for _, name := range names {
+		ns := info.Namespaces[name]
+		fmt.Printf("%-32s %12d %12d\n", namespaceLabel(name), ns.VectorCount, ns.PendingVectorCount)
+	}
+	fmt.Println()
+	fmt.Printf("%d namespaces, %d vectors (%d pending), dimension %d, %s\n",
+		len(names), info.VectorCount, info.PendingVectorCount, info.Dimension, info.SimilarityFunction)
+	return nil
+}
+
+func runNamespaceInfo(cmd *cobra.Command, args []string) error {
+	_, store, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+
+	info, err := store.Info(context.Background())
+	if err != nil {
+		return err
+	}
+	ns, ok := info.Namespaces[args[0]]
+	if !ok {
+		return fmt.Errorf("namespace %s does not exist", namespaceLabel(args[0]))
+	}
+
+	fmt.Printf("Namespace:       %s\n", namespaceLabel(args[0]))
+	fmt.Printf("Vectors:         %d\n", ns.VectorCount)
+	fmt.Printf("Pending vectors: %d\n", ns.PendingVectorCount)
+	fmt.Printf("Dimension:       %d\n", info.Dimension)
+	fmt.Printf("Similarity:      %s\n", info.SimilarityFunction)
+	fmt.Printf("Index vectors:   %d across %d namespaces\n", info.VectorCount, len(info.Namespaces))
+	return nil
+}
+
+func runNamespaceDelete(cmd *cobra.Command, args []string) error {
+	namespace := args[0]
+	if namespace == "" {
+		return fmt.Errorf("the default namespace cannot be deleted; use 'prj-start namespace reset \"\"' instead")
+	}
+
+	_, store, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	count, err := namespaceVectorCount(ctx, store, namespace)
+	if err != nil {
+		return err
+	}
+	if !namespaceYes && !confirm(fmt.Sprintf("Delete namespace %s and its %d vectors?", namespaceLabel(namespace), count)) {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	if err := store.DeleteNamespace(ctx, namespace); err != nil {
+		return err
+	}
+	logger.LogSuccess(fmt.Sprintf("Deleted namespace %s (%d vectors)", namespaceLabel(namespace), count))
+	return nil
+}
+
+func runNamespaceReset(cmd *cobra.Command, args []string) error {
+	namespace := args[0]
+
+	_, store, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	count, err := namespaceVectorCount(ctx, store, namespace)
+	if err != nil {
+		return err
+	}
+	if !namespaceYes && !confirm(fmt.Sprintf("Delete all %d vectors in namespace %s?", count, namespaceLabel(namespace))) {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	if err := store.ResetNamespace(ctx, namespace); err != nil {
+		return err
+	}
+	logger.LogSuccess(fmt.Sprintf("Reset namespace %s (%d vectors removed)", namespaceLabel(namespace), count))
+	return nil
+}
+
+func runNamespaceCopy(cmd *cobra.Command, args []string) error {
+	source, destination := args[0], args[1]
+	if source == destination {
+		return fmt.Errorf("source and destination are the same namespace")
+	}
+
+	_, store, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	total, err := namespaceVectorCount(ctx, store, source)
+	if err != nil {
+		return err
+	}
+	existing, err := namespaceVectorCount(ctx, store, destination)
+	if err == nil && existing > 0 {
+		logger.LogWarning(fmt.Sprintf("Destination %s already holds %d vectors; records with the same IDs are overwritten", namespaceLabel(destination), existing))
+	}
+	if namespaceMove && !namespaceYes && !confirm(fmt.Sprintf("Move %d vectors from %s to %s and delete the source?", total, namespaceLabel(source), namespaceLabel(destination))) {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	copied, err := vector.CopyNamespace(ctx, store, source, store, destination, namespacePageSize, func(copied int) {
+		logger.LogProgress(copied, total, "Vectors copied")
+	})
+	if err != nil {
+		return fmt.Errorf("copy %s to %s: %w", namespaceLabel(source), namespaceLabel(destination), err)
+	}
+	logger.LogSuccess(fmt.Sprintf("Copied %d vectors from %s to %s", copied, namespaceLabel(source), namespaceLabel(destination)))
+
+	if namespaceMove {
+		if err := removeCopiedNamespace(ctx, store, source, destination, copied, namespacePageSize); err != nil {
+			return err
+		}
+		logger.LogSuccess(fmt.Sprintf("Removed source namespace %s", namespaceLabel(source)))
+	}
+	return nil
+}
+
+// removeCopiedNamespace deletes (or, for the default namespace, resets) the
+// source of a move after checking the copy: the source must hold no more
+// vectors, pending ones included, than were copied, and every record it holds
+// must be in the destination. Writes during the copy, or vectors not yet
+// indexed, make it refuse rather than lose data.
+func removeCopiedNamespace(ctx context.Context, store vector.VectorStore, source, destination string, copied, pageSize int) error {
+	info, err := store.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("copied, but failed to verify the copy: %w", err)
+	}
+	if ns := info.Namespaces[source]; ns.VectorCount+ns.PendingVectorCount > copied {
+		return fmt.Errorf("copied %d vectors, but %s now holds %d (%d pending); the source was kept, run the move again", copied, namespaceLabel(source), ns.VectorCount+ns.PendingVectorCount, ns.PendingVectorCount)
+	}
+	missing, err := vector.MissingRecords(ctx, store, source, store, destination, pageSize)
+	if err != nil {
+		return fmt.Errorf("copied, but failed to verify the copy: %w", err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("copied, but %d records of %s are missing from %s (first: %s); the source was kept, run the move again", len(missing), namespaceLabel(source), namespaceLabel(destination), missing[0])
+	}
+
+	if source == "" {
+		err = store.ResetNamespace(ctx, source)
+	} else {
+		err = store.DeleteNamespace(ctx, source)
+	}
+	if err != nil {
+		return fmt.Errorf("copied, but failed to remove source namespace: %w", err)
+	}
+	return nil
+}
+
+// namespaceVectorCount returns the vector count of an existing namespace
+func namespaceVectorCount(ctx context.Context, store vector.VectorStore, namespace string) (int, error) {
+	info, err := store.Info(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ns, ok := info.Namespaces[namespace]
+	if !ok {
+		return 0, fmt.Errorf("namespace %s does not exist", namespaceLabel(namespace))
+	}
+	return ns.VectorCount, nil
+}
+
+// namespaceLabel names the default namespace, which is the empty string
+func namespaceLabel(namespace string) string {
+	if namespace == "" {
+		return `"" (default)`
+	}
+	return namespace
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s (y/N): ", question)
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
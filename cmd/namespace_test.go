@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+func TestRemoveCopiedNamespace(t *testing.T) {
+	logger.SetOutput(io.Discard)
+	store, err := vector.NewLocalStore(config.LocalStoreConfig{Path: filepath.Join(t.TempDir(), "vectors.jsonl"), Dimension: 32}, nil)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+	upsert := func(namespace string, ids ...string) {
+		t.Helper()
+		documents := make([]vector.Document, len(ids))
+		for i, id := range ids {
+			documents[i] = vector.Document{ID: id, Content: "content of " + id}
+		}
+		if err := store.UpsertBatch(ctx, documents, namespace); err != nil {
+			t.Fatalf("UpsertBatch: %v", err)
+		}
+	}
+	upsert("old", "a", "b", "c")
+
+	copied, err := vector.CopyNamespace(ctx, store, "old", store, "new", 2, nil)
+	if err != nil {
+		t.Fatalf("CopyNamespace: %v", err)
+	}
+
+	// A write during the copy leaves more vectors in the source than copied
+	upsert("old", "d")
+	if err := removeCopiedNamespace(ctx, store, "old", "new", copied, 2); err == nil || !strings.Contains(err.Error(), "source was kept") {
+		t.Fatalf("expected the move to be refused, got %v", err)
+	}
+
+	// A record that replaced one of the copied ones is still missing
+	if err := store.DeleteNamespace(ctx, "old"); err != nil {
+		t.Fatal(err)
+	}
+	upsert("old", "a", "b", "x")
+	if err := removeCopiedNamespace(ctx, store, "old", "new", copied, 2); err == nil || !strings.Contains(err.Error(), "missing from new") {
+		t.Fatalf("expected x to be reported missing, got %v", err)
+	}
+
+	if _, err := vector.CopyNamespace(ctx, store, "old", store, "new", 2, nil); err != nil {
+		t.Fatalf("CopyNamespace: %v", err)
+	}
+	if err := removeCopiedNamespace(ctx, store, "old", "new", copied, 2); err != nil {
+		t.Fatalf("removeCopiedNamespace: %v", err)
+	}
+	info, err := store.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := info.Namespaces["old"]; ok {
+		t.Error("source namespace still exists after the move")
+	}
+}
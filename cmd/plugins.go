@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/processor"
+)
+
+// pluginsCmd represents the plugins command
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Manage external chunker plugins",
+	Long: `Manage external chunker plugins declared in the configuration file.
+
+A plugin is an executable that receives a file as JSON on stdin and writes
+its chunks as JSON to stdout:
+
+  stdin:  {"path": "...", "relative_path": "...", "topic": "...",
+           "extension": ".dsl", "content": "...", "size": 123}
+  stdout: {"chunks": [{"content": "...", "metadata": {"symbol": "..."}}]}
+
+Plugins are declared in the config file:
+
+  plugins:
+    - name: dsl
+      command: /usr/local/bin/dsl-chunker
+      args: ["--mode", "json"]
+      extensions: [".dsl"]
+      filenames: ["*.rules"]
+      timeout: 10`,
+}
+
+// pluginsListCmd represents the plugins list command
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured chunker plugins",
+	Args:  cobra.NoArgs,
+	RunE:  runPluginsList,
+}
+
+// pluginsTestCmd represents the plugins test command
+var pluginsTestCmd = &cobra.Command{
+	Use:   "test <plugin> <file>",
+	Short: "Run a chunker plugin against a file and show its chunks",
+	Long: `Run a configured chunker plugin against a single file and print the
+chunks it returns. Use this to check a plugin before running an ingest.
+
+Examples:
+  prj-start plugins test dsl ./rules/pricing.dsl
+  prj-start plugins test dsl ./rules/pricing.dsl --full`,
+	Args: cobra.ExactArgs(2),
+	RunE: runPluginsTest,
+}
+
+var (
+	pluginsTestFull bool
+)
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+	pluginsCmd.AddCommand(pluginsListCmd)
+	pluginsCmd.AddCommand(pluginsTestCmd)
+	pluginsTestCmd.Flags().BoolVar(&pluginsTestFull, "full", false, "print full chunk content instead of a preview")
+}
+
+func runPluginsList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if len(cfg.Plugins) == 0 {
+		fmt.Println("No chunker plugins configured.")
+		return nil
+	}
+
+	for _, p := range cfg.Plugins {
+		fmt.Printf("%s\n", p.Name)
+		fmt.Printf("  command:    %s %s\n", p.Command, strings.Join(p.Args, " "))
+		if len(p.Extensions) > 0 {
+			fmt.Printf("  extensions: %s\n", strings.Join(p.Extensions, ", "))
+		}
+		if len(p.Filenames) > 0 {
+			fmt.Printf("  filenames:  %s\n", strings.Join(p.Filenames, ", "))
+		}
+		fmt.Printf("  timeout:    %v\n", p.TimeoutDuration())
+	}
+	return nil
+}
+
+func runPluginsTest(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	pluginCfg, ok := cfg.FindPlugin(args[0])
+	if !ok {
+		return fmt.Errorf("plugin '%s' is not configured\n\nUse 'prj-start plugins list' to see configured plugins", args[0])
+	}
+	if err := pluginCfg.Validate(); err != nil {
+		return err
+	}
+
+	fileInfo, err := readSingleDocument(args[1])
+	if err != nil {
+		return err
+	}
+
+	plugin := processor.PluginFromConfig(pluginCfg)
+	if !plugin.Matches(fileInfo) {
+		fmt.Printf("⚠️  %s does not match the extensions or filenames of plugin %s\n", fileInfo.RelativePath, plugin.Name)
+	}
+
+	start := time.Now()
+	chunks, err := plugin.Run(context.Background(), fileInfo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Plugin %s returned %d chunks for %s in %v\n", plugin.Name, len(chunks), fileInfo.RelativePath, time.Since(start).Round(time.Millisecond))
+	for _, chunk := range chunks {
+		fmt.Println()
+		fmt.Printf("--- chunk %d (%d bytes) ---\n", chunk.Index, len(chunk.Content))
+
+		keys := make([]string, 0, len(chunk.Metadata))
+		for k := range chunk.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("  %s: %v\n", k, chunk.Metadata[k])
+		}
+
+		content := chunk.Content
+		if !pluginsTestFull && len(content) > 200 {
+			content = content[:200] + "..."
+		}
+		fmt.Println(content)
+	}
+	return nil
+}
+
+// readSingleDocument reads one file the same way ingest does, relative to the working directory
+func readSingleDocument(path string) (document.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return document.FileInfo{}, fmt.Errorf("failed to access '%s': %w", path, err)
+	}
+	if info.IsDir() {
+		return document.FileInfo{}, fmt.Errorf("'%s' is a directory", path)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return document.FileInfo{}, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return document.FileInfo{}, err
+	}
+
+	return document.NewReader(cwd).ReadDocument(absPath)
+}
@@ -9,12 +9,13 @@ import (
 )
 
 var (
-	cfgFile string
-	verbose bool
-	version = "v0.1.7"
-	commit  = "unknown"
-	date    = "unknown"
-	builtBy = "unknown"
+	cfgFile     string
+	profileName string
+	verbose     bool
+	version     = "v0.1.7"
+	commit      = "unknown"
+	date        = "unknown"
+	builtBy     = "unknown"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -24,9 +25,18 @@ var rootCmd = &cobra.Command{
 	Long: `prj-start is a Go-based tool for document processing and MCP server functionality.
 
 Commands:
-  ingest    - Process and ingest documents into Upstash Vector database
-  init      - Initialize configuration
-  mcp       - Start MCP server for querying (coming soon)
+  cat          - Print a file reassembled from its indexed chunks
+  export       - Back up namespaces to compressed JSONL
+  fake-upstash - Run a fake Upstash Vector server for demos and testing
+  import       - Restore namespaces from a backup
+  ingest       - Process and ingest documents into Upstash Vector database
+  init         - Initialize configuration
+  mcp          - Start MCP server for querying over stdio or HTTP
+  metadata     - Inspect the metadata schema and migrate stored metadata
+  migrate      - Re-embed namespaces from one profile's index into another
+  namespace    - Inspect, copy, reset and delete namespaces
+  plugins      - Manage external chunker plugins
+  search       - Search ingested documents
 
 Use 'prj-start help <command>' for more information about a specific command.`,
 	Version: fmt.Sprintf("%s (commit: %s, built: %s by: %s)", version, commit, date, builtBy),
@@ -49,6 +59,7 @@ func Execute() {
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/prj-start/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "connection profile from the config file (default is default_profile)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 
 	// Add custom help template
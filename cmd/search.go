@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/typicalfo/prj-start/processor"
+	"github.com/typicalfo/prj-start/search"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+var (
+	searchNamespaces []string
+	searchFilter     string
+	searchWhere      string
+	searchTopK       int
+	searchMinScore   float64
+	searchMetadata   bool
+	searchData       bool
+	searchParent     bool
+	searchFusion     string
+	searchWeighting  string
+	searchExpand     int
+	searchBudget     int
+	searchJSON       bool
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search ingested documents from the command line",
+	Long: `Search ingested documents with the same engine as the MCP vector_query tool.
+
+Examples:
+  prj-start search "fiber middleware for authentication"
+  prj-start search "NewChunker" --namespace document --data
+  prj-start search "rate limiting" --namespace '*' --min-score 0.8
+  prj-start search "database setup" --filter "extension = '.go'" --json
+  prj-start search "upsert batching" --expand 1 --budget 2000
+  prj-start search "handlers" --where '{"field": "tags", "op": "contains", "value": "auth"}'`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().StringSliceVarP(&searchNamespaces, "namespace", "n", nil, "namespace to search, repeatable; '*' searches all (default is the default namespace)")
+	searchCmd.Flags().StringVar(&searchFilter, "filter", "", "metadata filter expression")
+	searchCmd.Flags().StringVar(&searchWhere, "where", "", "structured filter as JSON, validated against the metadata schema")
+	searchCmd.Flags().IntVarP(&searchTopK, "top-k", "k", search.DefaultTopK, "maximum number of results")
+	searchCmd.Flags().Float64Var(&searchMinScore, "min-score", 0, "drop results scoring below this threshold")
+	searchCmd.Flags().BoolVar(&searchMetadata, "metadata", false, "include metadata in results")
+	searchCmd.Flags().BoolVar(&searchData, "data", false, "include document content in results")
+	searchCmd.Flags().BoolVar(&searchParent, "parent", false, "attach the enclosing section or file outline")
+	searchCmd.Flags().StringVar(&searchFusion, "fusion", "", "hybrid indexes: RRF or DBSF")
+	searchCmd.Flags().StringVar(&searchWeighting, "sparse-weighting", "", "hybrid indexes: IDF or NONE")
+	searchCmd.Flags().IntVar(&searchExpand, "expand", 0, "add this many neighboring chunks around each hit and merge hits of the same file")
+	searchCmd.Flags().IntVar(&searchBudget, "budget", 0, "keep the best results that fit this many estimated tokens (implies --data)")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "print results as JSON")
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	cfg, store, err := openConfiguredStore()
+	if err != nil {
+		return err
+	}
+	searcher := search.NewService(store)
+
+	filter := searchFilter
+	if searchWhere != "" {
+		schema, err := processor.MetadataSchema(cfg)
+		if err != nil {
+			return err
+		}
+		searcher.SetMetadataSchema(schema)
+		where, err := vector.ParseFilterSpec([]byte(searchWhere))
+		if err != nil {
+			return err
+		}
+		if filter, err = searcher.CompileFilter(searchFilter, where); err != nil {
+			return fmt.Errorf("invalid --where: %w", err)
+		}
+	}
+
+	query := strings.Join(args, " ")
+	results, err := searcher.Search(context.Background(), search.Request{
+		Query:           query,
+		Filter:          filter,
+		Namespaces:      searchNamespaces,
+		TopK:            searchTopK,
+		MinScore:        searchMinScore,
+		IncludeMetadata: searchMetadata,
+		IncludeData:     searchData,
+		IncludeParent:   searchParent,
+		Fusion:          searchFusion,
+		SparseWeighting: searchWeighting,
+		Expand:          searchExpand,
+		TokenBudget:     searchBudget,
+	})
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	if searchJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No results.")
+		return nil
+	}
+	for i, result := range results {
+		location := result.Citation
+		if location == "" {
+			location = result.ID
+		}
+		fmt.Printf("%d. [%.4f] %s", i+1, result.Score, location)
+		if result.Namespace != "" {
+			fmt.Printf("  (%s)", result.Namespace)
+		}
+		fmt.Println()
+		fmt.Printf("   id: %s\n", result.ID)
+		if len(result.Hits) > 1 {
+			fmt.Printf("   merged hits: %s\n", strings.Join(result.Hits[1:], ", "))
+		}
+
+		if searchMetadata {
+			keys := make([]string, 0, len(result.Metadata))
+			for k := range result.Metadata {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Printf("   %s: %v\n", k, result.Metadata[k])
+			}
+		}
+		if (searchData || searchExpand > 0 || searchBudget > 0) && result.Data != "" {
+			fmt.Println("   | " + strings.ReplaceAll(result.Data, "\n", "\n   | "))
+		}
+		if result.Parent != nil {
+			fmt.Printf("   parent: %s %s\n", result.Parent.Level, result.Parent.Citation)
+		}
+	}
+	return nil
+}
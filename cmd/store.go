@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// loadConfig loads the configuration selected by the global --config and
+// --profile flags
+func loadConfig() (*config.Config, error) {
+	return config.LoadConfigProfile(cfgFile, profileName)
+}
+
+// openConfiguredStore loads the configuration and opens its vector store.
+// Log output is sent to stderr so command output on stdout stays clean for
+// piping.
+func openConfiguredStore() (*config.Config, vector.VectorStore, error) {
+	logger.SetOutput(os.Stderr)
+	if !verbose {
+		logger.SetLogLevel("warn")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !cfg.HasStoreConfig() {
+		return nil, nil, fmt.Errorf("Upstash configuration is incomplete\n\nUse 'prj-start init' to set up your configuration, or set store: local")
+	}
+
+	store, err := vector.OpenStore(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open vector store: %w", err)
+	}
+	return cfg, store, nil
+}
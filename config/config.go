@@ -5,17 +5,34 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Upstash          UpstashConfig `yaml:"upstash"`
-	DefaultNamespace string        `yaml:"default_namespace"`
-	BatchSize        int           `yaml:"batch_size"`
-	LogLevel         string        `yaml:"log_level"`
-	ConfigFile       string        `yaml:"-"`
+	Store            string             `yaml:"store,omitempty"` // upstash (default) or local
+	Upstash          UpstashConfig      `yaml:"upstash"`
+	Local            LocalStoreConfig   `yaml:"local,omitempty"`
+	Embedding        EmbeddingConfig    `yaml:"embedding,omitempty"`
+	Profiles         map[string]Profile `yaml:"profiles,omitempty"`
+	DefaultProfile   string             `yaml:"default_profile,omitempty"` // profile used when --profile is not given
+	DefaultNamespace string             `yaml:"default_namespace"`
+	BatchSize        int                `yaml:"batch_size"`                 // ceiling on documents per upsert
+	BatchMaxBytes    int                `yaml:"batch_max_bytes,omitempty"`  // payload budget per upsert
+	BatchMaxTokens   int                `yaml:"batch_max_tokens,omitempty"` // estimated token budget per upsert
+	LogLevel         string             `yaml:"log_level"`
+	Plugins          []PluginConfig     `yaml:"plugins,omitempty"`
+	SkipFiles        []string           `yaml:"skip_files,omitempty"` // defaults to go.sum when unset
+	ContextHeaders   bool               `yaml:"context_headers,omitempty"`
+	ParentChunks     string             `yaml:"parent_chunks,omitempty"`    // none, file or section
+	SparseVectors    bool               `yaml:"sparse_vectors,omitempty"`   // BM25 sparse vectors for hybrid indexes
+	MetadataSchema   map[string]string  `yaml:"metadata_schema,omitempty"`  // extra metadata key types, e.g. priority: int
+	MCPIngestRoots   []string           `yaml:"mcp_ingest_roots,omitempty"` // folders the MCP ingest tools may read
+	MCPAuthToken     string             `yaml:"mcp_auth_token,omitempty"`   // bearer token required by the MCP HTTP transport
+	ConfigFile       string             `yaml:"-"`
 }
 
 // GetConfigPaths returns possible config file paths in order of preference
@@ -60,8 +77,17 @@ func FindConfigFile() (string, error) {
 	return "", fmt.Errorf("no config file found")
 }
 
-// LoadConfig loads configuration from file, environment variables, and defaults
+// LoadConfig loads configuration from file, environment variables, and defaults,
+// connecting through the default profile if one is set
 func LoadConfig(configFile string) (*Config, error) {
+	return LoadConfigProfile(configFile, "")
+}
+
+// LoadConfigProfile loads configuration like LoadConfig, connecting through the
+// named profile. An explicitly named profile takes precedence over connection
+// environment variables; the default profile does not, so a shell that exports
+// UPSTASH_VECTOR_REST_URL keeps working after profiles are added.
+func LoadConfigProfile(configFile, profile string) (*Config, error) {
 	cfg := &Config{
 		DefaultNamespace: "default",
 		BatchSize:        10,
@@ -87,8 +113,31 @@ func LoadConfig(configFile string) (*Config, error) {
 
 	// Load environment variables (including .env file)
 	_ = godotenv.Load()
+
+	if profile == "" {
+		profiled, err := cfg.withDefaultProfile()
+		if err != nil {
+			return nil, err
+		}
+		cfg = profiled
+	}
+
 	loadFromEnv(cfg)
 
+	if profile != "" {
+		return cfg.WithProfile(profile)
+	}
+	return cfg, nil
+}
+
+// LoadFile reads a config file as written, without defaults or environment
+// variables, for commands that edit and save it
+func LoadFile(filename string) (*Config, error) {
+	cfg := &Config{}
+	if err := loadFromFile(cfg, filename); err != nil {
+		return nil, err
+	}
+	cfg.ConfigFile = filename
 	return cfg, nil
 }
 
@@ -121,9 +170,50 @@ func loadFromEnv(cfg *Config) {
 	if namespace := os.Getenv("DEFAULT_NAMESPACE"); namespace != "" {
 		cfg.DefaultNamespace = namespace
 	}
+	if batchSize, err := strconv.Atoi(os.Getenv("BATCH_SIZE")); err == nil && batchSize > 0 {
+		cfg.BatchSize = batchSize
+	}
+	if maxBytes, err := strconv.Atoi(os.Getenv("BATCH_MAX_BYTES")); err == nil && maxBytes > 0 {
+		cfg.BatchMaxBytes = maxBytes
+	}
+	if maxTokens, err := strconv.Atoi(os.Getenv("BATCH_MAX_TOKENS")); err == nil && maxTokens > 0 {
+		cfg.BatchMaxTokens = maxTokens
+	}
 	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
 		cfg.LogLevel = logLevel
 	}
+	if contextHeaders := os.Getenv("CONTEXT_HEADERS"); contextHeaders != "" {
+		cfg.ContextHeaders, _ = strconv.ParseBool(contextHeaders)
+	}
+	if parentChunks := os.Getenv("PARENT_CHUNKS"); parentChunks != "" {
+		cfg.ParentChunks = parentChunks
+	}
+	if sparseVectors := os.Getenv("SPARSE_VECTORS"); sparseVectors != "" {
+		cfg.SparseVectors, _ = strconv.ParseBool(sparseVectors)
+	}
+	if token := os.Getenv("MCP_AUTH_TOKEN"); token != "" {
+		cfg.MCPAuthToken = token
+	}
+	if store := os.Getenv("VECTOR_STORE"); store != "" {
+		cfg.Store = store
+	}
+	if localPath := os.Getenv("LOCAL_STORE_PATH"); localPath != "" {
+		cfg.Local.Path = localPath
+	}
+	if provider := os.Getenv("EMBEDDING_PROVIDER"); provider != "" {
+		cfg.Embedding.Provider = provider
+	}
+	if baseURL := os.Getenv("EMBEDDING_BASE_URL"); baseURL != "" {
+		cfg.Embedding.BaseURL = baseURL
+	}
+	if model := os.Getenv("EMBEDDING_MODEL"); model != "" {
+		cfg.Embedding.Model = model
+	}
+	if apiKey := os.Getenv("EMBEDDING_API_KEY"); apiKey != "" {
+		cfg.Embedding.APIKey = apiKey
+	} else if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" && cfg.Embedding.APIKey == "" {
+		cfg.Embedding.APIKey = apiKey
+	}
 }
 
 // SaveConfig saves configuration to the specified file
@@ -151,8 +241,25 @@ func (c *Config) HasUpstashConfig() bool {
 }
 
 func (c *Config) Validate() error {
-	if !c.HasUpstashConfig() {
-		return fmt.Errorf("upstash configuration is incomplete (URL and Token are required)")
+	switch strings.ToLower(c.Store) {
+	case "", StoreUpstash:
+		if !c.HasUpstashConfig() {
+			return fmt.Errorf("upstash configuration is incomplete (URL and Token are required)")
+		}
+	case StoreLocal:
+		if err := c.Local.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown store %q (use upstash or local)", c.Store)
+	}
+	if err := c.Embedding.Validate(); err != nil {
+		return err
+	}
+	for _, p := range c.Plugins {
+		if err := p.Validate(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
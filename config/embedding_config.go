@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Embedding providers selectable with embedding.provider. When unset the
+// vector store embeds text itself (Upstash's built-in model, or the local
+// store's hashing embedder).
+const (
+	EmbeddingOpenAI = "openai"
+	EmbeddingHash   = "hash"
+)
+
+// EmbeddingConfig selects a client-side embedder whose vectors are sent to
+// the store instead of raw text
+type EmbeddingConfig struct {
+	Provider  string `yaml:"provider,omitempty"`   // openai (any OpenAI-compatible server) or hash
+	BaseURL   string `yaml:"base_url,omitempty"`   // defaults to https://api.openai.com/v1
+	APIKey    string `yaml:"api_key,omitempty"`    // optional for local servers
+	Model     string `yaml:"model,omitempty"`      // e.g. text-embedding-3-small or nomic-embed-text
+	Dimension int    `yaml:"dimension,omitempty"`  // requested output dimension; learned from the server when unset
+	BatchSize int    `yaml:"batch_size,omitempty"` // texts per request, defaults to 64
+	Timeout   int    `yaml:"timeout,omitempty"`    // seconds, defaults to 60
+}
+
+// Enabled reports whether a client-side embedder is configured
+func (e EmbeddingConfig) Enabled() bool {
+	return e.Provider != ""
+}
+
+// TimeoutDuration returns the request timeout, defaulting to 60 seconds
+func (e EmbeddingConfig) TimeoutDuration() time.Duration {
+	if e.Timeout <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(e.Timeout) * time.Second
+}
+
+func (e EmbeddingConfig) Validate() error {
+	switch strings.ToLower(e.Provider) {
+	case "":
+	case EmbeddingOpenAI:
+		if e.Model == "" {
+			return fmt.Errorf("embedding model is required for the openai provider")
+		}
+	case EmbeddingHash:
+	default:
+		return fmt.Errorf("unknown embedding provider %q (use openai or hash)", e.Provider)
+	}
+	if e.Dimension < 0 {
+		return fmt.Errorf("embedding dimension must be positive")
+	}
+	return nil
+}
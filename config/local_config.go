@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store backends selectable with the top-level "store" key
+const (
+	StoreUpstash = "upstash"
+	StoreLocal   = "local"
+)
+
+// LocalStoreConfig configures the embedded file-backed vector store used for
+// offline work. Zero values fall back to the defaults below.
+type LocalStoreConfig struct {
+	Path       string `yaml:"path,omitempty"`       // journal file, defaults next to the user config
+	Similarity string `yaml:"similarity,omitempty"` // cosine, dot_product or euclidean
+	Dimension  int    `yaml:"dimension,omitempty"`  // embedding dimension, defaults to 256
+}
+
+// StorePath returns the journal file path, defaulting to vectors.jsonl in the
+// user config directory
+func (l LocalStoreConfig) StorePath() string {
+	if strings.HasPrefix(l.Path, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(homeDir, l.Path[2:])
+		}
+	}
+	if l.Path != "" {
+		return l.Path
+	}
+	return filepath.Join(filepath.Dir(GetDefaultConfigPath()), "vectors.jsonl")
+}
+
+func (l LocalStoreConfig) Validate() error {
+	switch strings.ToLower(l.Similarity) {
+	case "", "cosine", "dot_product", "dot", "euclidean":
+	default:
+		return fmt.Errorf("unknown local store similarity %q (use cosine, dot_product or euclidean)", l.Similarity)
+	}
+	if l.Dimension < 0 {
+		return fmt.Errorf("local store dimension must be positive")
+	}
+	return nil
+}
+
+// UsesLocalStore reports whether the embedded local store is selected
+func (c *Config) UsesLocalStore() bool {
+	return strings.EqualFold(c.Store, StoreLocal)
+}
+
+// HasStoreConfig reports whether the selected backend is usable: the local
+// store needs no credentials, Upstash needs URL and token
+func (c *Config) HasStoreConfig() bool {
+	return c.UsesLocalStore() || c.HasUpstashConfig()
+}
+
+// StoreLocation identifies the index the config connects to: the absolute
+// journal path of the local store, or the Upstash URL. Configs with the same
+// location read and write the same vectors.
+func (c *Config) StoreLocation() string {
+	if c.UsesLocalStore() {
+		path := c.Local.StorePath()
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return StoreLocal + ":" + filepath.Clean(path)
+	}
+	return StoreUpstash + ":" + strings.ToLower(strings.TrimRight(strings.TrimSpace(c.Upstash.URL), "/"))
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// PluginConfig declares an external chunker executable and the files it handles
+type PluginConfig struct {
+	Name       string   `yaml:"name"`
+	Command    string   `yaml:"command"`
+	Args       []string `yaml:"args,omitempty"`
+	Extensions []string `yaml:"extensions,omitempty"`
+	Filenames  []string `yaml:"filenames,omitempty"`
+	Timeout    int      `yaml:"timeout,omitempty"` // seconds
+}
+
+// TimeoutDuration returns the plugin timeout, defaulting to 30 seconds
+func (p PluginConfig) TimeoutDuration() time.Duration {
+	if p.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(p.Timeout) * time.Second
+}
+
+func (p PluginConfig) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("plugin name is required")
+	}
+	if p.Command == "" {
+		return fmt.Errorf("plugin %s has no command", p.Name)
+	}
+	if len(p.Extensions) == 0 && len(p.Filenames) == 0 {
+		return fmt.Errorf("plugin %s must declare at least one extension or filename", p.Name)
+	}
+	return nil
+}
+
+// FindPlugin returns the plugin with the given name
+func (c *Config) FindPlugin(name string) (PluginConfig, bool) {
+	for _, p := range c.Plugins {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return PluginConfig{}, false
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a named vector store connection: backend, credentials and
+// embedding settings. Everything else is shared with the top-level config.
+type Profile struct {
+	Store         string           `yaml:"store,omitempty"`
+	Upstash       UpstashConfig    `yaml:"upstash,omitempty"`
+	Local         LocalStoreConfig `yaml:"local,omitempty"`
+	Embedding     EmbeddingConfig  `yaml:"embedding,omitempty"`
+	SparseVectors bool             `yaml:"sparse_vectors,omitempty"`
+}
+
+// WithProfile returns a copy of the config connected through the named
+// profile. The profile replaces the top-level connection settings entirely,
+// including values from environment variables, so two profiles never end up
+// pointing at the same index by accident.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' is not defined (available: %v)", name, c.ProfileNames())
+	}
+
+	cfg := *c
+	cfg.Store = profile.Store
+	cfg.Upstash = profile.Upstash
+	cfg.Local = profile.Local
+	cfg.Embedding = profile.Embedding
+	cfg.SparseVectors = profile.SparseVectors
+	return &cfg, nil
+}
+
+// withDefaultProfile returns the config connected through default_profile, or
+// the config itself when no default is set
+func (c *Config) withDefaultProfile() (*Config, error) {
+	if c.DefaultProfile == "" {
+		return c, nil
+	}
+	cfg, err := c.WithProfile(c.DefaultProfile)
+	if err != nil {
+		return nil, fmt.Errorf("default_profile: %w", err)
+	}
+	return cfg, nil
+}
+
+// ProfileNames returns the defined profile names in sorted order
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SaveProfile adds or replaces one profile in a config file, optionally making
+// it the default. The file is edited in place, so other settings, profiles and
+// comments are kept as written. A missing file is created.
+func SaveProfile(filename, name string, profile Profile, makeDefault bool) error {
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	data, err := os.ReadFile(filename)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", filename, err)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a YAML mapping", filename)
+	}
+
+	var value yaml.Node
+	if err := value.Encode(profile); err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+	pruneEmpty(&value)
+	profiles := mappingValue(root, "profiles")
+	if profiles.Kind != yaml.MappingNode {
+		*profiles = yaml.Node{Kind: yaml.MappingNode}
+	}
+	*mappingValue(profiles, name) = value
+
+	if makeDefault {
+		*mappingValue(root, "default_profile") = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	encoder.Close()
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(filename, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value node of key in a mapping node, appending an
+// empty one when the key is missing
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return value
+}
+
+// pruneEmpty drops keys with empty values from a mapping node, recursively, so
+// a saved profile lists only the settings that were given
+func pruneEmpty(mapping *yaml.Node) {
+	kept := mapping.Content[:0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if value.Kind == yaml.MappingNode {
+			pruneEmpty(value)
+			if len(value.Content) == 0 {
+				continue
+			}
+		} else if value.Kind == yaml.ScalarNode && (value.Value == "" || value.Value == "0" || value.Value == "false") {
+			continue
+		}
+		kept = append(kept, key, value)
+	}
+	mapping.Content = kept
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithProfile(t *testing.T) {
+	cfg := &Config{
+		Upstash:   UpstashConfig{URL: "https://top.upstash.io", Token: "top"},
+		BatchSize: 10,
+		Profiles: map[string]Profile{
+			"offline": {Store: StoreLocal, Local: LocalStoreConfig{Path: "vectors.jsonl"}, SparseVectors: true},
+		},
+	}
+	profiled, err := cfg.WithProfile("offline")
+	if err != nil {
+		t.Fatalf("WithProfile: %v", err)
+	}
+	if !profiled.UsesLocalStore() || profiled.Local.Path != "vectors.jsonl" || !profiled.SparseVectors {
+		t.Errorf("profile settings not applied: %+v", profiled)
+	}
+	if profiled.Upstash.URL != "" {
+		t.Errorf("top-level Upstash URL leaked into the profile: %q", profiled.Upstash.URL)
+	}
+	if profiled.BatchSize != 10 || cfg.Store != "" {
+		t.Error("expected shared settings kept and the original config unchanged")
+	}
+
+	if _, err := cfg.WithProfile("missing"); err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("expected an error listing the available profiles, got %v", err)
+	}
+}
+
+func TestLoadConfigProfileDefault(t *testing.T) {
+	t.Setenv("UPSTASH_VECTOR_REST_URL", "")
+	t.Setenv("UPSTASH_VECTOR_REST_TOKEN", "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `default_profile: old
+profiles:
+  old:
+    upstash:
+      url: https://old.upstash.io
+      token: old
+  new:
+    upstash:
+      url: https://new.upstash.io
+      token: new
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Upstash.URL != "https://old.upstash.io" {
+		t.Errorf("default profile not applied, URL is %q", cfg.Upstash.URL)
+	}
+	named, err := LoadConfigProfile(path, "new")
+	if err != nil {
+		t.Fatalf("LoadConfigProfile: %v", err)
+	}
+	if named.Upstash.URL != "https://new.upstash.io" {
+		t.Errorf("named profile not applied, URL is %q", named.Upstash.URL)
+	}
+
+	if err := os.WriteFile(path, []byte("default_profile: gone\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "default_profile") {
+		t.Errorf("expected a default_profile error, got %v", err)
+	}
+}
+
+func TestSaveProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("# keep me\nbatch_size: 5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveProfile(path, "offline", Profile{Store: StoreLocal, Local: LocalStoreConfig{Dimension: 64}}, true); err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# keep me") {
+		t.Errorf("comment was dropped:\n%s", data)
+	}
+	if strings.Contains(string(data), "similarity") {
+		t.Errorf("empty settings were saved:\n%s", data)
+	}
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if cfg.BatchSize != 5 || cfg.DefaultProfile != "offline" || cfg.Profiles["offline"].Local.Dimension != 64 {
+		t.Errorf("unexpected config after save: %+v", cfg)
+	}
+}
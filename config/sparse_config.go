@@ -0,0 +1,28 @@
+package config
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var unsafeFileCharsRegex = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// SparseStatsPath returns the file that keeps the BM25 corpus statistics of
+// the selected index between ingestion runs: next to the journal for the
+// local store, or in the user config directory named after the Upstash host
+func (c *Config) SparseStatsPath() string {
+	if c.UsesLocalStore() {
+		return c.Local.StorePath() + ".bm25.json"
+	}
+	name := c.Upstash.URL
+	if u, err := url.Parse(c.Upstash.URL); err == nil && u.Host != "" {
+		name = u.Host
+	}
+	name = strings.Trim(unsafeFileCharsRegex.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(filepath.Dir(GetDefaultConfigPath()), "bm25", name+".json")
+}
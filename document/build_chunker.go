@@ -0,0 +1,257 @@
+package document
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	makeTargetRegex     = regexp.MustCompile(`^([^\s:=#][^:=#]*?)\s*::?(?:[^=]|$)`)
+	makeHelpRegex       = regexp.MustCompile(`##\s*(.*)$`)
+	dockerFromRegex     = regexp.MustCompile(`(?mi)^FROM\s+(?:--platform=\S+\s+)?(\S+)(?:\s+AS\s+(\S+))?`)
+	goModModuleRegex    = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	goModGoVersionRegex = regexp.MustCompile(`(?m)^go\s+(\S+)`)
+	goModRequireRegex   = regexp.MustCompile(`^\s*(?:require\s+)?([\w.\-/~]+\.[\w.\-/~]+)\s+(v\S+)(\s*//\s*indirect)?`)
+)
+
+// buildFileType identifies build files that are recognised by name rather than extension
+func buildFileType(relativePath string) string {
+	name := strings.ToLower(filepath.Base(relativePath))
+	switch {
+	case name == "makefile" || name == "gnumakefile" || strings.HasSuffix(name, ".mk"):
+		return "makefile"
+	case name == "dockerfile" || strings.HasPrefix(name, "dockerfile-") || strings.HasPrefix(name, "dockerfile.") || strings.HasSuffix(name, ".dockerfile"):
+		return "dockerfile"
+	case (strings.HasPrefix(name, "docker-compose") || strings.HasPrefix(name, "compose")) &&
+		(strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")):
+		return "compose"
+	case name == "go.mod":
+		return "gomod"
+	}
+	return ""
+}
+
+// chunkMakefile creates one chunk per target, keeping the comments above it
+// and recording the "##" help text used by self-documenting Makefiles
+func (c *Chunker) chunkMakefile(content string) ([]Chunk, error) {
+	var decls []declaration
+	offset := 0
+	inRecipe := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t") && inRecipe:
+			// Recipe line of the current target
+		case strings.TrimSpace(line) == "":
+			// Blank lines do not end a recipe
+		default:
+			inRecipe = false
+			m := makeTargetRegex.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			target := strings.TrimSpace(m[1])
+			if strings.HasPrefix(target, ".") && !strings.Contains(target, "/") {
+				// Special targets such as .PHONY are not chunked on their own
+				break
+			}
+			inRecipe = true
+			d := declaration{offset: offset, symbol: target, kind: "target"}
+			if help := makeHelpRegex.FindStringSubmatch(strings.TrimRight(line, "\r\n")); help != nil {
+				d.metadata = Metadata{"help": strings.TrimSpace(help[1])}
+			}
+			decls = append(decls, d)
+		}
+		offset += len(line)
+	}
+
+	chunks := c.chunkDeclarations(content, decls, "makefile_target", []string{"#"})
+	if len(chunks) == 0 {
+		return c.chunkText(content)
+	}
+	return chunks, nil
+}
+
+// chunkDockerfile creates one chunk per build stage
+func (c *Chunker) chunkDockerfile(content string) ([]Chunk, error) {
+	var decls []declaration
+	for i, m := range dockerFromRegex.FindAllStringSubmatchIndex(content, -1) {
+		image := content[m[2]:m[3]]
+		stage := ""
+		if m[4] >= 0 {
+			stage = content[m[4]:m[5]]
+		}
+		if stage == "" {
+			stage = fmt.Sprintf("stage-%d", i)
+		}
+		decls = append(decls, declaration{
+			offset:   m[0],
+			symbol:   stage,
+			kind:     "stage",
+			metadata: Metadata{"base_image": image},
+		})
+	}
+
+	chunks := c.chunkDeclarations(content, decls, "dockerfile_stage", []string{"#"})
+	if len(chunks) == 0 {
+		return c.chunkText(content)
+	}
+	return chunks, nil
+}
+
+// chunkCompose creates one chunk per service of a docker-compose file. Other
+// top-level sections (networks, volumes, ...) get a chunk each.
+func (c *Chunker) chunkCompose(content string) ([]Chunk, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &root); err != nil || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return c.chunkConfig(content)
+	}
+
+	lineOffsets := []int{0}
+	for i, r := range content {
+		if r == '\n' {
+			lineOffsets = append(lineOffsets, i+1)
+		}
+	}
+	offsetOf := func(line int) int {
+		if line-1 < len(lineOffsets) {
+			return lineOffsets[line-1]
+		}
+		return len(content)
+	}
+
+	var decls []declaration
+	top := root.Content[0]
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		key, value := top.Content[i], top.Content[i+1]
+		if key.Value != "services" || value.Kind != yaml.MappingNode {
+			decls = append(decls, declaration{offset: offsetOf(key.Line), symbol: key.Value, kind: "section"})
+			continue
+		}
+
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			name, service := value.Content[j], value.Content[j+1]
+			offset := offsetOf(name.Line)
+			if j == 0 {
+				// Keep the "services:" line with the first service
+				offset = offsetOf(key.Line)
+			}
+			decls = append(decls, declaration{
+				offset:   offset,
+				symbol:   name.Value,
+				kind:     "service",
+				metadata: composeServiceMetadata(service),
+			})
+		}
+	}
+
+	chunks := c.chunkDeclarations(content, decls, "compose_service", []string{"#"})
+	if len(chunks) == 0 {
+		return c.chunkConfig(content)
+	}
+	return chunks, nil
+}
+
+func composeServiceMetadata(service *yaml.Node) Metadata {
+	metadata := make(Metadata)
+	if service.Kind != yaml.MappingNode {
+		return metadata
+	}
+	for i := 0; i+1 < len(service.Content); i += 2 {
+		key, value := service.Content[i].Value, service.Content[i+1]
+		switch key {
+		case "image":
+			metadata["image"] = value.Value
+		case "depends_on":
+			var deps []string
+			if value.Kind == yaml.SequenceNode {
+				for _, d := range value.Content {
+					deps = append(deps, d.Value)
+				}
+			} else if value.Kind == yaml.MappingNode {
+				for k := 0; k < len(value.Content); k += 2 {
+					deps = append(deps, value.Content[k].Value)
+				}
+			}
+			metadata["depends_on"] = deps
+		case "ports":
+			var ports []string
+			for _, p := range value.Content {
+				if p.Kind == yaml.ScalarNode {
+					ports = append(ports, p.Value)
+				}
+			}
+			metadata["ports"] = ports
+		}
+	}
+	return metadata
+}
+
+// chunkGoMod records the module path, Go version and dependencies as metadata.
+// Small files stay in one chunk; larger ones are split into paragraphs.
+func (c *Chunker) chunkGoMod(content string) ([]Chunk, error) {
+	metadata := Metadata{
+		"chunk_type": "go_mod",
+	}
+	if m := goModModuleRegex.FindStringSubmatch(content); m != nil {
+		metadata["module"] = m[1]
+		metadata["symbol"] = m[1]
+		metadata["kind"] = "module"
+	}
+	if m := goModGoVersionRegex.FindStringSubmatch(content); m != nil {
+		metadata["go_version"] = m[1]
+	}
+
+	var direct, indirect []string
+	inRequire := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "require ("):
+			inRequire = true
+			continue
+		case inRequire && trimmed == ")":
+			inRequire = false
+			continue
+		case !inRequire && !strings.HasPrefix(trimmed, "require "):
+			continue
+		}
+		m := goModRequireRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		dep := m[1] + "@" + m[2]
+		if m[3] != "" {
+			indirect = append(indirect, dep)
+		} else {
+			direct = append(direct, dep)
+		}
+	}
+	if len(direct) > 0 {
+		metadata["dependencies"] = direct
+	}
+	if len(indirect) > 0 {
+		metadata["indirect_dependencies"] = indirect
+	}
+
+	var chunks []Chunk
+	if strings.TrimSpace(content) == "" {
+		return chunks, nil
+	}
+	if len(content) <= c.maxChunkSize {
+		chunks = []Chunk{{Index: 0, Content: strings.TrimSpace(content)}}
+	} else {
+		var err error
+		if chunks, err = c.chunkText(content); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range chunks {
+		chunks[i].Metadata = metadata.Clone()
+	}
+	return chunks, nil
+}
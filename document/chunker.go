@@ -1,6 +1,7 @@
 package document
 
 import (
+	"context"
 	"fmt"
 	"github.com/typicalfo/prj-start/logger"
 	"regexp"
@@ -10,11 +11,19 @@ import (
 type Chunk struct {
 	Index    int
 	Content  string
-	Metadata map[string]string
+	Metadata Metadata
+
+	// Location of the chunk inside the file: 1-based lines and byte offsets
+	// into FileInfo.Content. Zero when the chunk could not be located.
+	StartLine int
+	EndLine   int
+	StartByte int
+	EndByte   int
 }
 
 type Chunker struct {
 	maxChunkSize int
+	plugins      []*Plugin
 }
 
 func NewChunker(maxChunkSize int) *Chunker {
@@ -26,109 +35,126 @@ func NewChunker(maxChunkSize int) *Chunker {
 	}
 }
 
+// RegisterPlugin adds an external chunker. Plugins are consulted in
+// registration order before the built-in strategies.
+func (c *Chunker) RegisterPlugin(plugin *Plugin) {
+	c.plugins = append(c.plugins, plugin)
+}
+
 func (c *Chunker) ChunkDocument(fileInfo FileInfo) ([]Chunk, error) {
 	logger.LogInfo(fmt.Sprintf("Chunking document: %s", fileInfo.RelativePath))
 
 	content := fileInfo.Content
 	ext := strings.ToLower(fileInfo.Extension)
 
-	var chunks []Chunk
-	var err error
+	chunks, err := c.chunkWithPlugin(fileInfo)
+	if err != nil {
+		// A failing plugin must not abort ingestion; fall back to built-in chunking
+		logger.LogError(fmt.Sprintf("Plugin chunking failed for %s, using built-in strategy: %v", fileInfo.RelativePath, err))
+		chunks = nil
+	}
 
-	switch ext {
-	case ".go":
-		chunks, err = c.chunkGoCode(content)
-	case ".md":
-		chunks, err = c.chunkMarkdown(content)
-	case ".sql":
-		chunks, err = c.chunkSQL(content)
-	case ".json", ".yaml", ".yml", ".toml":
-		chunks, err = c.chunkConfig(content)
-	case ".html":
-		chunks, err = c.chunkHTML(content)
-	default:
-		chunks, err = c.chunkText(content)
+	if len(chunks) == 0 {
+		chunks, err = c.chunkBuiltin(fileInfo.RelativePath, ext, content)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("error chunking %s: %w", fileInfo.RelativePath, err)
 	}
 
+	locateChunks(content, chunks)
+
 	// Add file metadata to each chunk
 	for i := range chunks {
 		if chunks[i].Metadata == nil {
-			chunks[i].Metadata = make(map[string]string)
+			chunks[i].Metadata = make(Metadata)
 		}
 		chunks[i].Metadata["filename"] = fileInfo.RelativePath
 		chunks[i].Metadata["topic"] = fileInfo.Topic
 		chunks[i].Metadata["extension"] = ext
-		chunks[i].Metadata["total_chunks"] = fmt.Sprintf("%d", len(chunks))
+		chunks[i].Metadata["total_chunks"] = len(chunks)
+		for key, value := range fileInfo.Metadata {
+			chunks[i].Metadata[key] = value
+		}
 	}
 
 	logger.LogSuccess(fmt.Sprintf("Created %d chunks for %s", len(chunks), fileInfo.RelativePath))
 	return chunks, nil
 }
 
-func (c *Chunker) chunkGoCode(content string) ([]Chunk, error) {
-	var chunks []Chunk
-
-	// Split by major Go constructs
-	funcRegex := regexp.MustCompile(`(?m)^(func\s+\w+.*?{)`)
-	typeRegex := regexp.MustCompile(`(?m)^(type\s+\w+\s+(struct|interface)\s*{)`)
-	varRegex := regexp.MustCompile(`(?m)^(var\s+.*?\()`)
-	constRegex := regexp.MustCompile(`(?m)^(const\s+.*?\()`)
-
-	// Find all split points
-	splitPoints := []int{0}
-
-	matches := funcRegex.FindAllStringIndex(content, -1)
-	for _, match := range matches {
-		splitPoints = append(splitPoints, match[0])
+// chunkWithPlugin runs the first plugin that matches the file, if any
+func (c *Chunker) chunkWithPlugin(fileInfo FileInfo) ([]Chunk, error) {
+	for _, plugin := range c.plugins {
+		if !plugin.Matches(fileInfo) {
+			continue
+		}
+		logger.LogInfo(fmt.Sprintf("Chunking %s with plugin %s", fileInfo.RelativePath, plugin.Name))
+		return plugin.Run(context.Background(), fileInfo)
 	}
+	return nil, nil
+}
 
-	matches = typeRegex.FindAllStringIndex(content, -1)
-	for _, match := range matches {
-		splitPoints = append(splitPoints, match[0])
+func (c *Chunker) chunkBuiltin(relativePath, ext, content string) ([]Chunk, error) {
+	// Build files are recognised by name before falling back to the extension
+	switch buildFileType(relativePath) {
+	case "makefile":
+		return c.chunkMakefile(content)
+	case "dockerfile":
+		return c.chunkDockerfile(content)
+	case "compose":
+		return c.chunkCompose(content)
+	case "gomod":
+		return c.chunkGoMod(content)
 	}
 
-	matches = varRegex.FindAllStringIndex(content, -1)
-	for _, match := range matches {
-		splitPoints = append(splitPoints, match[0])
-	}
+	var chunks []Chunk
+	var err error
 
-	matches = constRegex.FindAllStringIndex(content, -1)
-	for _, match := range matches {
-		splitPoints = append(splitPoints, match[0])
+	switch ext {
+	case ".go":
+		chunks, err = c.chunkGoCode(content)
+	case ".md":
+		chunks, err = c.chunkMarkdown(content)
+	case ".sql":
+		chunks, err = c.chunkSQL(content)
+	case ".json", ".yaml", ".yml", ".toml":
+		chunks, err = c.chunkConfig(content)
+	case ".html":
+		chunks, err = c.chunkHTML(content)
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+		chunks, err = c.chunkJavaScript(content)
+	case ".py", ".pyi":
+		chunks, err = c.chunkPython(content)
+	case ".sh", ".bash", ".zsh":
+		chunks, err = c.chunkShell(content)
+	default:
+		// Extensionless scripts are recognised by their shebang
+		if lang := shebangLanguage(content); lang != "" {
+			return c.chunkBuiltin("", lang, content)
+		}
+		chunks, err = c.chunkText(content)
 	}
 
-	// Sort and deduplicate split points
-	splitPoints = c.uniqueSortedInts(splitPoints)
-
-	// Create chunks
-	for i := 0; i < len(splitPoints); i++ {
-		start := splitPoints[i]
-		end := len(content)
-		if i+1 < len(splitPoints) {
-			end = splitPoints[i+1]
-		}
+	return chunks, err
+}
 
-		chunk := strings.TrimSpace(content[start:end])
-		if len(chunk) > 0 {
-			chunks = append(chunks, Chunk{
-				Index:   i,
-				Content: chunk,
-				Metadata: map[string]string{
-					"chunk_type": "go_construct",
-				},
-			})
-		}
-	}
+func (c *Chunker) chunkGoCode(content string) ([]Chunk, error) {
+	// Split by major Go constructs: functions, methods, types and var/const blocks
+	decls := goDeclarations(content)
+	chunks := c.chunkDeclarations(content, decls, "go_construct", []string{"//"})
 
 	if len(chunks) == 0 {
 		// Fallback to text chunking
 		return c.chunkText(content)
 	}
 
+	// Every chunk carries the file's imports, so they can be filtered on
+	if imports := goImports(content); len(imports) > 0 {
+		for i := range chunks {
+			chunks[i].Metadata["imports"] = imports
+		}
+	}
+
 	return chunks, nil
 }
 
@@ -146,6 +172,10 @@ func (c *Chunker) chunkMarkdown(content string) ([]Chunk, error) {
 
 	splitPoints = c.uniqueSortedInts(splitPoints)
 
+	// Track the enclosing headings so each section knows its breadcrumb
+	var headings []string
+	var levels []int
+
 	for i := 0; i < len(splitPoints); i++ {
 		start := splitPoints[i]
 		end := len(content)
@@ -153,14 +183,32 @@ func (c *Chunker) chunkMarkdown(content string) ([]Chunk, error) {
 			end = splitPoints[i+1]
 		}
 
+		if strings.HasPrefix(content[start:], "#") {
+			line := content[start:end]
+			if idx := strings.Index(line, "\n"); idx >= 0 {
+				line = line[:idx]
+			}
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			for len(levels) > 0 && levels[len(levels)-1] >= level {
+				levels = levels[:len(levels)-1]
+				headings = headings[:len(headings)-1]
+			}
+			levels = append(levels, level)
+			headings = append(headings, strings.TrimSpace(strings.TrimLeft(line, "#")))
+		}
+
 		chunk := strings.TrimSpace(content[start:end])
 		if len(chunk) > 0 {
+			metadata := Metadata{
+				"chunk_type": "markdown_section",
+			}
+			if len(headings) > 0 {
+				metadata["heading"] = strings.Join(headings, " > ")
+			}
 			chunks = append(chunks, Chunk{
-				Index:   i,
-				Content: chunk,
-				Metadata: map[string]string{
-					"chunk_type": "markdown_section",
-				},
+				Index:    len(chunks),
+				Content:  chunk,
+				Metadata: metadata,
 			})
 		}
 	}
@@ -178,13 +226,13 @@ func (c *Chunker) chunkSQL(content string) ([]Chunk, error) {
 	// Split by semicolons
 	statements := strings.Split(content, ";")
 
-	for i, stmt := range statements {
+	for _, stmt := range statements {
 		stmt = strings.TrimSpace(stmt)
 		if len(stmt) > 0 {
 			chunks = append(chunks, Chunk{
-				Index:   i,
+				Index:   len(chunks),
 				Content: stmt + ";",
-				Metadata: map[string]string{
+				Metadata: Metadata{
 					"chunk_type": "sql_statement",
 				},
 			})
@@ -205,13 +253,13 @@ func (c *Chunker) chunkConfig(content string) ([]Chunk, error) {
 	// Simple approach: split by double newlines for major sections
 	sections := regexp.MustCompile(`\n\s*\n`).Split(content, -1)
 
-	for i, section := range sections {
+	for _, section := range sections {
 		section = strings.TrimSpace(section)
 		if len(section) > 0 {
 			chunks = append(chunks, Chunk{
-				Index:   i,
+				Index:   len(chunks),
 				Content: section,
-				Metadata: map[string]string{
+				Metadata: Metadata{
 					"chunk_type": "config_section",
 				},
 			})
@@ -249,9 +297,9 @@ func (c *Chunker) chunkHTML(content string) ([]Chunk, error) {
 		chunk := strings.TrimSpace(content[start:end])
 		if len(chunk) > 0 {
 			chunks = append(chunks, Chunk{
-				Index:   i,
+				Index:   len(chunks),
 				Content: chunk,
-				Metadata: map[string]string{
+				Metadata: Metadata{
 					"chunk_type": "html_section",
 				},
 			})
@@ -286,7 +334,7 @@ func (c *Chunker) chunkText(content string) ([]Chunk, error) {
 			chunks = append(chunks, Chunk{
 				Index:   chunkIndex,
 				Content: currentChunk.String(),
-				Metadata: map[string]string{
+				Metadata: Metadata{
 					"chunk_type": "text_paragraph",
 				},
 			})
@@ -307,7 +355,7 @@ func (c *Chunker) chunkText(content string) ([]Chunk, error) {
 		chunks = append(chunks, Chunk{
 			Index:   chunkIndex,
 			Content: currentChunk.String(),
-			Metadata: map[string]string{
+			Metadata: Metadata{
 				"chunk_type": "text_paragraph",
 			},
 		})
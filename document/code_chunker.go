@@ -0,0 +1,410 @@
+package document
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// declaration marks the start of a named construct inside a source file
+type declaration struct {
+	offset   int
+	symbol   string
+	kind     string
+	metadata Metadata // extra per-declaration metadata
+}
+
+// declarationPattern extracts declarations of one kind; symbolGroup is the
+// regexp submatch holding the symbol name
+type declarationPattern struct {
+	regex       *regexp.Regexp
+	kind        string
+	symbolGroup int
+}
+
+var (
+	goDeclarationPatterns = []declarationPattern{
+		{regexp.MustCompile(`(?m)^func\s+(\w+)`), "function", 1},
+		{regexp.MustCompile(`(?m)^type\s+(\w+)\s+struct\b`), "struct", 1},
+		{regexp.MustCompile(`(?m)^type\s+(\w+)\s+interface\b`), "interface", 1},
+		{regexp.MustCompile(`(?m)^type\s+(\w+)`), "type", 1},
+		{regexp.MustCompile(`(?m)^var\s+\(`), "var", 0},
+		{regexp.MustCompile(`(?m)^const\s+\(`), "const", 0},
+	}
+	goMethodRegex = regexp.MustCompile(`(?m)^func\s+\(\s*(?:\w+\s+)?\*?\s*(\w+)[^)]*\)\s*(\w+)`)
+	goImportRegex = regexp.MustCompile(`(?ms)^import\s*(?:\((.*?)^\)|(?:[\w.]+\s+)?("[^"]+"))`)
+	goImportPath  = regexp.MustCompile(`"([^"]+)"`)
+
+	jsDeclarationPatterns = []declarationPattern{
+		{regexp.MustCompile(`(?m)^(?:export\s+(?:default\s+)?)?(?:async\s+)?function\s*\*?\s*(\w+)`), "function", 1},
+		{regexp.MustCompile(`(?m)^(?:export\s+(?:default\s+)?)?(?:abstract\s+)?class\s+(\w+)`), "class", 1},
+		{regexp.MustCompile(`(?m)^(?:export\s+)?(?:const|let|var)\s+(\w+)\s*(?::[^=\n]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=\n]+)?=>|\w+\s*=>)`), "function", 1},
+		{regexp.MustCompile(`(?m)^(?:export\s+)?(?:declare\s+)?interface\s+(\w+)`), "interface", 1},
+		{regexp.MustCompile(`(?m)^(?:export\s+)?type\s+(\w+)\s*(?:<[^>\n]*>)?\s*=`), "type", 1},
+		{regexp.MustCompile(`(?m)^(?:export\s+)?(?:const\s+)?enum\s+(\w+)`), "enum", 1},
+		{regexp.MustCompile(`(?m)^export\s+(?:const|let|var)\s+(\w+)`), "export", 1},
+		{regexp.MustCompile(`(?m)^export\s+(default)\b`), "export", 1},
+		{regexp.MustCompile(`(?m)^(module\.exports|exports\.\w+)\s*=`), "export", 1},
+	}
+
+	shellDeclarationPatterns = []declarationPattern{
+		{regexp.MustCompile(`(?m)^function\s+([\w:.-]+)`), "function", 1},
+		{regexp.MustCompile(`(?m)^([\w:.-]+)\s*\(\s*\)`), "function", 1},
+	}
+
+	pythonDeclarationRegex = regexp.MustCompile(`^(?:async\s+)?(def|class)\s+(\w+)`)
+	pythonMethodRegex      = regexp.MustCompile(`^(\s+)(?:async\s+)?def\s+(\w+)`)
+	pythonHeaderEndRegex   = regexp.MustCompile(`:[ \t]*(?:#[^\n]*)?\n`)
+	pythonDocstringRegex   = regexp.MustCompile(`(?s)^\s*[rRuU]?("""|''')\s*(.*?)("""|''')`)
+)
+
+// findDeclarations runs all patterns against content. When several patterns
+// match at the same offset the first pattern in the list wins.
+func findDeclarations(content string, patterns []declarationPattern) []declaration {
+	byOffset := make(map[int]declaration)
+	for _, p := range patterns {
+		for _, m := range p.regex.FindAllStringSubmatchIndex(content, -1) {
+			if _, exists := byOffset[m[0]]; exists {
+				continue
+			}
+			symbol := ""
+			if p.symbolGroup > 0 && m[2*p.symbolGroup] >= 0 {
+				symbol = content[m[2*p.symbolGroup]:m[2*p.symbolGroup+1]]
+			}
+			byOffset[m[0]] = declaration{offset: m[0], symbol: symbol, kind: p.kind}
+		}
+	}
+
+	decls := make([]declaration, 0, len(byOffset))
+	for _, d := range byOffset {
+		decls = append(decls, d)
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].offset < decls[j].offset })
+	return decls
+}
+
+// leadingCommentStart moves offset back over the comment (or decorator) lines
+// directly above a declaration so they stay in the same chunk
+func leadingCommentStart(content string, offset int, prefixes []string) int {
+	start := offset
+	for start > 0 {
+		prevEnd := start - 1 // the newline terminating the previous line
+		prevStart := strings.LastIndex(content[:prevEnd], "\n") + 1
+		line := strings.TrimSpace(content[prevStart:prevEnd])
+		if line == "" || strings.HasPrefix(line, "#!") || !hasAnyPrefix(line, prefixes) {
+			break
+		}
+		start = prevStart
+	}
+	return start
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// chunkDeclarations splits content at each declaration. Content before the
+// first declaration (package clause, imports, shebang) becomes its own chunk.
+func (c *Chunker) chunkDeclarations(content string, decls []declaration, chunkType string, commentPrefixes []string) []Chunk {
+	for i := range decls {
+		decls[i].offset = leadingCommentStart(content, decls[i].offset, commentPrefixes)
+	}
+
+	var chunks []Chunk
+	addChunk := func(start, end int, symbol, kind string, extra Metadata) {
+		text := strings.TrimSpace(content[start:end])
+		if len(text) == 0 {
+			return
+		}
+		metadata := Metadata{
+			"chunk_type": chunkType,
+		}
+		for k, v := range extra {
+			metadata[k] = v
+		}
+		if symbol != "" {
+			metadata["symbol"] = symbol
+		}
+		if kind != "" {
+			metadata["kind"] = kind
+		}
+		chunks = append(chunks, Chunk{
+			Index:    len(chunks),
+			Content:  text,
+			Metadata: metadata,
+		})
+	}
+
+	for i, d := range decls {
+		if i == 0 && d.offset > 0 {
+			addChunk(0, d.offset, "", "", nil)
+		}
+		end := len(content)
+		if i+1 < len(decls) {
+			end = decls[i+1].offset
+		}
+		addChunk(d.offset, end, d.symbol, d.kind, d.metadata)
+	}
+
+	return chunks
+}
+
+// goDeclarations finds top-level Go declarations, naming methods Receiver.Method
+func goDeclarations(content string) []declaration {
+	decls := findDeclarations(content, goDeclarationPatterns)
+	for _, m := range goMethodRegex.FindAllStringSubmatchIndex(content, -1) {
+		decls = append(decls, declaration{
+			offset: m[0],
+			symbol: content[m[2]:m[3]] + "." + content[m[4]:m[5]],
+			kind:   "method",
+		})
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].offset < decls[j].offset })
+	return decls
+}
+
+// goImports lists the import paths of a Go file in order of appearance
+func goImports(content string) []string {
+	var imports []string
+	for _, m := range goImportRegex.FindAllStringSubmatch(content, -1) {
+		for _, path := range goImportPath.FindAllStringSubmatch(m[1]+m[2], -1) {
+			imports = append(imports, path[1])
+		}
+	}
+	return imports
+}
+
+func (c *Chunker) chunkJavaScript(content string) ([]Chunk, error) {
+	decls := findDeclarations(content, jsDeclarationPatterns)
+	chunks := c.chunkDeclarations(content, decls, "js_construct", []string{"//", "/*", "*", "@"})
+	if len(chunks) == 0 {
+		return c.chunkText(content)
+	}
+	return chunks, nil
+}
+
+func (c *Chunker) chunkShell(content string) ([]Chunk, error) {
+	decls := findDeclarations(content, shellDeclarationPatterns)
+	chunks := c.chunkDeclarations(content, decls, "shell_construct", []string{"#"})
+	if len(chunks) == 0 {
+		return c.chunkText(content)
+	}
+	return chunks, nil
+}
+
+// chunkPython splits a module into top-level def/class blocks. A block ends
+// at the first non-blank line back at column zero, so module-level code that
+// follows a function is not folded into it. Classes larger than the chunk
+// size are split further into their methods.
+func (c *Chunker) chunkPython(content string) ([]Chunk, error) {
+	lines := strings.SplitAfter(content, "\n")
+
+	var chunks []Chunk
+	addChunk := func(text, symbol, kind string) {
+		text = strings.TrimSpace(text)
+		if len(text) == 0 {
+			return
+		}
+		metadata := Metadata{
+			"chunk_type": "python_construct",
+		}
+		if symbol != "" {
+			metadata["symbol"] = symbol
+			metadata["kind"] = kind
+			if doc := pythonDocstring(text); doc != "" {
+				metadata["docstring"] = doc
+			}
+		}
+		chunks = append(chunks, Chunk{
+			Index:    len(chunks),
+			Content:  text,
+			Metadata: metadata,
+		})
+	}
+
+	var pending strings.Builder // decorators, comments and module-level code
+	i := 0
+	for i < len(lines) {
+		line := lines[i]
+		m := pythonDeclarationRegex.FindStringSubmatch(line)
+		if m == nil {
+			pending.WriteString(line)
+			i++
+			continue
+		}
+
+		// Decorators and comments directly above belong to the declaration
+		prefix := splitTrailingDecorators(pending.String())
+		addChunk(pending.String()[:len(pending.String())-len(prefix)], "", "")
+		pending.Reset()
+
+		end := pythonBlockEnd(lines, i)
+		block := prefix + strings.Join(lines[i:end], "")
+		kind := "function"
+		if m[1] == "class" {
+			kind = "class"
+		}
+
+		if kind == "class" && len(block) > c.maxChunkSize {
+			for _, part := range c.splitPythonClass(block, m[2]) {
+				addChunk(part.text, part.symbol, part.kind)
+			}
+		} else {
+			addChunk(block, m[2], kind)
+		}
+		i = end
+	}
+	addChunk(pending.String(), "", "")
+
+	if len(chunks) == 0 {
+		return c.chunkText(content)
+	}
+	return chunks, nil
+}
+
+type pythonPart struct {
+	text   string
+	symbol string
+	kind   string
+}
+
+// splitPythonClass splits a class block into its header (signature, docstring
+// and class attributes) and one part per method
+func (c *Chunker) splitPythonClass(block, className string) []pythonPart {
+	lines := strings.SplitAfter(block, "\n")
+
+	// Method indentation is the indentation of the first def inside the class
+	indent := ""
+	for _, line := range lines {
+		if m := pythonMethodRegex.FindStringSubmatch(line); m != nil {
+			indent = m[1]
+			break
+		}
+	}
+	if indent == "" {
+		return []pythonPart{{text: block, symbol: className, kind: "class"}}
+	}
+
+	var parts []pythonPart
+	var current strings.Builder
+	currentSymbol, currentKind := className, "class"
+	for _, line := range lines {
+		m := pythonMethodRegex.FindStringSubmatch(line)
+		if m != nil && m[1] == indent {
+			prefix := splitTrailingDecorators(current.String())
+			parts = append(parts, pythonPart{
+				text:   current.String()[:current.Len()-len(prefix)],
+				symbol: currentSymbol,
+				kind:   currentKind,
+			})
+			current.Reset()
+			current.WriteString(dedent(prefix, indent))
+			currentSymbol, currentKind = className+"."+m[2], "method"
+		}
+		if currentKind == "method" {
+			// Dedent method bodies so they read like top-level functions
+			line = strings.TrimPrefix(line, indent)
+		}
+		current.WriteString(line)
+	}
+	parts = append(parts, pythonPart{text: current.String(), symbol: currentSymbol, kind: currentKind})
+	return parts
+}
+
+func dedent(text, indent string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimPrefix(lines[i], indent)
+	}
+	return strings.Join(lines, "")
+}
+
+// pythonBlockEnd returns the index of the first line after the block that
+// starts at lines[start]
+func pythonBlockEnd(lines []string, start int) int {
+	end := start + 1
+	lastCode := start + 1
+	for end < len(lines) {
+		line := lines[end]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			// Blank lines do not end a block
+		case line[0] == ' ' || line[0] == '\t':
+			lastCode = end + 1
+		case strings.HasPrefix(trimmed, ")") || strings.HasPrefix(trimmed, "]") || strings.HasPrefix(trimmed, "}"):
+			// Closing bracket of a multi-line signature or literal
+			lastCode = end + 1
+		default:
+			return lastCode
+		}
+		end++
+	}
+	return lastCode
+}
+
+// splitTrailingDecorators returns the trailing decorator and comment lines of text
+func splitTrailingDecorators(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	start := len(lines)
+	for start > 0 {
+		trimmed := strings.TrimSpace(lines[start-1])
+		if !strings.HasPrefix(trimmed, "@") && !(strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "#!")) {
+			break
+		}
+		start--
+	}
+	return strings.Join(lines[start:], "")
+}
+
+// pythonDocstring returns the first line of a def/class docstring
+func pythonDocstring(block string) string {
+	// Skip decorators and comments before the signature
+	lines := strings.Split(block, "\n")
+	for len(lines) > 0 && pythonDeclarationRegex.FindString(strings.TrimSpace(lines[0])) == "" {
+		lines = lines[1:]
+	}
+	// The docstring is the first statement after the (possibly multi-line) signature
+	rest := strings.Join(lines, "\n")
+	loc := pythonHeaderEndRegex.FindStringIndex(rest)
+	if loc == nil {
+		return ""
+	}
+	m := pythonDocstringRegex.FindStringSubmatch(rest[loc[1]:])
+	if m == nil {
+		return ""
+	}
+	doc := strings.TrimSpace(m[2])
+	if idx := strings.Index(doc, "\n"); idx >= 0 {
+		doc = strings.TrimSpace(doc[:idx])
+	}
+	return doc
+}
+
+// shebangLanguage maps a "#!" interpreter line to the extension whose chunker handles it
+func shebangLanguage(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+	line := content
+	if idx := strings.Index(content, "\n"); idx >= 0 {
+		line = content[:idx]
+	}
+	switch {
+	case strings.Contains(line, "python"):
+		return ".py"
+	case strings.Contains(line, "node"), strings.Contains(line, "deno"), strings.Contains(line, "bun"):
+		return ".js"
+	case strings.HasSuffix(line, "sh"), strings.Contains(line, "bash"), strings.Contains(line, "zsh"), strings.Contains(line, "/sh "):
+		return ".sh"
+	}
+	return ""
+}
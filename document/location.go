@@ -0,0 +1,136 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+)
+
+// locateChunks records where each chunk came from inside the file as 1-based
+// start/end lines and byte offsets. Chunkers trim or re-join text, so chunks
+// are located by searching the file content in order rather than by tracking
+// offsets inside every strategy. Line ranges supplied by a plugin are kept, and
+// byte offsets are derived from them when the plugin gives none.
+func locateChunks(content string, chunks []Chunk) {
+	cursor := 0
+	for i := range chunks {
+		chunk := &chunks[i]
+		if startLine, ok := chunk.Metadata.Int("start_line"); ok {
+			chunk.StartLine = startLine
+			chunk.EndLine, _ = chunk.Metadata.Int("end_line")
+			if chunk.EndLine < chunk.StartLine {
+				chunk.EndLine = startLine
+			}
+			start, startOK := chunk.Metadata.Int("start_byte")
+			end, endOK := chunk.Metadata.Int("end_byte")
+			if !startOK || !endOK {
+				start, end, ok = lineRangeBytes(content, chunk.StartLine, chunk.EndLine)
+				if !ok {
+					continue
+				}
+			}
+			chunk.StartByte = start
+			chunk.EndByte = end
+			chunk.Metadata["end_line"] = chunk.EndLine
+			chunk.Metadata["start_byte"] = start
+			chunk.Metadata["end_byte"] = end
+			cursor = start + 1
+			continue
+		}
+
+		start, end, ok := locateChunk(content, chunk.Content, cursor)
+		if !ok {
+			continue
+		}
+		// Advance past the start only, so overlapping chunks are still found
+		cursor = start + 1
+
+		chunk.StartByte = start
+		chunk.EndByte = end
+		chunk.StartLine = strings.Count(content[:start], "\n") + 1
+		chunk.EndLine = chunk.StartLine + strings.Count(content[start:end], "\n")
+		if end > start && content[end-1] == '\n' {
+			chunk.EndLine--
+		}
+
+		if chunk.Metadata == nil {
+			chunk.Metadata = make(Metadata)
+		}
+		chunk.Metadata["start_line"] = chunk.StartLine
+		chunk.Metadata["end_line"] = chunk.EndLine
+		chunk.Metadata["start_byte"] = chunk.StartByte
+		chunk.Metadata["end_byte"] = chunk.EndByte
+	}
+}
+
+// lineRangeBytes returns the byte offsets spanning the 1-based lines start to
+// end of content, including the newline that ends the last line
+func lineRangeBytes(content string, start, end int) (int, int, bool) {
+	if start < 1 {
+		return 0, 0, false
+	}
+	line, startByte := 1, -1
+	if start == 1 {
+		startByte = 0
+	}
+	for i := 0; i < len(content); i++ {
+		if content[i] != '\n' {
+			continue
+		}
+		if line == end {
+			return startByte, i + 1, startByte >= 0
+		}
+		line++
+		if line == start {
+			startByte = i + 1
+		}
+	}
+	// The range runs to the end of a file without a trailing newline
+	if startByte < 0 || startByte > len(content) {
+		return 0, 0, false
+	}
+	return startByte, len(content), true
+}
+
+// locateChunk finds text in content at or after cursor. When the chunk is not
+// a verbatim substring (e.g. paragraphs re-joined by the text chunker) its
+// first and last lines are used as anchors instead.
+func locateChunk(content, text string, cursor int) (int, int, bool) {
+	if cursor > len(content) {
+		return 0, 0, false
+	}
+	if idx := strings.Index(content[cursor:], text); idx >= 0 && text != "" {
+		start := cursor + idx
+		return start, start + len(text), true
+	}
+
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	first := strings.TrimSpace(lines[0])
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if first == "" {
+		return 0, 0, false
+	}
+
+	idx := strings.Index(content[cursor:], first)
+	if idx < 0 {
+		return 0, 0, false
+	}
+	start := cursor + idx
+
+	lastIdx := strings.Index(content[start:], last)
+	if lastIdx < 0 {
+		return 0, 0, false
+	}
+	return start, start + lastIdx + len(last), true
+}
+
+// Citation formats a path:line reference such as "main.go:12-30"
+func Citation(path string, startLine, endLine int) string {
+	switch {
+	case path == "" || startLine <= 0:
+		return path
+	case endLine <= startLine:
+		return fmt.Sprintf("%s:%d", path, startLine)
+	default:
+		return fmt.Sprintf("%s:%d-%d", path, startLine, endLine)
+	}
+}
@@ -0,0 +1,59 @@
+package document
+
+import "testing"
+
+func TestLocateChunks(t *testing.T) {
+	content := "package main\n\nfunc a() {}\n\nfunc b() {\n\treturn\n}"
+	chunks := []Chunk{
+		{Content: "func a() {}"},
+		// A plugin chunk with a line range only
+		{Content: "func b() {\n\treturn\n}", Metadata: Metadata{"start_line": 5.0, "end_line": 7.0}},
+	}
+	locateChunks(content, chunks)
+
+	a := chunks[0]
+	if a.StartLine != 3 || a.EndLine != 3 || content[a.StartByte:a.EndByte] != "func a() {}" {
+		t.Errorf("chunk a at lines %d-%d, bytes %d-%d", a.StartLine, a.EndLine, a.StartByte, a.EndByte)
+	}
+	b := chunks[1]
+	if b.StartLine != 5 || b.EndLine != 7 || content[b.StartByte:b.EndByte] != "func b() {\n\treturn\n}" {
+		t.Errorf("plugin chunk b at lines %d-%d, bytes %d-%d", b.StartLine, b.EndLine, b.StartByte, b.EndByte)
+	}
+	for _, chunk := range chunks {
+		if _, ok := chunk.Metadata.Int("start_byte"); !ok {
+			t.Errorf("chunk %q lacks start_byte metadata", chunk.Content)
+		}
+		if end, _ := chunk.Metadata.Int("end_byte"); end != chunk.EndByte {
+			t.Errorf("chunk %q has end_byte %d, want %d", chunk.Content, end, chunk.EndByte)
+		}
+	}
+}
+
+func TestLocateChunksKeepsPluginBytes(t *testing.T) {
+	chunks := []Chunk{{Content: "x", Metadata: Metadata{"start_line": 1, "end_line": 1, "start_byte": 2, "end_byte": 3}}}
+	locateChunks("a x\n", chunks)
+	if chunks[0].StartByte != 2 || chunks[0].EndByte != 3 {
+		t.Errorf("plugin byte offsets replaced: %d-%d", chunks[0].StartByte, chunks[0].EndByte)
+	}
+}
+
+func TestLineRangeBytes(t *testing.T) {
+	content := "one\ntwo\nthree\n"
+	tests := []struct {
+		start, end int
+		want       string
+		ok         bool
+	}{
+		{1, 1, "one\n", true},
+		{2, 3, "two\nthree\n", true},
+		{3, 9, "three\n", true},
+		{0, 1, "", false},
+		{5, 6, "", false},
+	}
+	for _, tt := range tests {
+		start, end, ok := lineRangeBytes(content, tt.start, tt.end)
+		if ok != tt.ok || (ok && content[start:end] != tt.want) {
+			t.Errorf("lines %d-%d: got %q (%v), want %q (%v)", tt.start, tt.end, content[start:end], ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metadata holds typed chunk metadata: strings, numbers, booleans, string
+// arrays and nested objects, stored as-is in the vector index so numeric and
+// array filters work
+type Metadata map[string]interface{}
+
+// String returns the value of key as a string, or "" when it is missing
+func (m Metadata) String(key string) string {
+	switch v := m[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Int returns the value of key as an int. Numeric strings from metadata
+// written before typed values are accepted.
+func (m Metadata) Int(key string) (int, bool) {
+	value, err := coerceInt(m[key])
+	return value, err == nil
+}
+
+// Clone returns a shallow copy
+func (m Metadata) Clone() Metadata {
+	clone := make(Metadata, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// FieldType is the declared type of a metadata key
+type FieldType string
+
+const (
+	FieldString  FieldType = "string"
+	FieldInt     FieldType = "int"
+	FieldFloat   FieldType = "float"
+	FieldBool    FieldType = "bool"
+	FieldStrings FieldType = "string_array"
+	FieldObject  FieldType = "object"
+)
+
+// ParseFieldType validates a field type name from configuration
+func ParseFieldType(name string) (FieldType, error) {
+	switch t := FieldType(strings.ToLower(strings.TrimSpace(name))); t {
+	case FieldString, FieldInt, FieldFloat, FieldBool, FieldStrings, FieldObject:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown metadata type %q (use string, int, float, bool, string_array or object)", name)
+}
+
+// Schema declares the type of each metadata key. Keys without a declaration
+// are stored as given.
+type Schema map[string]FieldType
+
+// DefaultSchema declares the keys written by the built-in chunkers and the upserter
+var DefaultSchema = Schema{
+	"chunk_index":           FieldInt,
+	"total_chunks":          FieldInt,
+	"file_size":             FieldInt,
+	"start_line":            FieldInt,
+	"end_line":              FieldInt,
+	"start_byte":            FieldInt,
+	"end_byte":              FieldInt,
+	"child_count":           FieldInt,
+	"context_header_length": FieldInt,
+
+	"dependencies":          FieldStrings,
+	"indirect_dependencies": FieldStrings,
+	"depends_on":            FieldStrings,
+	"ports":                 FieldStrings,
+	"imports":               FieldStrings,
+	"tags":                  FieldStrings,
+
+	"source_file":  FieldString,
+	"filename":     FieldString,
+	"full_path":    FieldString,
+	"namespace":    FieldString,
+	"recipe_name":  FieldString,
+	"project_type": FieldString,
+	"topic":        FieldString,
+	"extension":    FieldString,
+	"chunk_type":   FieldString,
+	"symbol":       FieldString,
+	"kind":         FieldString,
+	"heading":      FieldString,
+	"level":        FieldString,
+	"parent_id":    FieldString,
+	"file_id":      FieldString,
+}
+
+// ParseSchema builds a schema from key-to-type names, on top of DefaultSchema
+func ParseSchema(types map[string]string) (Schema, error) {
+	schema := make(Schema, len(DefaultSchema)+len(types))
+	for key, t := range DefaultSchema {
+		schema[key] = t
+	}
+	for key, name := range types {
+		t, err := ParseFieldType(name)
+		if err != nil {
+			return nil, fmt.Errorf("metadata key '%s': %w", key, err)
+		}
+		schema[key] = t
+	}
+	return schema, nil
+}
+
+// Keys returns the declared keys in sorted order
+func (s Schema) Keys() []string {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Normalize returns a copy of metadata with every declared key converted to
+// its type. String values from untyped metadata, such as "12" for an int or
+// "a,b" for a string array, are converted; values that cannot be are an error.
+func (s Schema) Normalize(metadata Metadata) (Metadata, error) {
+	normalized := make(Metadata, len(metadata))
+	for key, value := range metadata {
+		t, declared := s[key]
+		if !declared || value == nil {
+			normalized[key] = value
+			continue
+		}
+		converted, err := coerce(value, t)
+		if err != nil {
+			return nil, fmt.Errorf("metadata key '%s': %w", key, err)
+		}
+		normalized[key] = converted
+	}
+	return normalized, nil
+}
+
+func coerce(value interface{}, t FieldType) (interface{}, error) {
+	switch t {
+	case FieldString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case bool, int, int64, float64, json.Number:
+			return fmt.Sprint(v), nil
+		}
+	case FieldInt:
+		return coerceInt(value)
+	case FieldFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case json.Number:
+			return v.Float64()
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+	case FieldBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(strings.TrimSpace(v))
+		}
+	case FieldStrings:
+		switch v := value.(type) {
+		case []string:
+			return v, nil
+		case []interface{}:
+			values := make([]string, len(v))
+			for i, item := range v {
+				values[i] = fmt.Sprint(item)
+			}
+			return values, nil
+		case string:
+			values := []string{}
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+			return values, nil
+		}
+	case FieldObject:
+		switch v := value.(type) {
+		case map[string]interface{}:
+			return v, nil
+		case Metadata:
+			return map[string]interface{}(v), nil
+		case string:
+			var object map[string]interface{}
+			if err := json.Unmarshal([]byte(v), &object); err != nil {
+				return nil, fmt.Errorf("expected a JSON object: %w", err)
+			}
+			return object, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %T %v to %s", value, value, t)
+}
+
+func coerceInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		return int(v), nil
+	case json.Number:
+		n, err := v.Int64()
+		return int(n), err
+	case string:
+		return strconv.Atoi(strings.TrimSpace(v))
+	}
+	return 0, fmt.Errorf("cannot convert %T %v to int", value, value)
+}
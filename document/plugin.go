@@ -0,0 +1,129 @@
+package document
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Plugin delegates chunking of matching files to an external executable.
+//
+// The executable receives the FileInfo as JSON on stdin and must write a
+// PluginResponse as JSON to stdout. A non-zero exit status, a timeout or an
+// "error" field in the response are all reported as errors.
+type Plugin struct {
+	Name       string
+	Command    string
+	Args       []string
+	Extensions []string
+	Filenames  []string
+	Timeout    time.Duration
+}
+
+// PluginChunk is a single chunk returned by a plugin
+type PluginChunk struct {
+	Content  string   `json:"content"`
+	Metadata Metadata `json:"metadata,omitempty"`
+}
+
+// PluginResponse is the JSON document a plugin writes to stdout
+type PluginResponse struct {
+	Chunks []PluginChunk `json:"chunks"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// maxPluginStderr limits how much plugin stderr is included in errors
+const maxPluginStderr = 512
+
+// Matches reports whether the plugin handles the given file
+func (p *Plugin) Matches(fileInfo FileInfo) bool {
+	ext := strings.ToLower(fileInfo.Extension)
+	for _, e := range p.Extensions {
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+
+	base := filepath.Base(fileInfo.RelativePath)
+	for _, pattern := range p.Filenames {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the plugin for a single file and returns its chunks
+func (p *Plugin) Run(ctx context.Context, fileInfo FileInfo) ([]Chunk, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	input, err := json.Marshal(fileInfo)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: failed to encode input: %w", p.Name, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command, p.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin %s timed out after %v", p.Name, timeout)
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w%s", p.Name, err, formatStderr(stderr.String()))
+	}
+
+	var response PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("plugin %s returned invalid JSON: %w", p.Name, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %s reported an error: %s", p.Name, response.Error)
+	}
+
+	var chunks []Chunk
+	for _, pc := range response.Chunks {
+		if strings.TrimSpace(pc.Content) == "" {
+			continue
+		}
+		metadata := pc.Metadata.Clone()
+		if metadata.String("chunk_type") == "" {
+			metadata["chunk_type"] = "plugin"
+		}
+		metadata["plugin"] = p.Name
+
+		chunks = append(chunks, Chunk{
+			Index:    len(chunks),
+			Content:  pc.Content,
+			Metadata: metadata,
+		})
+	}
+
+	return chunks, nil
+}
+
+func formatStderr(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	if len(stderr) > maxPluginStderr {
+		stderr = stderr[:maxPluginStderr] + "..."
+	}
+	return ": " + stderr
+}
@@ -11,34 +11,64 @@ import (
 )
 
 type FileInfo struct {
-	Path         string
-	RelativePath string
-	Topic        string // folder name
-	Extension    string
-	Content      string
-	Size         int64
+	Path         string `json:"path"`
+	RelativePath string `json:"relative_path"`
+	Topic        string `json:"topic"` // folder name
+	Extension    string `json:"extension"`
+	Content      string `json:"content"`
+	Size         int64  `json:"size"`
+
+	// Metadata is added to every chunk of the file, e.g. the tags of a note
+	Metadata Metadata `json:"metadata,omitempty"`
 }
 
 type Reader struct {
-	rootDir string
+	rootDir   string
+	skipFiles map[string]bool
 }
 
+// DefaultSkipFiles lists file names that are not ingested unless overridden
+var DefaultSkipFiles = []string{"go.sum"}
+
 func NewReader(rootDir string) *Reader {
-	return &Reader{
+	r := &Reader{
 		rootDir: rootDir,
 	}
+	r.SetSkipFiles(DefaultSkipFiles)
+	return r
+}
+
+// SetSkipFiles replaces the list of file names (or glob patterns) that are never read
+func (r *Reader) SetSkipFiles(names []string) {
+	r.skipFiles = make(map[string]bool, len(names))
+	for _, name := range names {
+		r.skipFiles[name] = true
+	}
 }
 
 func (r *Reader) ReadAllDocuments() ([]FileInfo, error) {
-	logger.LogInfo(fmt.Sprintf("Reading documents from: %s", r.rootDir))
+	return r.ReadDocumentsUnder(r.rootDir)
+}
+
+// ReadDocumentsUnder reads the documents in a subdirectory of the root, with
+// paths still relative to the root
+func (r *Reader) ReadDocumentsUnder(dir string) ([]FileInfo, error) {
+	logger.LogInfo(fmt.Sprintf("Reading documents from: %s", dir))
 
 	var documents []FileInfo
-	err := filepath.WalkDir(r.rootDir, func(path string, d fs.DirEntry, err error) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			logger.LogError(fmt.Sprintf("Error accessing path %s: %v", path, err))
 			return nil // Continue walking
 		}
 
+		// Symlinks are not followed, so a link cannot pull in files from
+		// outside the folder being read
+		if d.Type()&fs.ModeSymlink != 0 {
+			logger.LogInfo(fmt.Sprintf("Skipping symlink: %s", path))
+			return nil
+		}
+
 		if d.IsDir() {
 			// Skip hidden directories (starting with .) and system directories
 			if r.shouldSkipDirectory(path) {
@@ -117,6 +147,14 @@ func (r *Reader) shouldSkipFile(path string) bool {
 		return true
 	}
 
+	// Skip lock files and other configured names
+	for pattern := range r.skipFiles {
+		if ok, _ := filepath.Match(pattern, fileName); ok {
+			logger.LogInfo(fmt.Sprintf("Skipping file: %s", path))
+			return true
+		}
+	}
+
 	// Skip binary files, large files, and common non-text files
 	ext := strings.ToLower(filepath.Ext(path))
 	skipExts := map[string]bool{
@@ -144,6 +182,11 @@ func (r *Reader) shouldSkipFile(path string) bool {
 	return skipExts[ext]
 }
 
+// ReadDocument reads a single file relative to the reader's root directory
+func (r *Reader) ReadDocument(path string) (FileInfo, error) {
+	return r.readFile(path)
+}
+
 func (r *Reader) readFile(path string) (FileInfo, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -1,55 +1,74 @@
 package logger
 
 import (
+	"io"
+	"sync"
+
 	"github.com/fatih/color"
 	"github.com/sirupsen/logrus"
 )
 
 var Logger *logrus.Logger
 
+var defaultOnce sync.Once
+
+// structured returns Logger, creating it with the defaults on first use so
+// the Log functions are safe before InitLogger, e.g. in tests
+func structured() *logrus.Logger {
+	defaultOnce.Do(func() {
+		if Logger == nil {
+			InitLogger()
+		}
+	})
+	return Logger
+}
+
 func InitLogger() {
 	Logger = logrus.New()
 	Logger.SetFormatter(&logrus.TextFormatter{
 		ForceColors:   true,
 		FullTimestamp: true,
 	})
-	SetLogLevel("info")
+	Logger.SetLevel(logrus.InfoLevel)
 }
 
 func SetLogLevel(level string) {
-	if Logger == nil {
-		InitLogger()
-	}
-
 	logLevel, err := logrus.ParseLevel(level)
 	if err != nil {
 		logLevel = logrus.InfoLevel
 	}
-	Logger.SetLevel(logLevel)
+	structured().SetLevel(logLevel)
+}
+
+// SetOutput redirects both the colored console output and the structured log,
+// e.g. to stderr when stdout carries a protocol such as MCP stdio
+func SetOutput(w io.Writer) {
+	color.Output = w
+	structured().SetOutput(w)
 }
 
 func LogInfo(message string) {
 	color.Cyan("[INFO] %s", message)
-	Logger.Info(message)
+	structured().Info(message)
 }
 
 func LogSuccess(message string) {
 	color.Green("[SUCCESS] %s", message)
-	Logger.Info(message)
+	structured().Info(message)
 }
 
 func LogWarning(message string) {
 	color.Yellow("[WARNING] %s", message)
-	Logger.Warn(message)
+	structured().Warn(message)
 }
 
 func LogError(message string) {
 	color.Red("[ERROR] %s", message)
-	Logger.Error(message)
+	structured().Error(message)
 }
 
 func LogProgress(current, total int, description string) {
 	percentage := float64(current) / float64(total) * 100
 	color.Blue("[PROGRESS] %d/%d (%.1f%%) - %s", current, total, percentage, description)
-	Logger.Infof("Progress: %d/%d (%.1f%%) - %s", current, total, percentage, description)
+	structured().Infof("Progress: %d/%d (%.1f%%) - %s", current, total, percentage, description)
 }
@@ -0,0 +1,247 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// Options controls a migration
+type Options struct {
+	Namespaces    []string // defaults to every source namespace
+	PageSize      int      // records read and upserted per request, defaults to 100
+	StateFile     string   // checkpoint file; progress is resumed from it when present
+	SparseVectors bool     // compute BM25 sparse vectors for a hybrid target
+
+	// CorpusStats are the target's BM25 statistics. Each page is added to them
+	// before encoding and they are saved after every page, so sparse vectors
+	// are normalised like those of later ingests into the target. Without
+	// them the statistics cover this migration only.
+	CorpusStats *vector.CorpusStatsFile
+}
+
+// State is the checkpoint written after every page, so an interrupted
+// migration continues where it stopped
+type State struct {
+	Namespaces map[string]*NamespaceState `json:"namespaces"`
+}
+
+// NamespaceState tracks one namespace's progress
+type NamespaceState struct {
+	Cursor   string `json:"cursor,omitempty"`
+	Migrated int    `json:"migrated"`
+	Skipped  int    `json:"skipped"` // records without data, which cannot be re-embedded
+	Done     bool   `json:"done"`
+}
+
+// Verification compares vector counts of one namespace after a migration
+type Verification struct {
+	Namespace string `json:"namespace"`
+	Source    int    `json:"source"`
+	Target    int    `json:"target"`
+	Skipped   int    `json:"skipped"`
+}
+
+// OK reports whether every source record that could be migrated is present
+func (v Verification) OK() bool {
+	return v.Target >= v.Source-v.Skipped
+}
+
+// Run copies Data and metadata of every namespace from src into dst, letting
+// dst embed the data itself, so the two may use different models, dimensions
+// or similarity functions. Progress is checkpointed to opts.StateFile.
+func Run(ctx context.Context, src, dst vector.VectorStore, opts Options) (*State, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = 100
+	}
+
+	state, err := LoadState(opts.StateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := sourceNamespaces(ctx, src, opts.Namespaces)
+	if err != nil {
+		return state, err
+	}
+
+	for _, namespace := range namespaces {
+		ns := state.Namespaces[namespace]
+		if ns == nil {
+			ns = &NamespaceState{}
+			state.Namespaces[namespace] = ns
+		}
+		if ns.Done {
+			logger.LogInfo(fmt.Sprintf("Namespace '%s' already migrated (%d records), skipping", namespace, ns.Migrated))
+			continue
+		}
+		if ns.Cursor != "" {
+			logger.LogInfo(fmt.Sprintf("Resuming namespace '%s' after %d records", namespace, ns.Migrated))
+		}
+
+		if err := migrateNamespace(ctx, src, dst, namespace, ns, opts, state); err != nil {
+			return state, fmt.Errorf("namespace '%s': %w", namespace, err)
+		}
+		logger.LogSuccess(fmt.Sprintf("Migrated namespace '%s': %d records", namespace, ns.Migrated))
+		if ns.Skipped > 0 {
+			logger.LogWarning(fmt.Sprintf("Skipped %d records without data in namespace '%s'", ns.Skipped, namespace))
+		}
+	}
+	return state, nil
+}
+
+func migrateNamespace(ctx context.Context, src, dst vector.VectorStore, namespace string, ns *NamespaceState, opts Options, state *State) error {
+	stats := vector.NewCorpusStats()
+	if opts.CorpusStats != nil {
+		stats = opts.CorpusStats.Namespace(namespace)
+	}
+	for {
+		page, err := src.Range(ctx, namespace, vector.RangeRequest{
+			Cursor:          ns.Cursor,
+			Limit:           opts.PageSize,
+			IncludeMetadata: true,
+			IncludeData:     true,
+		})
+		if err != nil {
+			return err
+		}
+
+		documents := make([]vector.Document, 0, len(page.Records))
+		for _, record := range page.Records {
+			if record.Data == "" {
+				ns.Skipped++
+				continue
+			}
+			documents = append(documents, vector.Document{
+				ID:        record.ID,
+				Content:   record.Data,
+				Metadata:  document.Metadata(record.Metadata),
+				Namespace: namespace,
+			})
+		}
+
+		if opts.SparseVectors && len(documents) > 0 {
+			for _, doc := range documents {
+				stats.Add(doc.ID, doc.Content)
+			}
+			encoder := stats.Encoder()
+			for i := range documents {
+				documents[i].SparseVector = encoder.EncodeDocument(documents[i].Content)
+			}
+		}
+
+		if len(documents) > 0 {
+			if err := dst.UpsertBatch(ctx, documents, namespace); err != nil {
+				return fmt.Errorf("upsert after %d records: %w", ns.Migrated, err)
+			}
+			if opts.SparseVectors && opts.CorpusStats != nil {
+				// Failing to save only costs accuracy of later ingests
+				if err := opts.CorpusStats.Save(); err != nil {
+					logger.LogWarning(fmt.Sprintf("Failed to save BM25 statistics: %v", err))
+				}
+			}
+		}
+		ns.Migrated += len(documents)
+		ns.Cursor = page.NextCursor
+		ns.Done = page.NextCursor == ""
+
+		if err := saveState(opts.StateFile, state); err != nil {
+			return err
+		}
+		if ns.Done {
+			return nil
+		}
+	}
+}
+
+// Verify compares per-namespace vector counts, including pending vectors,
+// between the source and target indexes
+func Verify(ctx context.Context, src, dst vector.VectorStore, namespaces []string, state *State) ([]Verification, error) {
+	srcInfo, err := src.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("source info: %w", err)
+	}
+	dstInfo, err := dst.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("target info: %w", err)
+	}
+
+	if len(namespaces) == 0 {
+		for name := range srcInfo.Namespaces {
+			namespaces = append(namespaces, name)
+		}
+		sort.Strings(namespaces)
+	}
+
+	results := make([]Verification, 0, len(namespaces))
+	for _, name := range namespaces {
+		source := srcInfo.Namespaces[name]
+		target := dstInfo.Namespaces[name]
+		v := Verification{
+			Namespace: name,
+			Source:    source.VectorCount + source.PendingVectorCount,
+			Target:    target.VectorCount + target.PendingVectorCount,
+		}
+		if state != nil && state.Namespaces[name] != nil {
+			v.Skipped = state.Namespaces[name].Skipped
+		}
+		results = append(results, v)
+	}
+	return results, nil
+}
+
+func sourceNamespaces(ctx context.Context, src vector.VectorStore, selected []string) ([]string, error) {
+	if len(selected) > 0 {
+		return selected, nil
+	}
+	namespaces, err := src.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// LoadState reads a checkpoint, or returns an empty state when there is none
+func LoadState(path string) (*State, error) {
+	state := &State{Namespaces: make(map[string]*NamespaceState)}
+	if path == "" {
+		return state, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid migration state %s: %w", path, err)
+	}
+	if state.Namespaces == nil {
+		state.Namespaces = make(map[string]*NamespaceState)
+	}
+	return state, nil
+}
+
+// saveState writes the checkpoint atomically
+func saveState(path string, state *State) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write migration state: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
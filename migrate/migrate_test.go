@@ -0,0 +1,126 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+func openStore(t *testing.T, path string) *vector.LocalStore {
+	t.Helper()
+	store, err := vector.NewLocalStore(config.LocalStoreConfig{Path: path, Dimension: 32}, nil)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	return store
+}
+
+// sparseValue returns the weight of index in v, or zero
+func sparseValue(v *vector.SparseVector, index int32) float32 {
+	for i, idx := range v.Indices {
+		if idx == index {
+			return v.Values[i]
+		}
+	}
+	return 0
+}
+
+func TestRunAndVerify(t *testing.T) {
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+	src, dst := openStore(t, filepath.Join(dir, "src.jsonl")), openStore(t, filepath.Join(dir, "dst.jsonl"))
+	ctx := context.Background()
+
+	documents := make([]vector.Document, 5)
+	for i := range documents {
+		documents[i] = vector.Document{ID: fmt.Sprintf("doc-%d", i), Content: fmt.Sprintf("chunk %d about routers", i), Metadata: map[string]interface{}{"chunk_index": i}}
+	}
+	if err := src.UpsertBatch(ctx, documents, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.UpsertRecords(ctx, "docs", []vector.Record{{ID: "no-data", Vector: make([]float32, 32)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	statePath := filepath.Join(dir, "state.json")
+	state, err := Run(ctx, src, dst, Options{PageSize: 2, StateFile: statePath})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	ns := state.Namespaces["docs"]
+	if ns == nil || !ns.Done || ns.Migrated != 5 || ns.Skipped != 1 {
+		t.Fatalf("unexpected state %+v", ns)
+	}
+
+	// A finished migration is not repeated
+	if state, err = Run(ctx, src, dst, Options{PageSize: 2, StateFile: statePath}); err != nil || state.Namespaces["docs"].Migrated != 5 {
+		t.Fatalf("second Run: %v, %+v", err, state.Namespaces["docs"])
+	}
+
+	results, err := Verify(ctx, src, dst, []string{"docs"}, state)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if len(results) != 1 || !results[0].OK() || results[0].Source != 6 || results[0].Target != 5 {
+		t.Errorf("unexpected verification %+v", results)
+	}
+}
+
+func TestRunKeepsTargetCorpusStats(t *testing.T) {
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+	src, dst := openStore(t, filepath.Join(dir, "src.jsonl")), openStore(t, filepath.Join(dir, "dst.jsonl"))
+	ctx := context.Background()
+
+	// Short chunks first, long ones last, so per-page averages differ
+	texts := []string{"router", "router", "router middleware handler context request response logger config", "router middleware handler context request response logger config"}
+	documents := make([]vector.Document, len(texts))
+	for i, text := range texts {
+		documents[i] = vector.Document{ID: fmt.Sprintf("doc-%d", i), Content: text}
+	}
+	if err := src.UpsertBatch(ctx, documents, "docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	statsPath := filepath.Join(dir, "bm25.json")
+	stats, err := vector.LoadCorpusStats(statsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(ctx, src, dst, Options{PageSize: 2, SparseVectors: true, CorpusStats: stats}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	saved, err := vector.LoadCorpusStats(statsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corpus := saved.Namespace("docs")
+	if corpus.DocumentCount() != 4 {
+		t.Fatalf("saved statistics hold %d documents, want 4", corpus.DocumentCount())
+	}
+
+	// The last page is normalised over the whole namespace, not itself alone
+	page, err := src.Range(ctx, "docs", vector.RangeRequest{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastID := page.Records[len(page.Records)-1].ID
+	records, err := dst.Fetch(ctx, "docs", vector.FetchRequest{IDs: []string{lastID}, IncludeData: true, IncludeVectors: true})
+	if err != nil || len(records) != 1 || records[0].SparseVector == nil {
+		t.Fatalf("Fetch: %v, %+v", err, records)
+	}
+	want := corpus.Encoder().EncodeDocument(records[0].Data)
+	query := vector.EncodeSparseQuery("router")
+	got := sparseValue(records[0].SparseVector, query.Indices[0])
+	if math.Abs(float64(got-sparseValue(want, query.Indices[0]))) > 1e-6 {
+		t.Errorf("router weighs %v in %s, want %v from the namespace statistics", got, lastID, sparseValue(want, query.Indices[0]))
+	}
+}
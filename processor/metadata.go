@@ -0,0 +1,18 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/document"
+)
+
+// MetadataSchema returns the built-in metadata schema extended with the
+// key types declared under metadata_schema
+func MetadataSchema(cfg *config.Config) (document.Schema, error) {
+	schema, err := document.ParseSchema(cfg.MetadataSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata_schema: %w", err)
+	}
+	return schema, nil
+}
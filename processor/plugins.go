@@ -0,0 +1,34 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/logger"
+)
+
+// NewChunker creates a document chunker with all configured plugins registered
+func NewChunker(cfg *config.Config) (*document.Chunker, error) {
+	chunker := document.NewChunker(1000)
+	for _, p := range cfg.Plugins {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid plugin configuration: %w", err)
+		}
+		chunker.RegisterPlugin(PluginFromConfig(p))
+		logger.LogInfo(fmt.Sprintf("Registered chunker plugin: %s (%s)", p.Name, p.Command))
+	}
+	return chunker, nil
+}
+
+// PluginFromConfig converts a plugin declaration into a runnable document plugin
+func PluginFromConfig(p config.PluginConfig) *document.Plugin {
+	return &document.Plugin{
+		Name:       p.Name,
+		Command:    p.Command,
+		Args:       p.Args,
+		Extensions: p.Extensions,
+		Filenames:  p.Filenames,
+		Timeout:    p.TimeoutDuration(),
+	}
+}
@@ -13,22 +13,25 @@ import (
 	"github.com/typicalfo/prj-start/vector"
 )
 
-// ProcessFolder processes all documents in a folder and upserts them to Upstash Vector
+// ProcessFolder processes all documents in a folder and upserts them to the configured vector store
 func ProcessFolder(ctx context.Context, cfg *config.Config, folderPath string) error {
 	logger.LogInfo("Starting document processing")
 	logger.LogInfo("Configuration loaded successfully")
 	logger.LogInfo(fmt.Sprintf("Default namespace: %s", cfg.DefaultNamespace))
-	logger.LogInfo(fmt.Sprintf("Batch size: %d", cfg.BatchSize))
+	logger.LogInfo(fmt.Sprintf("Batch size: up to %d documents", cfg.BatchSize))
 
-	// Initialize Upstash Vector client
-	client, err := vector.NewClient(&cfg.Upstash)
+	// Initialize the configured vector store
+	client, err := vector.OpenStore(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create Upstash client: %w", err)
+		return fmt.Errorf("failed to open vector store: %w", err)
 	}
 
 	// Read all documents from the folder
 	logger.LogInfo(fmt.Sprintf("Scanning folder: %s", folderPath))
 	reader := document.NewReader(folderPath)
+	if cfg.SkipFiles != nil {
+		reader.SetSkipFiles(cfg.SkipFiles)
+	}
 	documents, err := reader.ReadAllDocuments()
 	if err != nil {
 		return fmt.Errorf("failed to read documents: %w", err)
@@ -42,7 +45,19 @@ func ProcessFolder(ctx context.Context, cfg *config.Config, folderPath string) e
 	logger.LogSuccess(fmt.Sprintf("Found %d documents to process", len(documents)))
 
 	// Create upserter
-	upserter := vector.NewUpserter(client, cfg.BatchSize)
+	upserter, err := NewUpserter(cfg, client)
+	if err != nil {
+		return err
+	}
+	if parentLevel, _ := vector.ParseParentLevel(cfg.ParentChunks); parentLevel != vector.ParentsNone {
+		logger.LogInfo(fmt.Sprintf("Parent records enabled: %s", parentLevel))
+	}
+	if cfg.ContextHeaders {
+		logger.LogInfo("Context headers enabled for embedded chunks")
+	}
+	if cfg.SparseVectors {
+		logger.LogInfo("BM25 sparse vectors enabled for hybrid search")
+	}
 
 	// Process all documents
 	startTime := time.Now()
@@ -66,6 +81,41 @@ func ProcessFolder(ctx context.Context, cfg *config.Config, folderPath string) e
 	return nil
 }
 
+// NewUpserter creates an upserter for the store with the chunker plugins,
+// batch limits, metadata schema, context headers, parent records and sparse
+// vectors of the configuration
+func NewUpserter(cfg *config.Config, client vector.VectorStore) (*vector.Upserter, error) {
+	chunker, err := NewChunker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := MetadataSchema(cfg)
+	if err != nil {
+		return nil, err
+	}
+	parentLevel, err := vector.ParseParentLevel(cfg.ParentChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	upserter := vector.NewUpserter(client, cfg.BatchSize)
+	upserter.SetBatchLimits(vector.BatchLimitsFromConfig(cfg))
+	upserter.SetMetadataSchema(schema)
+	upserter.SetChunker(chunker)
+	upserter.SetContextHeaders(cfg.ContextHeaders)
+	upserter.SetParentLevel(parentLevel)
+	upserter.SetSparseVectors(cfg.SparseVectors)
+	if cfg.SparseVectors {
+		stats, err := vector.LoadCorpusStats(cfg.SparseStatsPath())
+		if err != nil {
+			logger.LogWarning(fmt.Sprintf("%v; normalising sparse vectors over this run only", err))
+		} else {
+			upserter.SetCorpusStats(stats)
+		}
+	}
+	return upserter, nil
+}
+
 // ValidateFolder checks if the folder exists and is readable
 func ValidateFolder(folderPath string) error {
 	// Check if folder exists
@@ -0,0 +1,111 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+	"github.com/typicalfo/prj-start/vectortest"
+)
+
+// writeFiles creates files under dir from relative paths to contents
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// storeRecords returns every record of a namespace by ID
+func storeRecords(t *testing.T, store vector.VectorStore, namespace string) map[string]vector.Record {
+	t.Helper()
+	page, err := store.Range(context.Background(), namespace, vector.RangeRequest{Limit: 1000, IncludeMetadata: true, IncludeData: true})
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	records := make(map[string]vector.Record, len(page.Records))
+	for _, record := range page.Records {
+		records[record.ID] = record
+	}
+	return records
+}
+
+func TestProcessFolderUpsertsToUpstash(t *testing.T) {
+	logger.SetOutput(io.Discard)
+	srv, err := vectortest.NewServer(vectortest.Options{})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go-fiber-recipes/404-handler/main.go":   "package main\n\nfunc main() {\n\tprintln(\"404\")\n}\n",
+		"go-fiber-recipes/404-handler/README.md": "# 404 Handler\n\nServes a custom not found page.\n",
+		"go-fiber-recipes/404-handler/go.sum":    "skipped by default\n",
+	})
+
+	cfg := &config.Config{Upstash: *srv.Config(), BatchSize: 10, ParentChunks: "file"}
+	if err := ProcessFolder(context.Background(), cfg, dir); err != nil {
+		t.Fatalf("ProcessFolder: %v", err)
+	}
+
+	records := storeRecords(t, srv.Store(), "404-handler")
+	chunk, ok := records[vector.DocumentID("go-fiber-recipes/404-handler/main.go", 0)]
+	if !ok {
+		t.Fatalf("main.go chunk 0 not upserted; have %d records", len(records))
+	}
+	m := document.Metadata(chunk.Metadata)
+	if m.String("recipe_name") != "404-handler" || m.String("project_type") != "go-fiber-recipes" || m.String("level") != "chunk" {
+		t.Errorf("unexpected metadata: %v", chunk.Metadata)
+	}
+	if line, ok := m.Int("start_line"); !ok || line != 1 {
+		t.Errorf("start_line = %v, want 1", chunk.Metadata["start_line"])
+	}
+	for _, record := range records {
+		if document.Metadata(record.Metadata).String("filename") == "go.sum" {
+			t.Error("go.sum should be skipped")
+		}
+	}
+}
+
+func TestNewUpserterRejectsInvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]*config.Config{
+		"parent level":    {BatchSize: 10, ParentChunks: "paragraph"},
+		"metadata schema": {BatchSize: 10, MetadataSchema: map[string]string{"priority": "decimal"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewUpserter(cfg, nil); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestValidateFolder(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	writeFiles(t, dir, map[string]string{"file.txt": "x"})
+
+	if err := ValidateFolder(dir); err != nil {
+		t.Errorf("ValidateFolder(dir) = %v", err)
+	}
+	if err := ValidateFolder(file); err == nil {
+		t.Error("expected an error for a file")
+	}
+	if err := ValidateFolder(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing folder")
+	}
+}
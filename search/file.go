@@ -0,0 +1,285 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// rangePageSize is the page size used when scanning a namespace
+const rangePageSize = 1000
+
+// File is a source file reassembled from its indexed chunks. Complete is false
+// when chunks are missing compared with the total_chunks recorded at ingestion.
+type File struct {
+	Source      string `json:"source"`
+	Namespace   string `json:"namespace"`
+	Chunks      int    `json:"chunks"`
+	TotalChunks int    `json:"totalChunks"`
+	Missing     []int  `json:"missing,omitempty"` // chunk indexes not in the index
+	Complete    bool   `json:"complete"`
+	Content     string `json:"content"`
+}
+
+// Source summarizes one ingested file
+type Source struct {
+	Path       string `json:"path"`
+	Namespace  string `json:"namespace"`
+	RecipeName string `json:"recipeName,omitempty"`
+	Chunks     int    `json:"chunks"`
+}
+
+// Recipe is a recipe folder: its files and README
+type Recipe struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Files     []Source `json:"files"`
+	Readme    string   `json:"readme,omitempty"`
+}
+
+// GetFile reassembles a file from its chunks. An empty namespace means the
+// namespace the file would be ingested into. It returns nil when no chunks of
+// the file are indexed.
+func (s *Service) GetFile(ctx context.Context, namespace, source string) (*File, error) {
+	if namespace == "" {
+		namespace = vector.NamespaceForPath(source)
+	}
+	records, err := s.scan(ctx, namespace, true, func(m document.Metadata) bool {
+		return m.String("source_file") == source && isLeafChunk(m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	records = orderChunks(records)
+	file := &File{
+		Source:    source,
+		Namespace: namespace,
+		Chunks:    len(records),
+		Content:   stitchChunks(records),
+	}
+	file.TotalChunks, file.Missing = missingChunks(records)
+	file.Complete = len(file.Missing) == 0
+	return file, nil
+}
+
+// ListSources returns every ingested file in the store, sorted by path
+func (s *Service) ListSources(ctx context.Context) ([]Source, error) {
+	namespaces, err := s.store.ListNamespaces(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	var sources []Source
+	for _, namespace := range namespaces {
+		found, err := s.namespaceSources(ctx, namespace, nil)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, found...)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Path < sources[j].Path })
+	return sources, nil
+}
+
+// GetRecipe lists the files of a recipe folder and reassembles its README.
+// It returns nil when no files of the recipe are indexed.
+func (s *Service) GetRecipe(ctx context.Context, name string) (*Recipe, error) {
+	namespace := vector.NamespaceForPath(path.Join(name, "README.md"))
+	files, err := s.namespaceSources(ctx, namespace, func(m document.Metadata) bool {
+		return m.String("recipe_name") == name
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	recipe := &Recipe{Name: name, Namespace: namespace, Files: files}
+	for _, file := range files {
+		if strings.EqualFold(path.Base(file.Path), "README.md") {
+			readme, err := s.GetFile(ctx, namespace, file.Path)
+			if err != nil {
+				return nil, err
+			}
+			if readme != nil {
+				recipe.Readme = readme.Content
+			}
+			break
+		}
+	}
+	return recipe, nil
+}
+
+// namespaceSources groups the chunks of a namespace by source file
+func (s *Service) namespaceSources(ctx context.Context, namespace string, match func(document.Metadata) bool) ([]Source, error) {
+	records, err := s.scan(ctx, namespace, false, func(m document.Metadata) bool {
+		return m.String("source_file") != "" && isLeafChunk(m) && (match == nil || match(m))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	var sources []Source
+	for _, record := range records {
+		m := document.Metadata(record.Metadata)
+		file := m.String("source_file")
+		if i, ok := index[file]; ok {
+			sources[i].Chunks++
+			continue
+		}
+		index[file] = len(sources)
+		sources = append(sources, Source{Path: file, Namespace: namespace, RecipeName: m.String("recipe_name"), Chunks: 1})
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Path < sources[j].Path })
+	return sources, nil
+}
+
+// isLeafChunk reports whether metadata belongs to a chunk of file content
+// rather than a file outline or section parent record. Chunks have no level,
+// or level "chunk" when parent records were ingested.
+func isLeafChunk(m document.Metadata) bool {
+	switch m.String("level") {
+	case "", "chunk":
+		return true
+	}
+	return false
+}
+
+// scan pages through a namespace and returns the records whose metadata matches
+func (s *Service) scan(ctx context.Context, namespace string, includeData bool, match func(document.Metadata) bool) ([]vector.Record, error) {
+	var records []vector.Record
+	cursor := ""
+	for {
+		page, err := s.store.Range(ctx, namespace, vector.RangeRequest{
+			Cursor:          cursor,
+			Limit:           rangePageSize,
+			IncludeMetadata: true,
+			IncludeData:     includeData,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan namespace '%s': %w", namespace, err)
+		}
+		for _, record := range page.Records {
+			if match(document.Metadata(record.Metadata)) {
+				records = append(records, record)
+			}
+		}
+		if page.NextCursor == "" {
+			return records, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// orderChunks sorts chunks by chunk_index and drops duplicate indexes
+func orderChunks(records []vector.Record) []vector.Record {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, _ := document.Metadata(records[i].Metadata).Int("chunk_index")
+		b, _ := document.Metadata(records[j].Metadata).Int("chunk_index")
+		return a < b
+	})
+	ordered := records[:0]
+	for i, record := range records {
+		index, _ := document.Metadata(record.Metadata).Int("chunk_index")
+		if i > 0 {
+			previous, _ := document.Metadata(ordered[len(ordered)-1].Metadata).Int("chunk_index")
+			if index == previous {
+				continue
+			}
+		}
+		ordered = append(ordered, record)
+	}
+	return ordered
+}
+
+// missingChunks compares ordered chunks with the recorded total_chunks and
+// returns the total and the absent chunk indexes
+func missingChunks(records []vector.Record) (int, []int) {
+	total := 0
+	present := make(map[int]bool, len(records))
+	for _, record := range records {
+		m := document.Metadata(record.Metadata)
+		if n, ok := m.Int("total_chunks"); ok && n > total {
+			total = n
+		}
+		index, _ := m.Int("chunk_index")
+		present[index] = true
+		if index+1 > total {
+			total = index + 1
+		}
+	}
+
+	var missing []int
+	for index := 0; index < total; index++ {
+		if !present[index] {
+			missing = append(missing, index)
+		}
+	}
+	return total, missing
+}
+
+// stitchChunks joins ordered chunks. Located chunks are separated by as many
+// newlines as their line numbers call for, so the result keeps the file's
+// line numbering, and text that overlaps the previous chunk's byte range is
+// dropped. Other chunks are separated by a blank line, after removing whole
+// lines repeated from the end of the previous chunk.
+func stitchChunks(records []vector.Record) string {
+	var b strings.Builder
+	previous := ""
+	previousEndLine, previousEndByte := 0, 0
+	for i, record := range records {
+		m := document.Metadata(record.Metadata)
+		content := vector.StripContextHeader(record.Data, record.Metadata)
+		startLine, _ := m.Int("start_line")
+		startByte, located := m.Int("start_byte")
+		located = located && startLine > 0
+
+		if i > 0 {
+			switch {
+			case located && previousEndByte > 0 && startByte < previousEndByte:
+				overlap := previousEndByte - startByte
+				if overlap >= len(content) {
+					continue
+				}
+				content = content[overlap:]
+			case located && previousEndLine > 0 && startLine > previousEndLine:
+				b.WriteString(strings.Repeat("\n", startLine-previousEndLine))
+			default:
+				content = trimRepeatedLines(previous, content)
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(content)
+
+		previous = content
+		previousEndLine, _ = m.Int("end_line")
+		if end, ok := m.Int("end_byte"); ok && end > previousEndByte {
+			previousEndByte = end
+		}
+	}
+	return b.String()
+}
+
+// trimRepeatedLines drops the longest run of leading lines of next that
+// repeats the trailing lines of previous
+func trimRepeatedLines(previous, next string) string {
+	prevLines := strings.Split(previous, "\n")
+	nextLines := strings.Split(next, "\n")
+	for n := min(len(prevLines), len(nextLines)-1); n > 0; n-- {
+		if strings.Join(prevLines[len(prevLines)-n:], "\n") == strings.Join(nextLines[:n], "\n") {
+			return strings.Join(nextLines[n:], "\n")
+		}
+	}
+	return next
+}
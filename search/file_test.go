@@ -0,0 +1,117 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// newTestStore opens an empty local store in a temporary directory
+func newTestStore(t *testing.T) *vector.LocalStore {
+	t.Helper()
+	logger.SetOutput(io.Discard)
+	store, err := vector.NewLocalStore(config.LocalStoreConfig{Path: filepath.Join(t.TempDir(), "vectors.jsonl")}, nil)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	return store
+}
+
+// ingestTestFile chunks and upserts one file the way ingest does
+func ingestTestFile(t *testing.T, store vector.VectorStore, parents vector.ParentLevel, relativePath, content string) {
+	t.Helper()
+	upserter := vector.NewUpserter(store, 10)
+	upserter.SetParentLevel(parents)
+	doc := document.FileInfo{
+		Path:         relativePath,
+		RelativePath: relativePath,
+		Extension:    filepath.Ext(relativePath),
+		Content:      content,
+		Size:         int64(len(content)),
+	}
+	if err := upserter.UpsertAllDocuments(context.Background(), []document.FileInfo{doc}); err != nil {
+		t.Fatalf("UpsertAllDocuments: %v", err)
+	}
+}
+
+// testGoSource is a Go file long enough to be split into several chunks
+func testGoSource(functions int) string {
+	var b strings.Builder
+	b.WriteString("package sample\n\nimport \"fmt\"\n")
+	for i := 0; i < functions; i++ {
+		fmt.Fprintf(&b, "\n// Step%d prints its number\nfunc Step%d() {\n", i, i)
+		for j := 0; j < 30; j++ {
+			fmt.Fprintf(&b, "\tfmt.Println(\"step %d line %d\")\n", i, j)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func TestGetFileReassemblesChunks(t *testing.T) {
+	for name, parents := range map[string]vector.ParentLevel{
+		"none":    vector.ParentsNone,
+		"file":    vector.ParentsFile,
+		"section": vector.ParentsSection,
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t)
+			source := testGoSource(6)
+			ingestTestFile(t, store, parents, "recipes/sample/main.go", source)
+
+			file, err := NewService(store).GetFile(context.Background(), "", "recipes/sample/main.go")
+			if err != nil {
+				t.Fatalf("GetFile: %v", err)
+			}
+			if file == nil {
+				t.Fatal("GetFile returned nil for an ingested file")
+			}
+			if file.Chunks < 2 {
+				t.Fatalf("expected several chunks, got %d", file.Chunks)
+			}
+			if !file.Complete || file.TotalChunks != file.Chunks {
+				t.Errorf("expected a complete file, got %d of %d chunks, missing %v", file.Chunks, file.TotalChunks, file.Missing)
+			}
+			if file.Content != strings.TrimSpace(source) {
+				t.Errorf("reassembled content differs from the source:\n%s", file.Content)
+			}
+		})
+	}
+}
+
+func TestGetFileReportsMissingChunks(t *testing.T) {
+	store := newTestStore(t)
+	ingestTestFile(t, store, vector.ParentsNone, "recipes/sample/main.go", testGoSource(6))
+	if _, err := store.Delete(context.Background(), "sample", []string{vector.DocumentID("recipes/sample/main.go", 1)}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	file, err := NewService(store).GetFile(context.Background(), "", "recipes/sample/main.go")
+	if err != nil || file == nil {
+		t.Fatalf("GetFile: %v, %v", file, err)
+	}
+	if file.Complete || len(file.Missing) != 1 || file.Missing[0] != 1 {
+		t.Errorf("expected chunk 1 to be missing, got complete=%v missing=%v", file.Complete, file.Missing)
+	}
+}
+
+func TestListSourcesWithParents(t *testing.T) {
+	store := newTestStore(t)
+	ingestTestFile(t, store, vector.ParentsFile, "recipes/sample/main.go", testGoSource(3))
+
+	sources, err := NewService(store).ListSources(context.Background())
+	if err != nil {
+		t.Fatalf("ListSources: %v", err)
+	}
+	if len(sources) != 1 || sources[0].Path != "recipes/sample/main.go" || sources[0].RecipeName == "" {
+		t.Errorf("unexpected sources: %+v", sources)
+	}
+}
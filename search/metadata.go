@@ -0,0 +1,211 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// DefaultMetadataLimit is used when a metadata request does not set Limit
+const DefaultMetadataLimit = 10
+
+// DefaultOrderBy orders metadata results file by file, in chunk order
+var DefaultOrderBy = []string{"source_file", "chunk_index"}
+
+// MetadataRequest describes a metadata-only lookup: every record matching
+// Filter, without a query vector. Namespaces defaults to the default namespace.
+type MetadataRequest struct {
+	Filter     string
+	Namespaces []string
+	Limit      int
+	Cursor     string   // from a previous response's NextCursor
+	OrderBy    []string // metadata keys, "-key" for descending; default DefaultOrderBy
+
+	IncludeMetadata bool
+	IncludeData     bool
+}
+
+// MetadataResponse is one page of a metadata lookup
+type MetadataResponse struct {
+	Results    []Result `json:"results"`
+	Total      int      `json:"total"`   // records matching the filter
+	Scanned    int      `json:"scanned"` // records examined
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+// QueryMetadata pages through the namespaces, evaluates the filter on every
+// record's metadata and returns one page of the matches in a deterministic
+// order, ties broken by namespace and ID. Each page rescans the namespaces,
+// so the cursor stays valid without server-side state.
+func (s *Service) QueryMetadata(ctx context.Context, req MetadataRequest) (*MetadataResponse, error) {
+	filter, err := vector.ParseFilter(req.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	if req.Limit <= 0 {
+		req.Limit = DefaultMetadataLimit
+	}
+	orderBy := req.OrderBy
+	if len(orderBy) == 0 {
+		orderBy = DefaultOrderBy
+	}
+	offset, err := decodeCursor(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := s.resolveNamespaces(ctx, req.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &MetadataResponse{}
+	var matches []Result
+	for _, namespace := range namespaces {
+		records, err := s.scan(ctx, namespace, false, func(m document.Metadata) bool {
+			response.Scanned++
+			return filter.Match(m)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			matches = append(matches, Result{
+				ID:        record.ID,
+				Namespace: namespace,
+				Citation:  Citation(record.Metadata),
+				Metadata:  record.Metadata,
+			})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		for _, key := range orderBy {
+			descending := strings.HasPrefix(key, "-")
+			key = strings.TrimPrefix(key, "-")
+			a, b := matches[i].Metadata[key], matches[j].Metadata[key]
+			if (a == nil) != (b == nil) {
+				// Missing values sort last in either direction
+				return b == nil
+			}
+			if c := compareMetadata(a, b); c != 0 {
+				return (c < 0) != descending
+			}
+		}
+		if matches[i].Namespace != matches[j].Namespace {
+			return matches[i].Namespace < matches[j].Namespace
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	response.Total = len(matches)
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	end := offset + req.Limit
+	if end < len(matches) {
+		response.NextCursor = encodeCursor(end)
+	} else {
+		end = len(matches)
+	}
+	response.Results = matches[offset:end]
+
+	if req.IncludeData {
+		if err := s.attachData(ctx, response.Results); err != nil {
+			return nil, err
+		}
+	}
+	if !req.IncludeMetadata {
+		for i := range response.Results {
+			response.Results[i].Metadata = nil
+		}
+	}
+	return response, nil
+}
+
+// attachData fetches the data of a page of results, one request per namespace
+func (s *Service) attachData(ctx context.Context, results []Result) error {
+	byNamespace := make(map[string][]int)
+	for i, result := range results {
+		byNamespace[result.Namespace] = append(byNamespace[result.Namespace], i)
+	}
+	for namespace, indexes := range byNamespace {
+		ids := make([]string, len(indexes))
+		for i, index := range indexes {
+			ids[i] = results[index].ID
+		}
+		records, err := s.store.Fetch(ctx, namespace, vector.FetchRequest{IDs: ids, IncludeData: true})
+		if err != nil {
+			return err
+		}
+		data := make(map[string]string, len(records))
+		for _, record := range records {
+			data[record.ID] = record.Data
+		}
+		for _, index := range indexes {
+			results[index].Data = vector.StripContextHeader(data[results[index].ID], results[index].Metadata)
+		}
+	}
+	return nil
+}
+
+// compareMetadata orders metadata values: numbers numerically, everything else
+// as text, and missing values last
+func compareMetadata(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	x, xNumeric := metadataNumber(a)
+	y, yNumeric := metadataNumber(b)
+	if xNumeric && yNumeric {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func metadataNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// Cursors are opaque to clients; they encode the offset of the next page
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if offset, err := strconv.Atoi(string(raw)); err == nil && offset >= 0 {
+			return offset, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor %q", cursor)
+}
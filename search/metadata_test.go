@@ -0,0 +1,128 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// seedMetadataRecords stores records with the given metadata, keyed by ID
+func seedMetadataRecords(t *testing.T, store vector.VectorStore, namespace string, records map[string]document.Metadata) {
+	t.Helper()
+	documents := make([]vector.Document, 0, len(records))
+	for id, metadata := range records {
+		documents = append(documents, vector.Document{ID: id, Content: "content of " + id, Metadata: metadata})
+	}
+	if err := store.UpsertBatch(context.Background(), documents, namespace); err != nil {
+		t.Fatalf("UpsertBatch: %v", err)
+	}
+}
+
+func newMetadataTestService(t *testing.T) *Service {
+	t.Helper()
+	store := newTestStore(t)
+	seedMetadataRecords(t, store, "a", map[string]document.Metadata{
+		"r1": {"source_file": "b.go", "chunk_index": 1, "priority": 2, "kind": "x"},
+		"r2": {"source_file": "a.go", "chunk_index": 0, "priority": 5, "kind": "x"},
+		"r3": {"source_file": "a.go", "chunk_index": 1, "kind": "y"},
+		"r4": {"source_file": "b.go", "chunk_index": 0, "priority": 10, "kind": "x"},
+		"r5": {"source_file": "c.go", "chunk_index": 0, "priority": 1, "kind": "x"},
+	})
+	seedMetadataRecords(t, store, "b", map[string]document.Metadata{
+		"r6": {"source_file": "a.go", "chunk_index": 0, "priority": 5, "kind": "x"},
+	})
+	return NewService(store)
+}
+
+func resultIDs(results []Result) string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestQueryMetadataPagesWithCursor(t *testing.T) {
+	service := newMetadataTestService(t)
+	ctx := context.Background()
+
+	var pages []string
+	cursor := ""
+	for i := 0; i < 5; i++ {
+		response, err := service.QueryMetadata(ctx, MetadataRequest{Filter: "kind = 'x'", Namespaces: []string{"a"}, Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("QueryMetadata: %v", err)
+		}
+		if response.Total != 4 || response.Scanned != 5 {
+			t.Errorf("page %d: total %d, scanned %d, want 4 and 5", i, response.Total, response.Scanned)
+		}
+		pages = append(pages, resultIDs(response.Results))
+		if response.NextCursor == "" {
+			break
+		}
+		cursor = response.NextCursor
+	}
+	if got := strings.Join(pages, " | "); got != "r2,r4 | r1,r5" {
+		t.Errorf("pages %s, want r2,r4 | r1,r5 (by source_file, then chunk_index)", got)
+	}
+
+	// A cursor past the end yields an empty last page
+	response, err := service.QueryMetadata(ctx, MetadataRequest{Namespaces: []string{"a"}, Cursor: encodeCursor(50)})
+	if err != nil || len(response.Results) != 0 || response.NextCursor != "" {
+		t.Errorf("cursor past the end: %v, %+v", err, response)
+	}
+	if _, err := service.QueryMetadata(ctx, MetadataRequest{Namespaces: []string{"a"}, Cursor: "not a cursor"}); err == nil {
+		t.Error("expected an error for an invalid cursor")
+	}
+}
+
+func TestQueryMetadataOrderBy(t *testing.T) {
+	service := newMetadataTestService(t)
+	tests := []struct {
+		orderBy []string
+		want    string
+	}{
+		// Numbers compare numerically, and missing values sort last either way
+		{[]string{"priority"}, "r5,r1,r2,r4,r3"},
+		{[]string{"-priority"}, "r4,r2,r1,r5,r3"},
+		{[]string{"-source_file", "chunk_index"}, "r5,r4,r1,r2,r3"},
+		{[]string{"kind", "-chunk_index"}, "r1,r2,r4,r5,r3"},
+	}
+	for _, tt := range tests {
+		response, err := service.QueryMetadata(context.Background(), MetadataRequest{Namespaces: []string{"a"}, Limit: 10, OrderBy: tt.orderBy})
+		if err != nil {
+			t.Fatalf("QueryMetadata: %v", err)
+		}
+		if got := resultIDs(response.Results); got != tt.want {
+			t.Errorf("orderBy %v: got %s, want %s", tt.orderBy, got, tt.want)
+		}
+	}
+}
+
+func TestQueryMetadataAcrossNamespaces(t *testing.T) {
+	service := newMetadataTestService(t)
+	response, err := service.QueryMetadata(context.Background(), MetadataRequest{
+		Filter:          "source_file = 'a.go' AND chunk_index = 0",
+		Namespaces:      []string{"b", "a"},
+		IncludeData:     true,
+		IncludeMetadata: false,
+	})
+	if err != nil {
+		t.Fatalf("QueryMetadata: %v", err)
+	}
+	if response.Total != 2 || response.Scanned != 6 {
+		t.Errorf("total %d, scanned %d, want 2 and 6", response.Total, response.Scanned)
+	}
+	// Ties are broken by namespace, then ID
+	if got := resultIDs(response.Results); got != "r2,r6" {
+		t.Fatalf("got %s, want r2,r6", got)
+	}
+	for _, result := range response.Results {
+		if result.Data != "content of "+result.ID || result.Metadata != nil {
+			t.Errorf("%s: data %q, metadata %v", result.ID, result.Data, result.Metadata)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package search
+
+import (
+	"context"
+	"sort"
+
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// passage is a run of consecutive chunks of one file holding one or more hits
+type passage struct {
+	namespace string
+	source    string
+	start     int // first chunk index
+	end       int // last chunk index
+	hits      []Result
+	shrunk    bool // already reduced to the hit chunks
+}
+
+// pack expands each hit with its neighboring chunks, merges overlapping or
+// adjacent hits of the same file into one result and keeps the results, best
+// first, that fit the token budget. A passage that does not fit is shrunk to
+// its hit chunks, which compete with the remaining results. Hits that are not
+// file chunks, such as parent records, are kept as they are.
+func (s *Service) pack(ctx context.Context, hits []Result, expand, budget int) ([]Result, error) {
+	passages := buildPassages(hits, expand)
+	chunks, err := s.fetchPassageChunks(ctx, passages)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(passages))
+	used := 0
+	for i := 0; i < len(passages); i++ {
+		p := passages[i]
+		result := p.hits[0]
+		if p.source != "" {
+			result = p.result(chunks)
+		}
+		result.Tokens = vector.EstimateTokens(result.Data)
+		if budget > 0 && used+result.Tokens > budget {
+			if p.source != "" && !p.shrunk {
+				passages = append(passages, buildPassages(p.hits, 0)...)
+				sortPassages(passages[i+1:])
+			}
+			continue
+		}
+		used += result.Tokens
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// buildPassages turns hits into passages of chunk_index ± expand, merged per
+// file and ordered by their best hit
+func buildPassages(hits []Result, expand int) []*passage {
+	var passages []*passage
+	byFile := make(map[string][]*passage)
+	for _, hit := range hits {
+		m := document.Metadata(hit.Metadata)
+		source := m.String("source_file")
+		index, ok := m.Int("chunk_index")
+		if source == "" || !ok || !isLeafChunk(m) {
+			passages = append(passages, &passage{hits: []Result{hit}})
+			continue
+		}
+		start, end := max(index-expand, 0), index+expand
+		if total, ok := m.Int("total_chunks"); ok && end > total-1 {
+			end = max(total-1, index)
+		}
+		key := hit.Namespace + "\x00" + source
+		byFile[key] = append(byFile[key], &passage{
+			namespace: hit.Namespace,
+			source:    source,
+			start:     start,
+			end:       end,
+			hits:      []Result{hit},
+			shrunk:    expand == 0,
+		})
+	}
+	for _, spans := range byFile {
+		passages = append(passages, mergePassages(spans)...)
+	}
+	sortPassages(passages)
+	return passages
+}
+
+// sortPassages orders passages by their best hit, which comes first
+func sortPassages(passages []*passage) {
+	sort.SliceStable(passages, func(i, j int) bool {
+		return passages[i].hits[0].Score > passages[j].hits[0].Score
+	})
+}
+
+// mergePassages merges the passages of one file whose chunk ranges overlap or
+// touch, keeping the hits of a merged passage best first
+func mergePassages(spans []*passage) []*passage {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := merged[len(merged)-1]
+		if span.start > last.end+1 {
+			merged = append(merged, span)
+			continue
+		}
+		last.end = max(last.end, span.end)
+		last.hits = append(last.hits, span.hits...)
+	}
+	for _, p := range merged {
+		sort.SliceStable(p.hits, func(i, j int) bool { return p.hits[i].Score > p.hits[j].Score })
+	}
+	return merged
+}
+
+// fetchPassageChunks fetches the chunks of every passage, one request per
+// namespace, with context headers removed. Chunk IDs are derived from the
+// source file and chunk index; hits already carry their data and are not
+// fetched again.
+func (s *Service) fetchPassageChunks(ctx context.Context, passages []*passage) (map[string]vector.Record, error) {
+	chunks := make(map[string]vector.Record)
+	byNamespace := make(map[string][]string)
+	for _, p := range passages {
+		for _, hit := range p.hits {
+			chunks[hit.ID] = vector.Record{ID: hit.ID, Metadata: withoutHeaderLength(hit.Metadata), Data: hit.Data}
+		}
+		for index := p.start; p.source != "" && index <= p.end; index++ {
+			id := vector.DocumentID(p.source, index)
+			if _, ok := chunks[id]; !ok {
+				byNamespace[p.namespace] = append(byNamespace[p.namespace], id)
+			}
+		}
+	}
+
+	for namespace, ids := range byNamespace {
+		records, err := s.store.Fetch(ctx, namespace, vector.FetchRequest{
+			IDs:             ids,
+			IncludeMetadata: true,
+			IncludeData:     true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			chunks[record.ID] = vector.Record{
+				ID:       record.ID,
+				Metadata: withoutHeaderLength(record.Metadata),
+				Data:     vector.StripContextHeader(record.Data, record.Metadata),
+			}
+		}
+	}
+	return chunks, nil
+}
+
+// result stitches the chunks of the passage into one result carrying the
+// best hit's ID, score and metadata. Chunks missing from the index are skipped.
+func (p *passage) result(chunks map[string]vector.Record) Result {
+	var records []vector.Record
+	var indexes []int
+	for index := p.start; index <= p.end; index++ {
+		record, ok := chunks[vector.DocumentID(p.source, index)]
+		if !ok || document.Metadata(record.Metadata).String("source_file") != p.source {
+			continue
+		}
+		records = append(records, record)
+		indexes = append(indexes, index)
+	}
+
+	result := p.hits[0]
+	result.Chunks = indexes
+	result.Hits = make([]string, len(p.hits))
+	for i, hit := range p.hits {
+		result.Hits[i] = hit.ID
+	}
+	if len(records) == 0 {
+		return result
+	}
+	result.Data = stitchChunks(records)
+
+	first, last := document.Metadata(records[0].Metadata), document.Metadata(records[len(records)-1].Metadata)
+	startLine, _ := first.Int("start_line")
+	endLine, _ := last.Int("end_line")
+	result.Citation = document.Citation(p.source, startLine, endLine)
+	return result
+}
+
+// withoutHeaderLength copies metadata without the context header length, so
+// stitchChunks does not strip data twice
+func withoutHeaderLength(metadata map[string]interface{}) map[string]interface{} {
+	if _, ok := metadata[vector.ContextHeaderLengthKey]; !ok {
+		return metadata
+	}
+	copied := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		if k != vector.ContextHeaderLengthKey {
+			copied[k] = v
+		}
+	}
+	return copied
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+func TestSearchExpandsNeighbors(t *testing.T) {
+	for name, parents := range map[string]vector.ParentLevel{
+		"none": vector.ParentsNone,
+		"file": vector.ParentsFile,
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStore(t)
+			ingestTestFile(t, store, parents, "recipes/sample/main.go", testGoSource(6))
+
+			results, err := NewService(store).Search(context.Background(), Request{
+				Query:           "Step3 prints its number",
+				Filter:          "HAS NOT FIELD level OR level = 'chunk'",
+				Namespaces:      []string{"sample"},
+				TopK:            1,
+				Expand:          1,
+				IncludeMetadata: true,
+			})
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("expected one result, got %d", len(results))
+			}
+
+			result := results[0]
+			index, _ := document.Metadata(result.Metadata).Int("chunk_index")
+			if len(result.Chunks) < 2 || result.Chunks[0] > index || result.Chunks[len(result.Chunks)-1] < index {
+				t.Fatalf("hit chunk %d was not expanded: chunks %v", index, result.Chunks)
+			}
+			if !strings.Contains(result.Data, "func Step3()") {
+				t.Errorf("packed data lacks the hit:\n%s", result.Data)
+			}
+			if result.Tokens != vector.EstimateTokens(result.Data) {
+				t.Errorf("tokens = %d, want %d", result.Tokens, vector.EstimateTokens(result.Data))
+			}
+		})
+	}
+}
+
+func TestSearchMergesAdjacentHits(t *testing.T) {
+	store := newTestStore(t)
+	ingestTestFile(t, store, vector.ParentsFile, "recipes/sample/main.go", testGoSource(4))
+
+	results, err := NewService(store).Search(context.Background(), Request{
+		Query:      "prints its number",
+		Filter:     "level = 'chunk'",
+		Namespaces: []string{"sample"},
+		TopK:       10,
+		Expand:     1,
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	// Every chunk of the file is a hit, so they merge into one passage
+	if len(results) != 1 {
+		t.Fatalf("expected one merged passage, got %d", len(results))
+	}
+	if len(results[0].Hits) < 2 {
+		t.Errorf("expected several merged hits, got %v", results[0].Hits)
+	}
+}
+
+func TestSearchTokenBudget(t *testing.T) {
+	store := newTestStore(t)
+	ingestTestFile(t, store, vector.ParentsFile, "recipes/sample/main.go", testGoSource(6))
+	service := NewService(store)
+
+	request := Request{
+		Query:      "Step3 prints its number",
+		Filter:     "level = 'chunk'",
+		Namespaces: []string{"sample"},
+		TopK:       3,
+		Expand:     2,
+	}
+	unlimited, err := service.Search(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	request.TokenBudget = unlimited[0].Tokens - 1
+	packed, err := service.Search(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	used := 0
+	for _, result := range packed {
+		used += result.Tokens
+	}
+	if len(packed) == 0 || used > request.TokenBudget {
+		t.Fatalf("expected results within %d tokens, got %d results using %d", request.TokenBudget, len(packed), used)
+	}
+	if len(packed[0].Chunks) >= len(unlimited[0].Chunks) {
+		t.Errorf("expected the passage to shrink to fit, got chunks %v", packed[0].Chunks)
+	}
+}
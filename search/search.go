@@ -0,0 +1,268 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// AllNamespaces searches every namespace in the store
+const AllNamespaces = "*"
+
+// DefaultTopK is used when a request does not set TopK
+const DefaultTopK = 5
+
+// Request describes a search. Query is embedded by the store unless Vector
+// is set. Namespaces defaults to the default namespace.
+type Request struct {
+	Query      string
+	Vector     []float32
+	Filter     string
+	Namespaces []string
+	TopK       int
+	MinScore   float64 // results scoring below are dropped
+
+	IncludeMetadata bool
+	IncludeData     bool
+	IncludeParent   bool // attach the section or file outline parent record
+
+	// Packing: Expand adds this many neighboring chunks on each side of a hit,
+	// hits of the same file that overlap or touch are merged, and TokenBudget,
+	// when set, caps the estimated tokens of the returned data. Either option
+	// implies IncludeData.
+	Expand      int
+	TokenBudget int
+
+	// Hybrid index options, see vector.QueryRequest
+	Fusion          string
+	SparseWeighting string
+}
+
+// Result is a single search hit, or a packed passage of consecutive chunks
+// around one or more hits. Data has any embedded context header removed.
+type Result struct {
+	ID        string                 `json:"id"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Score     float64                `json:"score"`
+	Citation  string                 `json:"citation,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Data      string                 `json:"data,omitempty"`
+	Parent    *Parent                `json:"parent,omitempty"`
+
+	// Set on packed results
+	Chunks []int    `json:"chunks,omitempty"` // chunk indexes stitched into Data
+	Hits   []string `json:"hits,omitempty"`   // IDs of the search hits merged, best first
+	Tokens int      `json:"tokens,omitempty"` // estimated tokens of Data
+}
+
+// Parent is the larger record (section or file outline) enclosing a result
+type Parent struct {
+	ID       string `json:"id"`
+	Level    string `json:"level"`
+	Citation string `json:"citation,omitempty"`
+	Data     string `json:"data,omitempty"`
+}
+
+// Service runs searches and lookups against a vector store
+type Service struct {
+	store  vector.VectorStore
+	schema document.Schema
+}
+
+func NewService(store vector.VectorStore) *Service {
+	return &Service{store: store, schema: document.DefaultSchema}
+}
+
+// SetMetadataSchema replaces the schema structured filters are validated against
+func (s *Service) SetMetadataSchema(schema document.Schema) {
+	if schema != nil {
+		s.schema = schema
+	}
+}
+
+// CompileFilter validates a structured filter against the metadata schema and
+// combines it with a filter expression; either may be empty
+func (s *Service) CompileFilter(filter string, where *vector.FilterSpec) (string, error) {
+	if where == nil {
+		return filter, nil
+	}
+	compiled, err := where.Compile(s.schema)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(filter) == "" {
+		return compiled, nil
+	}
+	return fmt.Sprintf("(%s) AND (%s)", filter, compiled), nil
+}
+
+// Store returns the underlying vector store
+func (s *Service) Store() vector.VectorStore {
+	return s.store
+}
+
+// Search queries each requested namespace and merges the hits by score
+func (s *Service) Search(ctx context.Context, req Request) ([]Result, error) {
+	if req.Query == "" && len(req.Vector) == 0 {
+		return nil, fmt.Errorf("search needs a query or a vector")
+	}
+	if req.TopK <= 0 {
+		req.TopK = DefaultTopK
+	}
+	packing := req.Expand > 0 || req.TokenBudget > 0
+	if req.Expand < 0 || req.TokenBudget < 0 {
+		return nil, fmt.Errorf("expand and token budget must not be negative")
+	}
+
+	namespaces, err := s.resolveNamespaces(ctx, req.Namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, namespace := range namespaces {
+		hits, err := s.store.Query(ctx, namespace, vector.QueryRequest{
+			Data:            req.Query,
+			Vector:          req.Vector,
+			TopK:            req.TopK,
+			Filter:          req.Filter,
+			IncludeMetadata: true, // needed for citations and context headers
+			IncludeData:     req.IncludeData || packing,
+			Fusion:          req.Fusion,
+			SparseWeighting: req.SparseWeighting,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range hits {
+			if hit.Score < req.MinScore {
+				continue
+			}
+			results = append(results, Result{
+				ID:        hit.ID,
+				Namespace: namespace,
+				Score:     hit.Score,
+				Citation:  Citation(hit.Metadata),
+				Metadata:  hit.Metadata,
+				Data:      vector.StripContextHeader(hit.Data, hit.Metadata),
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > req.TopK {
+		results = results[:req.TopK]
+	}
+
+	if packing {
+		results, err = s.pack(ctx, results, req.Expand, req.TokenBudget)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.IncludeParent {
+		s.attachParents(ctx, results)
+	}
+	if !req.IncludeMetadata {
+		for i := range results {
+			results[i].Metadata = nil
+		}
+	}
+	return results, nil
+}
+
+// Get fetches a single record by ID, or returns nil when it does not exist
+func (s *Service) Get(ctx context.Context, namespace, id string, includeMetadata, includeData bool) (*Result, error) {
+	records, err := s.store.Fetch(ctx, namespace, vector.FetchRequest{
+		IDs:             []string{id},
+		IncludeMetadata: true, // needed for citations and context headers
+		IncludeData:     includeData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	record := records[0]
+	result := &Result{
+		ID:        record.ID,
+		Namespace: namespace,
+		Citation:  Citation(record.Metadata),
+		Data:      vector.StripContextHeader(record.Data, record.Metadata),
+	}
+	if includeMetadata {
+		result.Metadata = record.Metadata
+	}
+	return result, nil
+}
+
+// resolveNamespaces expands AllNamespaces and defaults to the default namespace
+func (s *Service) resolveNamespaces(ctx context.Context, namespaces []string) ([]string, error) {
+	if len(namespaces) == 0 {
+		return []string{""}, nil
+	}
+	for _, ns := range namespaces {
+		if ns == AllNamespaces {
+			all, err := s.store.ListNamespaces(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list namespaces: %w", err)
+			}
+			return all, nil
+		}
+	}
+	return namespaces, nil
+}
+
+// attachParents fetches the parent record referenced by each result's
+// parent_id metadata. Failures only drop the parent context.
+func (s *Service) attachParents(ctx context.Context, results []Result) {
+	byNamespace := make(map[string][]string)
+	for _, result := range results {
+		if parentID, _ := result.Metadata["parent_id"].(string); parentID != "" {
+			byNamespace[result.Namespace] = append(byNamespace[result.Namespace], parentID)
+		}
+	}
+
+	parents := make(map[string]Parent)
+	for namespace, ids := range byNamespace {
+		records, err := s.store.Fetch(ctx, namespace, vector.FetchRequest{
+			IDs:             ids,
+			IncludeMetadata: true,
+			IncludeData:     true,
+		})
+		if err != nil {
+			continue
+		}
+		for _, r := range records {
+			level, _ := r.Metadata["level"].(string)
+			parents[r.ID] = Parent{
+				ID:       r.ID,
+				Level:    level,
+				Citation: Citation(r.Metadata),
+				Data:     vector.StripContextHeader(r.Data, r.Metadata),
+			}
+		}
+	}
+
+	for i := range results {
+		parentID, _ := results[i].Metadata["parent_id"].(string)
+		if parent, ok := parents[parentID]; ok {
+			results[i].Parent = &parent
+		}
+	}
+}
+
+// Citation renders a path:line citation from chunk metadata
+func Citation(metadata map[string]interface{}) string {
+	m := document.Metadata(metadata)
+	startLine, _ := m.Int("start_line")
+	endLine, _ := m.Int("end_line")
+	return document.Citation(m.String("source_file"), startLine, endLine)
+}
@@ -0,0 +1,137 @@
+package vector
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// annThreshold is the namespace size from which queries use the LSH index
+// instead of a full scan
+const annThreshold = 2000
+
+const annTables = 8
+
+// similarity scores a stored vector against a query vector. Scores follow
+// Upstash's normalisation so higher is always better and results are
+// comparable across backends.
+type similarity string
+
+const (
+	similarityCosine    similarity = "COSINE"
+	similarityDot       similarity = "DOT_PRODUCT"
+	similarityEuclidean similarity = "EUCLIDEAN"
+)
+
+func parseSimilarity(name string) similarity {
+	switch name {
+	case "dot", "dot_product", "DOT_PRODUCT":
+		return similarityDot
+	case "euclidean", "EUCLIDEAN":
+		return similarityEuclidean
+	}
+	return similarityCosine
+}
+
+func (s similarity) score(a, b []float32) float64 {
+	switch s {
+	case similarityDot:
+		return (1 + dot(a, b)) / 2
+	case similarityEuclidean:
+		var sum float64
+		for i := range a {
+			d := float64(a[i] - b[i])
+			sum += d * d
+		}
+		return 1 / (1 + sum)
+	}
+	na, nb := math.Sqrt(dot(a, a)), math.Sqrt(dot(b, b))
+	if na == 0 || nb == 0 {
+		return 0.5
+	}
+	return (1 + dot(a, b)/(na*nb)) / 2
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// annIndex is a random-hyperplane LSH index. Each table hashes a vector to
+// the sign pattern of its projections; queries probe the matching bucket and
+// every bucket one bit away, then rerank the candidates exactly.
+type annIndex struct {
+	planes  [][][]float32 // [table][bit]hyperplane
+	buckets []map[uint64][]string
+}
+
+func newANNIndex(dimension, size int) *annIndex {
+	// Aim for roughly 16 vectors per bucket
+	bits := 4
+	for bits < 20 && size>>bits > 16 {
+		bits++
+	}
+
+	// A fixed seed keeps results stable across runs
+	rng := rand.New(rand.NewSource(1))
+	idx := &annIndex{
+		planes:  make([][][]float32, annTables),
+		buckets: make([]map[uint64][]string, annTables),
+	}
+	for t := range idx.planes {
+		idx.planes[t] = make([][]float32, bits)
+		for b := range idx.planes[t] {
+			plane := make([]float32, dimension)
+			for i := range plane {
+				plane[i] = float32(rng.NormFloat64())
+			}
+			idx.planes[t][b] = plane
+		}
+		idx.buckets[t] = make(map[uint64][]string)
+	}
+	return idx
+}
+
+func (idx *annIndex) hash(table int, v []float32) uint64 {
+	var h uint64
+	for b, plane := range idx.planes[table] {
+		if dot(plane, v) >= 0 {
+			h |= 1 << uint(b)
+		}
+	}
+	return h
+}
+
+func (idx *annIndex) add(id string, v []float32) {
+	for t := range idx.planes {
+		h := idx.hash(t, v)
+		idx.buckets[t][h] = append(idx.buckets[t][h], id)
+	}
+}
+
+// candidates returns the IDs sharing a bucket, or a bucket one bit away,
+// with the query in any table
+func (idx *annIndex) candidates(v []float32) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	collect := func(t int, h uint64) {
+		for _, id := range idx.buckets[t][h] {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	for t := range idx.planes {
+		h := idx.hash(t, v)
+		collect(t, h)
+		for b := range idx.planes[t] {
+			collect(t, h^(1<<uint(b)))
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
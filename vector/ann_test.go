@@ -0,0 +1,79 @@
+package vector
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestSimilarityScore(t *testing.T) {
+	a, b := []float32{1, 0}, []float32{0, 1}
+	tests := []struct {
+		similarity similarity
+		x, y       []float32
+		want       float64
+	}{
+		{similarityCosine, a, a, 1},
+		{similarityCosine, a, b, 0.5},
+		{similarityCosine, a, []float32{-1, 0}, 0},
+		{similarityCosine, a, []float32{0, 0}, 0.5},
+		{similarityDot, a, a, 1},
+		{similarityDot, a, b, 0.5},
+		{similarityEuclidean, a, a, 1},
+		{similarityEuclidean, a, b, 1.0 / 3},
+	}
+	for _, tt := range tests {
+		if got := tt.similarity.score(tt.x, tt.y); got-tt.want > 1e-9 || tt.want-got > 1e-9 {
+			t.Errorf("%s(%v, %v) = %v, want %v", tt.similarity, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestANNIndexFindsNearNeighbors(t *testing.T) {
+	const dimension, size = 32, 4000
+	rng := rand.New(rand.NewSource(42))
+	vectors := make(map[string][]float32, size)
+	idx := newANNIndex(dimension, size)
+	for i := 0; i < size; i++ {
+		v := make([]float32, dimension)
+		for j := range v {
+			v[j] = float32(rng.NormFloat64())
+		}
+		id := fmt.Sprintf("v%d", i)
+		vectors[id] = v
+		idx.add(id, v)
+	}
+
+	found := 0
+	for i := 0; i < 50; i++ {
+		// A slightly perturbed copy of a stored vector should land in its bucket
+		id := fmt.Sprintf("v%d", rng.Intn(size))
+		query := make([]float32, dimension)
+		for j, x := range vectors[id] {
+			query[j] = x + float32(rng.NormFloat64()*0.05)
+		}
+		candidates := idx.candidates(query)
+		if len(candidates) >= size/2 {
+			t.Fatalf("index probed %d of %d vectors; expected a small candidate set", len(candidates), size)
+		}
+		for _, candidate := range candidates {
+			if candidate == id {
+				found++
+				break
+			}
+		}
+	}
+	if found < 48 {
+		t.Errorf("found the source vector for %d of 50 queries", found)
+	}
+}
+
+func TestANNIndexIsDeterministic(t *testing.T) {
+	v := []float32{0.3, -0.2, 0.9, 0.1}
+	a, b := newANNIndex(len(v), 100), newANNIndex(len(v), 100)
+	for table := range a.planes {
+		if a.hash(table, v) != b.hash(table, v) {
+			t.Fatalf("table %d hashes differ between indexes", table)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Upstash accepts at most 1000 vectors per upsert request
+const maxUpsertCount = 1000
+
+// Batch budget defaults. The count is the batch size upserts have always used;
+// the byte budget keeps requests well below Upstash's request size limit; the
+// token budget keeps embedding requests below the per-request input limits of
+// common embedding APIs.
+const (
+	DefaultBatchCount     = 10
+	DefaultBatchMaxBytes  = 1 << 20
+	DefaultBatchMaxTokens = 100000
+)
+
+// BatchLimits bounds a single upsert request. A batch is closed as soon as
+// adding the next document would exceed any of the limits, so MaxCount is a
+// ceiling rather than a fixed size. Zero values use the defaults.
+type BatchLimits struct {
+	MaxCount  int
+	MaxBytes  int
+	MaxTokens int
+}
+
+func (l BatchLimits) withDefaults() BatchLimits {
+	if l.MaxCount <= 0 {
+		l.MaxCount = DefaultBatchCount
+	} else if l.MaxCount > maxUpsertCount {
+		l.MaxCount = maxUpsertCount
+	}
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = DefaultBatchMaxBytes
+	}
+	if l.MaxTokens <= 0 {
+		l.MaxTokens = DefaultBatchMaxTokens
+	}
+	return l
+}
+
+// Check returns an error when a document cannot fit in any batch on its own.
+// vectorBytes is the payload added per document for a client-side vector.
+func (l BatchLimits) Check(doc Document, vectorBytes int) error {
+	l = l.withDefaults()
+	size := documentBytes(doc) + vectorBytes
+	if size > l.MaxBytes {
+		return fmt.Errorf("chunk %s is %d bytes, over the batch limit of %d bytes", describeDocument(doc), size, l.MaxBytes)
+	}
+	if tokens := EstimateTokens(doc.Content); tokens > l.MaxTokens {
+		return fmt.Errorf("chunk %s is about %d tokens, over the batch limit of %d tokens", describeDocument(doc), tokens, l.MaxTokens)
+	}
+	return nil
+}
+
+// Split groups documents into batches within the limits, keeping their order.
+// Every document is checked first, so an oversized chunk fails the whole call
+// before anything is sent.
+func (l BatchLimits) Split(documents []Document, vectorBytes int) ([][]Document, error) {
+	l = l.withDefaults()
+	for _, doc := range documents {
+		if err := l.Check(doc, vectorBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	var batches [][]Document
+	start, size, tokens := 0, 0, 0
+	for i, doc := range documents {
+		docSize := documentBytes(doc) + vectorBytes
+		docTokens := EstimateTokens(doc.Content)
+		if i > start && (i-start >= l.MaxCount || size+docSize > l.MaxBytes || tokens+docTokens > l.MaxTokens) {
+			batches = append(batches, documents[start:i])
+			start, size, tokens = i, 0, 0
+		}
+		size += docSize
+		tokens += docTokens
+	}
+	if start < len(documents) {
+		batches = append(batches, documents[start:])
+	}
+	return batches, nil
+}
+
+// documentBytes approximates the JSON payload of a document: its ID, data,
+// metadata and sparse vector, with a few bytes of quoting per field
+func documentBytes(doc Document) int {
+	size := len(doc.ID) + len(doc.Content) + 32
+	for k, v := range doc.Metadata {
+		size += len(k) + metadataValueBytes(v) + 6
+	}
+	if doc.SparseVector != nil {
+		size += len(doc.SparseVector.Indices) * 24
+	}
+	return size
+}
+
+// metadataValueBytes approximates the JSON size of a metadata value
+func metadataValueBytes(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []string:
+		size := 2
+		for _, item := range v {
+			size += len(item) + 3
+		}
+		return size
+	default:
+		data, _ := json.Marshal(v)
+		return len(data)
+	}
+}
+
+// denseVectorBytes approximates the JSON size of a dense vector
+func denseVectorBytes(dimension int) int {
+	return dimension * 12
+}
+
+// EstimateTokens approximates the token count of text at four bytes per token
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func describeDocument(doc Document) string {
+	if source := doc.Metadata.String("source_file"); source != "" {
+		if index, ok := doc.Metadata.Int("chunk_index"); ok {
+			return fmt.Sprintf("%s #%d of %s", doc.ID, index, source)
+		}
+		return fmt.Sprintf("%s of %s", doc.ID, source)
+	}
+	return doc.ID
+}
@@ -0,0 +1,52 @@
+package vector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBatchLimitsDefaults(t *testing.T) {
+	tests := []struct {
+		count int
+		want  int
+	}{
+		{0, DefaultBatchCount},
+		{-5, DefaultBatchCount},
+		{25, 25},
+		{maxUpsertCount, maxUpsertCount},
+		{5000, maxUpsertCount},
+	}
+	for _, tt := range tests {
+		if got := (BatchLimits{MaxCount: tt.count}).withDefaults().MaxCount; got != tt.want {
+			t.Errorf("MaxCount %d became %d, want %d", tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestBatchLimitsSplit(t *testing.T) {
+	documents := make([]Document, 25)
+	for i := range documents {
+		documents[i] = Document{ID: string(rune('a' + i)), Content: "chunk"}
+	}
+	batches, err := BatchLimits{}.Split(documents, 0)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	if len(batches) != 3 || len(batches[0]) != DefaultBatchCount || len(batches[2]) != 5 {
+		t.Errorf("expected batches of 10, 10 and 5, got %d batches", len(batches))
+	}
+
+	// The token budget closes a batch before the count ceiling
+	long := Document{ID: "long", Content: strings.Repeat("word ", 400)}
+	batches, err = BatchLimits{MaxCount: 10, MaxTokens: EstimateTokens(long.Content) * 2}.Split([]Document{long, long, long}, 0)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	if len(batches) != 2 {
+		t.Errorf("expected the token budget to split 3 documents into 2 batches, got %d", len(batches))
+	}
+
+	if _, err := (BatchLimits{MaxTokens: 10}).Split([]Document{long}, 0); err == nil {
+		t.Error("expected an error for a chunk over the token budget")
+	}
+}
@@ -0,0 +1,173 @@
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CorpusStats are the BM25 statistics of one namespace: the length and the
+// distinct terms of every chunk, by record ID. They are kept between
+// ingestion runs, so document vectors are normalised by the average length
+// over the whole namespace rather than over the files of one run, and
+// re-ingesting a chunk replaces its entry instead of counting it twice.
+type CorpusStats struct {
+	Documents map[string]CorpusDocument `json:"documents"`
+
+	// Derived from Documents on first use
+	totalLength int
+	docFreq     map[int32]int
+}
+
+// CorpusDocument is what one chunk contributes to the corpus statistics
+type CorpusDocument struct {
+	Length int     `json:"length"` // tokens
+	Terms  []int32 `json:"terms"`  // distinct term indices, sorted
+}
+
+// NewCorpusStats returns empty statistics
+func NewCorpusStats() *CorpusStats {
+	return &CorpusStats{Documents: make(map[string]CorpusDocument)}
+}
+
+// Add counts the chunk stored under id, replacing an earlier version
+func (c *CorpusStats) Add(id, text string) {
+	c.Remove(id)
+	tokens := SparseTokens(text)
+	doc := CorpusDocument{Length: len(tokens)}
+	seen := make(map[int32]bool)
+	for term := range termCounts(tokens) {
+		if index := termIndex(term); !seen[index] {
+			seen[index] = true
+			doc.Terms = append(doc.Terms, index)
+		}
+	}
+	sort.Slice(doc.Terms, func(i, j int) bool { return doc.Terms[i] < doc.Terms[j] })
+
+	c.Documents[id] = doc
+	c.totalLength += doc.Length
+	for _, index := range doc.Terms {
+		c.docFreq[index]++
+	}
+}
+
+// Remove forgets the chunks stored under ids
+func (c *CorpusStats) Remove(ids ...string) {
+	c.index()
+	for _, id := range ids {
+		doc, ok := c.Documents[id]
+		if !ok {
+			continue
+		}
+		delete(c.Documents, id)
+		c.totalLength -= doc.Length
+		for _, index := range doc.Terms {
+			if c.docFreq[index]--; c.docFreq[index] <= 0 {
+				delete(c.docFreq, index)
+			}
+		}
+	}
+}
+
+// DocumentCount returns the number of chunks counted
+func (c *CorpusStats) DocumentCount() int {
+	return len(c.Documents)
+}
+
+// AvgDocLength returns the average chunk length in tokens, or one for an
+// empty corpus
+func (c *CorpusStats) AvgDocLength() float64 {
+	c.index()
+	if len(c.Documents) == 0 || c.totalLength == 0 {
+		return 1
+	}
+	return float64(c.totalLength) / float64(len(c.Documents))
+}
+
+// DocFreq returns the number of chunks containing the term with the given index
+func (c *CorpusStats) DocFreq(index int32) int {
+	c.index()
+	return c.docFreq[index]
+}
+
+// Encoder returns a BM25 encoder normalising by the corpus's average length
+func (c *CorpusStats) Encoder() *BM25Encoder {
+	return &BM25Encoder{avgDocLength: c.AvgDocLength()}
+}
+
+// index derives the total length and document frequencies from Documents
+func (c *CorpusStats) index() {
+	if c.Documents == nil {
+		c.Documents = make(map[string]CorpusDocument)
+	}
+	if c.docFreq != nil {
+		return
+	}
+	c.docFreq = make(map[int32]int)
+	c.totalLength = 0
+	for _, doc := range c.Documents {
+		c.totalLength += doc.Length
+		for _, index := range doc.Terms {
+			c.docFreq[index]++
+		}
+	}
+}
+
+// CorpusStatsFile keeps the corpus statistics of every namespace of an index
+// in a JSON file
+type CorpusStatsFile struct {
+	Namespaces map[string]*CorpusStats `json:"namespaces"`
+
+	path string
+}
+
+// LoadCorpusStats reads the statistics saved at path; a missing file holds
+// no statistics yet
+func LoadCorpusStats(path string) (*CorpusStatsFile, error) {
+	f := &CorpusStatsFile{Namespaces: make(map[string]*CorpusStats), path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read BM25 statistics: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("corrupt BM25 statistics %s: %w", path, err)
+	}
+	if f.Namespaces == nil {
+		f.Namespaces = make(map[string]*CorpusStats)
+	}
+	return f, nil
+}
+
+// Namespace returns the statistics of a namespace, creating them if needed
+func (f *CorpusStatsFile) Namespace(name string) *CorpusStats {
+	stats := f.Namespaces[name]
+	if stats == nil {
+		stats = NewCorpusStats()
+		f.Namespaces[name] = stats
+	}
+	return stats
+}
+
+// Save writes the statistics back to the file they were loaded from
+func (f *CorpusStatsFile) Save() error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to encode BM25 statistics: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create BM25 statistics directory: %w", err)
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write BM25 statistics: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to write BM25 statistics: %w", err)
+	}
+	return nil
+}
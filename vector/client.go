@@ -4,16 +4,23 @@ import (
 	"context"
 	"fmt"
 	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/document"
 	"github.com/typicalfo/prj-start/logger"
 
 	"github.com/upstash/vector-go"
 )
 
+// Client is the Upstash Vector implementation of VectorStore
 type Client struct {
-	config *config.UpstashConfig
-	index  *vector.Index
+	config   *config.UpstashConfig
+	index    *vector.Index
+	embedder Embedder // nil uses the index's built-in embedding model
+	sparse   bool     // query with BM25 sparse vectors (hybrid indexes)
+	limits   BatchLimits
 }
 
+var _ VectorStore = (*Client)(nil)
+
 func NewClient(cfg *config.UpstashConfig) (*Client, error) {
 	if !cfg.Validate() {
 		return nil, fmt.Errorf("invalid Upstash configuration")
@@ -33,6 +40,24 @@ func NewClient(cfg *config.UpstashConfig) (*Client, error) {
 	}, nil
 }
 
+// SetEmbedder makes the client embed text itself and send raw vectors, for
+// indexes without an embedding model or to choose the model
+func (c *Client) SetEmbedder(embedder Embedder) {
+	c.embedder = embedder
+}
+
+// SetSparseVectors makes text queries include a BM25 sparse vector, for
+// hybrid indexes ingested with sparse vectors
+func (c *Client) SetSparseVectors(enabled bool) {
+	c.sparse = enabled
+}
+
+// SetBatchLimits bounds the size of each upsert request. UpsertBatch splits
+// larger batches to fit.
+func (c *Client) SetBatchLimits(limits BatchLimits) {
+	c.limits = limits
+}
+
 func (c *Client) Upsert(ctx context.Context, id string, metadata map[string]string, content string, namespace string) error {
 	logger.LogInfo(fmt.Sprintf("Upserting document: %s (namespace: %s)", id, namespace))
 
@@ -61,10 +86,33 @@ func (c *Client) Upsert(ctx context.Context, id string, metadata map[string]stri
 	return nil
 }
 
+// UpsertBatch upserts documents in as many requests as the batch limits
+// require. Oversized documents are rejected before any request is sent.
 func (c *Client) UpsertBatch(ctx context.Context, documents []Document, namespace string) error {
+	vectorBytes := 0
+	if c.embedder != nil {
+		vectorBytes = denseVectorBytes(c.embedder.Dimension())
+	}
+	batches, err := c.limits.Split(documents, vectorBytes)
+	if err != nil {
+		return fmt.Errorf("namespace '%s': %w", namespace, err)
+	}
+	for _, batch := range batches {
+		if err := c.upsertBatch(ctx, batch, namespace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) upsertBatch(ctx context.Context, documents []Document, namespace string) error {
 	logger.LogInfo(fmt.Sprintf("Upserting batch of %d documents (namespace: %s)", len(documents), namespace))
 	logger.LogInfo(fmt.Sprintf("Namespace debug: '%s' (len=%d)", namespace, len(namespace)))
 
+	if c.embedder != nil || hasSparseVectors(documents) {
+		return c.upsertVectors(ctx, documents, namespace)
+	}
+
 	// Convert documents to UpsertData format
 	upsertData := make([]vector.UpsertData, len(documents))
 	for i, doc := range documents {
@@ -97,11 +145,61 @@ func (c *Client) UpsertBatch(ctx context.Context, documents []Document, namespac
 	return nil
 }
 
+// upsertVectors embeds documents client-side and upserts raw dense vectors
+// together with any sparse vectors. The content is still stored as data so
+// results can return it.
+func (c *Client) upsertVectors(ctx context.Context, documents []Document, namespace string) error {
+	if c.embedder == nil {
+		return fmt.Errorf("upserting sparse vectors to namespace '%s' requires an embedder for the dense vectors", namespace)
+	}
+	texts := make([]string, len(documents))
+	for i, doc := range documents {
+		texts[i] = doc.Content
+	}
+	vectors, err := c.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed batch for namespace '%s': %w", namespace, err)
+	}
+
+	upserts := make([]vector.Upsert, len(documents))
+	for i, doc := range documents {
+		upsertMetadata := make(map[string]any)
+		for k, v := range doc.Metadata {
+			upsertMetadata[k] = v
+		}
+		upserts[i] = vector.Upsert{
+			Id:           doc.ID,
+			Vector:       vectors[i],
+			SparseVector: toSDKSparse(doc.SparseVector),
+			Data:         doc.Content,
+			Metadata:     upsertMetadata,
+		}
+	}
+
+	if err := c.index.Namespace(namespace).UpsertMany(upserts); err != nil {
+		logger.LogError(fmt.Sprintf("Failed to upsert batch of %d vectors: %v", len(documents), err))
+		return err
+	}
+
+	logger.LogSuccess(fmt.Sprintf("Successfully upserted batch of %d vectors (namespace: %s)", len(documents), namespace))
+	return nil
+}
+
 type Document struct {
-	ID        string
-	Content   string
-	Metadata  map[string]string
-	Namespace string
+	ID           string
+	Content      string
+	Metadata     document.Metadata
+	Namespace    string
+	SparseVector *SparseVector // BM25 vector for hybrid indexes, if enabled
+}
+
+func hasSparseVectors(documents []Document) bool {
+	for _, doc := range documents {
+		if doc.SparseVector != nil {
+			return true
+		}
+	}
+	return false
 }
 
 type QueryResult struct {
@@ -109,46 +207,129 @@ type QueryResult struct {
 	Score    float64                `json:"score"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 	Data     string                 `json:"data,omitempty"`
+	Vector   []float32              `json:"vector,omitempty"`
 }
 
-func (c *Client) Query(ctx context.Context, queryVector []float32, topK int, namespace string) ([]QueryResult, error) {
-	logger.LogInfo(fmt.Sprintf("Querying namespace '%s' with vector of length %d", namespace, len(queryVector)))
-
-	// Create namespace instance
+// Query searches a namespace by raw text or by vector. Text is embedded by
+// the configured Embedder, or by Upstash when there is none.
+func (c *Client) Query(ctx context.Context, namespace string, req QueryRequest) ([]QueryResult, error) {
 	ns := c.index.Namespace(namespace)
 
-	// Query with vector
-	results, err := ns.Query(vector.Query{
-		Vector:          queryVector,
-		TopK:            topK,
-		IncludeMetadata: true,
-	})
+	if len(req.Vector) == 0 && c.embedder != nil {
+		queryVector, err := embedOne(ctx, c.embedder, req.Data)
+		if err != nil {
+			return nil, fmt.Errorf("query namespace '%s': failed to embed query: %w", namespace, err)
+		}
+		req.Vector = queryVector
+	}
 
+	fusion, weighting, err := hybridOptions(req)
 	if err != nil {
-		logger.LogError(fmt.Sprintf("Failed to query namespace %s: %v", namespace, err))
-		return nil, err
+		return nil, fmt.Errorf("query namespace '%s': %w", namespace, err)
+	}
+	var sparseVector *SparseVector
+	if c.sparse && req.Data != "" {
+		sparseVector = EncodeSparseQuery(req.Data)
+	}
+
+	var results []vector.VectorScore
+	if len(req.Vector) > 0 {
+		logger.Logger.Debugf("Querying namespace '%s' with vector of length %d", namespace, len(req.Vector))
+		results, err = ns.Query(vector.Query{
+			Vector:            req.Vector,
+			SparseVector:      toSDKSparse(sparseVector),
+			TopK:              req.TopK,
+			IncludeMetadata:   req.IncludeMetadata,
+			IncludeData:       req.IncludeData,
+			IncludeVectors:    req.IncludeVectors,
+			Filter:            filterValue(req.Filter),
+			WeightingStrategy: sdkWeighting(sparseVector != nil, weighting, SparseWeightingIDF),
+			FusionAlgorithm:   sdkFusion(sparseVector != nil, fusion),
+		})
+	} else {
+		logger.Logger.Debugf("Querying namespace '%s' with data: %s", namespace, req.Data)
+		results, err = ns.QueryData(vector.QueryData{
+			Data:              req.Data,
+			TopK:              req.TopK,
+			IncludeMetadata:   req.IncludeMetadata,
+			IncludeData:       req.IncludeData,
+			IncludeVectors:    req.IncludeVectors,
+			Filter:            filterValue(req.Filter),
+			WeightingStrategy: sdkWeighting(true, weighting, ""),
+			FusionAlgorithm:   sdkFusion(true, fusion),
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query namespace '%s': %w", namespace, err)
 	}
 
 	// Convert results to our format
 	queryResults := make([]QueryResult, len(results))
 	for i, result := range results {
-		// Convert metadata to map[string]interface{} for flexibility
-		metadata := make(map[string]interface{})
-		for k, v := range result.Metadata {
-			metadata[k] = v
-		}
-
 		queryResults[i] = QueryResult{
 			ID:       result.Id,
 			Score:    float64(result.Score),
-			Metadata: metadata,
+			Metadata: result.Metadata,
+			Data:     result.Data,
+			Vector:   result.Vector,
 		}
 	}
-
-	logger.LogSuccess(fmt.Sprintf("Query returned %d results from namespace '%s'", len(queryResults), namespace))
 	return queryResults, nil
 }
 
+func (c *Client) Fetch(ctx context.Context, namespace string, req FetchRequest) ([]Record, error) {
+	vectors, err := c.index.Namespace(namespace).Fetch(vector.Fetch{
+		Ids:             req.IDs,
+		IncludeMetadata: req.IncludeMetadata,
+		IncludeData:     req.IncludeData,
+		IncludeVectors:  req.IncludeVectors,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetch from namespace '%s': %w", namespace, err)
+	}
+
+	// Upstash returns an empty entry for every ID that does not exist
+	records := make([]Record, 0, len(vectors))
+	for _, v := range vectors {
+		if v.Id == "" {
+			continue
+		}
+		records = append(records, toRecord(v))
+	}
+	return records, nil
+}
+
+func (c *Client) Delete(ctx context.Context, namespace string, ids []string) (int, error) {
+	count, err := c.index.Namespace(namespace).DeleteMany(ids)
+	if err != nil {
+		return 0, fmt.Errorf("delete from namespace '%s': %w", namespace, err)
+	}
+	return count, nil
+}
+
+func (c *Client) Range(ctx context.Context, namespace string, req RangeRequest) (RangePage, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	page, err := c.index.Namespace(namespace).Range(vector.Range{
+		Cursor:          req.Cursor,
+		Limit:           limit,
+		IncludeMetadata: req.IncludeMetadata,
+		IncludeData:     req.IncludeData,
+		IncludeVectors:  req.IncludeVectors,
+	})
+	if err != nil {
+		return RangePage{}, fmt.Errorf("range namespace '%s': %w", namespace, err)
+	}
+
+	records := make([]Record, len(page.Vectors))
+	for i, v := range page.Vectors {
+		records[i] = toRecord(v)
+	}
+	return RangePage{NextCursor: page.NextCursor, Records: records}, nil
+}
+
 func (c *Client) ListNamespaces(ctx context.Context) ([]string, error) {
 	logger.LogInfo("Listing all namespaces")
 
@@ -161,3 +342,107 @@ func (c *Client) ListNamespaces(ctx context.Context) ([]string, error) {
 	logger.LogSuccess(fmt.Sprintf("Found %d namespaces", len(namespaces)))
 	return namespaces, nil
 }
+
+func (c *Client) DeleteNamespace(ctx context.Context, namespace string) error {
+	if err := c.index.Namespace(namespace).DeleteNamespace(); err != nil {
+		return fmt.Errorf("delete namespace '%s': %w", namespace, err)
+	}
+	return nil
+}
+
+func (c *Client) ResetNamespace(ctx context.Context, namespace string) error {
+	if err := c.index.Namespace(namespace).Reset(); err != nil {
+		return fmt.Errorf("reset namespace '%s': %w", namespace, err)
+	}
+	return nil
+}
+
+func (c *Client) UpsertRecords(ctx context.Context, namespace string, records []Record) error {
+	upserts := make([]vector.Upsert, len(records))
+	for i, r := range records {
+		upserts[i] = vector.Upsert{
+			Id:           r.ID,
+			Vector:       r.Vector,
+			SparseVector: toSDKSparse(r.SparseVector),
+			Data:         r.Data,
+			Metadata:     r.Metadata,
+		}
+	}
+	if err := c.index.Namespace(namespace).UpsertMany(upserts); err != nil {
+		return fmt.Errorf("upsert %d records to namespace '%s': %w", len(records), namespace, err)
+	}
+	return nil
+}
+
+func (c *Client) Info(ctx context.Context) (IndexInfo, error) {
+	info, err := c.index.Info()
+	if err != nil {
+		return IndexInfo{}, fmt.Errorf("index info: %w", err)
+	}
+
+	namespaces := make(map[string]NamespaceInfo, len(info.Namespaces))
+	for name, ns := range info.Namespaces {
+		namespaces[name] = NamespaceInfo{
+			VectorCount:        ns.VectorCount,
+			PendingVectorCount: ns.PendingVectorCount,
+		}
+	}
+	return IndexInfo{
+		VectorCount:        info.VectorCount,
+		PendingVectorCount: info.PendingVectorCount,
+		IndexSize:          info.IndexSize,
+		Dimension:          info.Dimension,
+		SimilarityFunction: info.SimilarityFunction,
+		Namespaces:         namespaces,
+	}, nil
+}
+
+func toRecord(v vector.Vector) Record {
+	record := Record{
+		ID:       v.Id,
+		Vector:   v.Vector,
+		Metadata: v.Metadata,
+		Data:     v.Data,
+	}
+	if v.SparseVector != nil {
+		record.SparseVector = &SparseVector{Indices: v.SparseVector.Indices, Values: v.SparseVector.Values}
+	}
+	return record
+}
+
+func toSDKSparse(v *SparseVector) *vector.SparseVector {
+	if v == nil {
+		return nil
+	}
+	return &vector.SparseVector{Indices: v.Indices, Values: v.Values}
+}
+
+// sdkWeighting maps the sparse weighting option, falling back to def when
+// unset. Upstash applies no weighting when the strategy is omitted, and
+// options are only sent with a sparse component so dense indexes never see them.
+func sdkWeighting(sparse bool, weighting, def string) vector.WeightingStrategy {
+	if weighting == "" {
+		weighting = def
+	}
+	if !sparse || weighting != SparseWeightingIDF {
+		return ""
+	}
+	return vector.WeightingStrategyIDF
+}
+
+// sdkFusion maps the fusion option, which like the weighting is only sent
+// with a sparse component
+func sdkFusion(sparse bool, fusion string) vector.FusionAlgorithm {
+	if !sparse {
+		return ""
+	}
+	return vector.FusionAlgorithm(fusion)
+}
+
+// filterValue omits empty filters from requests
+func filterValue(filter string) any {
+	if filter == "" {
+		return nil
+	}
+	return filter
+}
@@ -0,0 +1,148 @@
+package vector_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/logger"
+	"github.com/typicalfo/prj-start/vector"
+	"github.com/typicalfo/prj-start/vectortest"
+)
+
+// newTestClient starts a fake Upstash index and a client connected to it
+func newTestClient(t *testing.T) (*vector.Client, *vectortest.Server) {
+	t.Helper()
+	logger.SetOutput(io.Discard)
+	srv, err := vectortest.NewServer(vectortest.Options{})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := vector.NewClient(srv.Config())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client, srv
+}
+
+func testDocuments() []vector.Document {
+	return []vector.Document{
+		{ID: "a", Content: "fiber middleware for authentication", Metadata: document.Metadata{"extension": ".go", "chunk_index": 0}},
+		{ID: "b", Content: "postgres connection pooling", Metadata: document.Metadata{"extension": ".go", "chunk_index": 1}},
+		{ID: "c", Content: "deploying with docker compose", Metadata: document.Metadata{"extension": ".md", "chunk_index": 0}},
+	}
+}
+
+func TestClientUpsertQueryFetch(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if err := client.UpsertBatch(ctx, testDocuments(), "recipes"); err != nil {
+		t.Fatalf("UpsertBatch: %v", err)
+	}
+
+	hits, err := client.Query(ctx, "recipes", vector.QueryRequest{
+		Data:            "fiber middleware for authentication",
+		TopK:            2,
+		IncludeMetadata: true,
+		IncludeData:     true,
+	})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(hits) != 2 || hits[0].ID != "a" {
+		t.Fatalf("expected a as the best of 2 hits, got %+v", hits)
+	}
+	if hits[0].Data != "fiber middleware for authentication" || hits[0].Metadata["extension"] != ".go" {
+		t.Errorf("hit lacks data or metadata: %+v", hits[0])
+	}
+
+	filtered, err := client.Query(ctx, "recipes", vector.QueryRequest{
+		Data:   "fiber middleware for authentication",
+		TopK:   5,
+		Filter: "extension = '.md'",
+	})
+	if err != nil {
+		t.Fatalf("Query with filter: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != "c" {
+		t.Errorf("filter did not restrict hits: %+v", filtered)
+	}
+
+	records, err := client.Fetch(ctx, "recipes", vector.FetchRequest{IDs: []string{"b", "missing"}, IncludeMetadata: true, IncludeData: true})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != "b" || records[0].Data != "postgres connection pooling" {
+		t.Fatalf("unexpected fetch result: %+v", records)
+	}
+	if index, _ := document.Metadata(records[0].Metadata).Int("chunk_index"); index != 1 {
+		t.Errorf("chunk_index = %v, want typed 1", records[0].Metadata["chunk_index"])
+	}
+}
+
+func TestClientRangeDeleteNamespaces(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	if err := client.UpsertBatch(ctx, testDocuments(), "recipes"); err != nil {
+		t.Fatalf("UpsertBatch: %v", err)
+	}
+
+	seen := 0
+	cursor := ""
+	for {
+		page, err := client.Range(ctx, "recipes", vector.RangeRequest{Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("Range: %v", err)
+		}
+		seen += len(page.Records)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if seen != 3 {
+		t.Errorf("range visited %d records, want 3", seen)
+	}
+
+	deleted, err := client.Delete(ctx, "recipes", []string{"a", "c"})
+	if err != nil || deleted != 2 {
+		t.Fatalf("Delete = %d, %v; want 2", deleted, err)
+	}
+
+	namespaces, err := client.ListNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("ListNamespaces: %v", err)
+	}
+	found := false
+	for _, ns := range namespaces {
+		found = found || ns == "recipes"
+	}
+	if !found {
+		t.Errorf("recipes missing from %v", namespaces)
+	}
+
+	info, err := client.Info(ctx)
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if info.VectorCount != 1 {
+		t.Errorf("VectorCount = %d, want 1", info.VectorCount)
+	}
+}
+
+func TestClientRejectsBadToken(t *testing.T) {
+	_, srv := newTestClient(t)
+	cfg := srv.Config()
+	cfg.Token = "wrong"
+	client, err := vector.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, err := client.ListNamespaces(context.Background()); err == nil {
+		t.Error("expected an error for an invalid token")
+	}
+}
@@ -0,0 +1,45 @@
+package vector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/typicalfo/prj-start/document"
+)
+
+// ContextHeaderLengthKey is the metadata key holding the byte length of the
+// synthesized header that was embedded in front of a chunk
+const ContextHeaderLengthKey = "context_header_length"
+
+// buildContextHeader describes where a chunk comes from: file, recipe and
+// heading breadcrumb or enclosing symbol. Embedding it alongside the chunk
+// lets fragments such as a bare return statement match queries about their file.
+func buildContextHeader(metadata document.Metadata) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "File: %s\n", metadata.String("source_file"))
+	if recipe := metadata.String("recipe_name"); recipe != "" && recipe != "root" {
+		fmt.Fprintf(&b, "Recipe: %s\n", recipe)
+	}
+	if heading := metadata.String("heading"); heading != "" {
+		fmt.Fprintf(&b, "Section: %s\n", heading)
+	}
+	if symbol := metadata.String("symbol"); symbol != "" {
+		if kind := metadata.String("kind"); kind != "" {
+			fmt.Fprintf(&b, "Symbol: %s %s\n", kind, symbol)
+		} else {
+			fmt.Fprintf(&b, "Symbol: %s\n", symbol)
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// StripContextHeader removes the embedded context header from a stored chunk,
+// returning the original chunk content. Data without a header is returned as is.
+func StripContextHeader(data string, metadata map[string]any) string {
+	length, ok := document.Metadata(metadata).Int(ContextHeaderLengthKey)
+	if !ok || length <= 0 || length > len(data) {
+		return data
+	}
+	return data[length:]
+}
@@ -0,0 +1,93 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+)
+
+// CopyNamespace pages through a namespace with Range and upserts every
+// record, vectors included, into the destination namespace, which may live
+// in another store. The namespace metadata field is rewritten to match the
+// destination. progress, if set, is called with the running total after
+// each page. It returns the number of records copied.
+func CopyNamespace(ctx context.Context, src VectorStore, srcNamespace string, dst VectorStore, dstNamespace string, pageSize int, progress func(copied int)) (int, error) {
+	copied := 0
+	cursor := ""
+	for {
+		page, err := src.Range(ctx, srcNamespace, RangeRequest{
+			Cursor:          cursor,
+			Limit:           pageSize,
+			IncludeMetadata: true,
+			IncludeData:     true,
+			IncludeVectors:  true,
+		})
+		if err != nil {
+			return copied, err
+		}
+
+		if len(page.Records) > 0 {
+			for i := range page.Records {
+				if _, ok := page.Records[i].Metadata["namespace"]; ok {
+					// Copy first: stores may hand out their own maps
+					metadata := make(map[string]interface{}, len(page.Records[i].Metadata))
+					for k, v := range page.Records[i].Metadata {
+						metadata[k] = v
+					}
+					metadata["namespace"] = dstNamespace
+					page.Records[i].Metadata = metadata
+				}
+			}
+			if err := dst.UpsertRecords(ctx, dstNamespace, page.Records); err != nil {
+				return copied, fmt.Errorf("failed after copying %d records: %w", copied, err)
+			}
+			copied += len(page.Records)
+			if progress != nil {
+				progress(copied)
+			}
+		}
+
+		if page.NextCursor == "" {
+			return copied, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// MissingRecords pages through the IDs of a namespace and fetches them from
+// the destination namespace, returning the IDs the destination does not hold.
+// It confirms a copy is complete before the source is removed.
+func MissingRecords(ctx context.Context, src VectorStore, srcNamespace string, dst VectorStore, dstNamespace string, pageSize int) ([]string, error) {
+	var missing []string
+	cursor := ""
+	for {
+		page, err := src.Range(ctx, srcNamespace, RangeRequest{Cursor: cursor, Limit: pageSize})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(page.Records) > 0 {
+			ids := make([]string, len(page.Records))
+			for i, record := range page.Records {
+				ids[i] = record.ID
+			}
+			found, err := dst.Fetch(ctx, dstNamespace, FetchRequest{IDs: ids})
+			if err != nil {
+				return nil, err
+			}
+			present := make(map[string]bool, len(found))
+			for _, record := range found {
+				present[record.ID] = true
+			}
+			for _, id := range ids {
+				if !present[id] {
+					missing = append(missing, id)
+				}
+			}
+		}
+
+		if page.NextCursor == "" {
+			return missing, nil
+		}
+		cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,43 @@
+package vector
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestCopyNamespaceAndMissingRecords(t *testing.T) {
+	dir := t.TempDir()
+	src := openTestStore(t, filepath.Join(dir, "src.jsonl"))
+	dst := openTestStore(t, filepath.Join(dir, "dst.jsonl"))
+	ctx := context.Background()
+	upsertTestDocuments(t, src, "docs", "a", "b", "c", "d", "e")
+
+	missing, err := MissingRecords(ctx, src, "docs", dst, "copy", 2)
+	if err != nil {
+		t.Fatalf("MissingRecords: %v", err)
+	}
+	if len(missing) != 5 {
+		t.Fatalf("expected all 5 records missing before the copy, got %v", missing)
+	}
+
+	copied, err := CopyNamespace(ctx, src, "docs", dst, "copy", 2, nil)
+	if err != nil || copied != 5 {
+		t.Fatalf("CopyNamespace copied %d: %v", copied, err)
+	}
+	ids := storeIDs(t, dst, "copy")
+	sort.Strings(ids)
+	if len(ids) != 5 || ids[0] != "a" || ids[4] != "e" {
+		t.Errorf("destination holds %v", ids)
+	}
+	if missing, err := MissingRecords(ctx, src, "docs", dst, "copy", 2); err != nil || len(missing) != 0 {
+		t.Errorf("expected nothing missing after the copy, got %v (%v)", missing, err)
+	}
+
+	// A record written to the source after the copy is reported
+	upsertTestDocuments(t, src, "docs", "f")
+	if missing, err := MissingRecords(ctx, src, "docs", dst, "copy", 2); err != nil || len(missing) != 1 || missing[0] != "f" {
+		t.Errorf("expected f missing, got %v (%v)", missing, err)
+	}
+}
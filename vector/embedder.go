@@ -0,0 +1,59 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/typicalfo/prj-start/config"
+)
+
+// Embedder turns text into dense vectors on the client side. When a store
+// has one it sends raw vectors instead of relying on server-side embedding.
+type Embedder interface {
+	// Embed returns one vector per text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Dimension is the vector length, or 0 until the first Embed call when
+	// the model decides it
+	Dimension() int
+}
+
+// NewEmbedder builds the embedder selected by cfg, or nil when none is configured
+func NewEmbedder(cfg config.EmbeddingConfig) (Embedder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(cfg.Provider) {
+	case config.EmbeddingOpenAI:
+		return NewOpenAIEmbedder(cfg), nil
+	case config.EmbeddingHash:
+		dimension := cfg.Dimension
+		if dimension == 0 {
+			dimension = defaultLocalDimension
+		}
+		return NewHashEmbedder(dimension), nil
+	}
+	return nil, nil
+}
+
+// embedderDimension returns the embedder's dimension, embedding a probe text
+// when the model only reveals it in a response
+func embedderDimension(ctx context.Context, embedder Embedder) (int, error) {
+	if dimension := embedder.Dimension(); dimension > 0 {
+		return dimension, nil
+	}
+	vectors, err := embedder.Embed(ctx, []string{"dimension probe"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine embedding dimension: %w", err)
+	}
+	return len(vectors[0]), nil
+}
+
+// embedOne embeds a single text
+func embedOne(ctx context.Context, embedder Embedder, text string) ([]float32, error) {
+	vectors, err := embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
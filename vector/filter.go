@@ -0,0 +1,550 @@
+package vector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MetadataFilter is a compiled metadata filter in Upstash's filter syntax,
+// evaluated client-side by the local store.
+//
+// Supported: =, !=, <, <=, >, >=, GLOB, NOT GLOB, IN (...), NOT IN (...),
+// CONTAINS, NOT CONTAINS, HAS FIELD, HAS NOT FIELD, AND, OR and parentheses.
+// Fields may use dotted paths and array indexes such as tags[0] or tags[#-1].
+type MetadataFilter interface {
+	Match(metadata map[string]interface{}) bool
+}
+
+// ParseFilter compiles a filter expression. An empty expression matches everything.
+func ParseFilter(expr string) (MetadataFilter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return matchAll{}, nil
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("filter: unexpected %q", p.peek().text)
+	}
+	return f, nil
+}
+
+type matchAll struct{}
+
+func (matchAll) Match(map[string]interface{}) bool { return true }
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		ch := expr[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(':
+			tokens = append(tokens, filterToken{tokLParen, "("})
+			i++
+		case ch == ')':
+			tokens = append(tokens, filterToken{tokRParen, ")"})
+			i++
+		case ch == ',':
+			tokens = append(tokens, filterToken{tokComma, ","})
+			i++
+		case ch == '\'' || ch == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(expr) && expr[j] != ch; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				sb.WriteByte(expr[j])
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("filter: unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, filterToken{tokString, sb.String()})
+			i = j + 1
+		case strings.ContainsRune("=!<>", rune(ch)):
+			op := string(ch)
+			if i+1 < len(expr) && expr[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("filter: unexpected '!' at offset %d", i)
+			}
+			tokens = append(tokens, filterToken{tokOp, op})
+			i += len(op)
+		case ch == '-' || ch == '+' || ch == '.' || (ch >= '0' && ch <= '9'):
+			j := i + 1
+			for j < len(expr) && strings.ContainsRune("0123456789.eE+-", rune(expr[j])) {
+				j++
+			}
+			if _, err := strconv.ParseFloat(expr[i:j], 64); err != nil {
+				return nil, fmt.Errorf("filter: invalid number %q", expr[i:j])
+			}
+			tokens = append(tokens, filterToken{tokNumber, expr[i:j]})
+			i = j
+		case isIdentChar(rune(ch)):
+			j := i
+			for j < len(expr) && (isIdentChar(rune(expr[j])) || expr[j] == '[' || expr[j] == ']' || expr[j] == '#' || (expr[j] == '-' && j > 0 && expr[j-1] == '#')) {
+				j++
+			}
+			tokens = append(tokens, filterToken{tokIdent, expr[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("filter: unexpected character %q at offset %d", ch, i)
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$' || r == '.'
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *filterParser) peek() filterToken {
+	if p.done() {
+		return filterToken{kind: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword consumes the given case-insensitive keyword if it is next
+func (p *filterParser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (MetadataFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (MetadataFilter, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseTerm() (MetadataFilter, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("filter: missing closing parenthesis")
+		}
+		return f, nil
+	}
+
+	if p.keyword("HAS") {
+		negate := p.keyword("NOT")
+		if !p.keyword("FIELD") {
+			return nil, fmt.Errorf("filter: expected FIELD after HAS")
+		}
+		field := p.next()
+		if field.kind != tokIdent {
+			return nil, fmt.Errorf("filter: expected field name after HAS FIELD")
+		}
+		return hasFieldFilter{path: field.text, negate: negate}, nil
+	}
+
+	field := p.next()
+	if field.kind != tokIdent {
+		return nil, fmt.Errorf("filter: expected field name, got %q", field.text)
+	}
+
+	negate := p.keyword("NOT")
+	switch {
+	case p.keyword("GLOB"):
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		re, err := globToRegexp(fmt.Sprint(value))
+		if err != nil {
+			return nil, err
+		}
+		return globFilter{path: field.text, re: re, negate: negate}, nil
+	case p.keyword("IN"):
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return inFilter{path: field.text, values: values, negate: negate}, nil
+	case p.keyword("CONTAINS"):
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return containsFilter{path: field.text, value: value, negate: negate}, nil
+	case negate:
+		return nil, fmt.Errorf("filter: expected GLOB, IN or CONTAINS after NOT")
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("filter: expected operator after %s", field.text)
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return compareFilter{path: field.text, op: op.text, value: value}, nil
+}
+
+func (p *filterParser) parseValue() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return t.text, nil
+	case tokNumber:
+		n, _ := strconv.ParseFloat(t.text, 64)
+		return n, nil
+	case tokIdent:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return nil, fmt.Errorf("filter: expected value, got %q", t.text)
+}
+
+func (p *filterParser) parseList() ([]interface{}, error) {
+	if p.next().kind != tokLParen {
+		return nil, fmt.Errorf("filter: expected ( after IN")
+	}
+	var values []interface{}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		switch p.next().kind {
+		case tokComma:
+			continue
+		case tokRParen:
+			return values, nil
+		default:
+			return nil, fmt.Errorf("filter: expected , or ) in IN list")
+		}
+	}
+}
+
+type andFilter struct{ left, right MetadataFilter }
+
+func (f andFilter) Match(m map[string]interface{}) bool { return f.left.Match(m) && f.right.Match(m) }
+
+type orFilter struct{ left, right MetadataFilter }
+
+func (f orFilter) Match(m map[string]interface{}) bool { return f.left.Match(m) || f.right.Match(m) }
+
+type hasFieldFilter struct {
+	path   string
+	negate bool
+}
+
+func (f hasFieldFilter) Match(m map[string]interface{}) bool {
+	_, ok := lookupField(m, f.path)
+	return ok != f.negate
+}
+
+type compareFilter struct {
+	path  string
+	op    string
+	value interface{}
+}
+
+func (f compareFilter) Match(m map[string]interface{}) bool {
+	actual, ok := lookupField(m, f.path)
+	if !ok {
+		return false
+	}
+	cmp, comparable := compareValues(actual, f.value)
+	switch f.op {
+	case "=":
+		return comparable && cmp == 0
+	case "!=":
+		return !comparable || cmp != 0
+	case "<":
+		return comparable && cmp < 0
+	case "<=":
+		return comparable && cmp <= 0
+	case ">":
+		return comparable && cmp > 0
+	case ">=":
+		return comparable && cmp >= 0
+	}
+	return false
+}
+
+type globFilter struct {
+	path   string
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (f globFilter) Match(m map[string]interface{}) bool {
+	actual, ok := lookupField(m, f.path)
+	if !ok {
+		return false
+	}
+	s, ok := actual.(string)
+	if !ok {
+		return false
+	}
+	return f.re.MatchString(s) != f.negate
+}
+
+type inFilter struct {
+	path   string
+	values []interface{}
+	negate bool
+}
+
+func (f inFilter) Match(m map[string]interface{}) bool {
+	actual, ok := lookupField(m, f.path)
+	if !ok {
+		return false
+	}
+	for _, v := range f.values {
+		if cmp, comparable := compareValues(actual, v); comparable && cmp == 0 {
+			return !f.negate
+		}
+	}
+	return f.negate
+}
+
+type containsFilter struct {
+	path   string
+	value  interface{}
+	negate bool
+}
+
+func (f containsFilter) Match(m map[string]interface{}) bool {
+	actual, ok := lookupField(m, f.path)
+	if !ok {
+		return false
+	}
+	items, ok := toList(actual)
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if cmp, comparable := compareValues(item, f.value); comparable && cmp == 0 {
+			return !f.negate
+		}
+	}
+	return f.negate
+}
+
+// lookupField resolves a dotted path with optional [n] or [#-n] array indexes
+func lookupField(m map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = m
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		var indexes []string
+		if i := strings.Index(part, "["); i >= 0 {
+			name = part[:i]
+			for _, idx := range strings.Split(part[i+1:], "[") {
+				indexes = append(indexes, strings.TrimSuffix(idx, "]"))
+			}
+		}
+
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = obj[name]; !ok {
+			return nil, false
+		}
+
+		for _, idx := range indexes {
+			arr, ok := toList(current)
+			if !ok {
+				return nil, false
+			}
+			var n int
+			var err error
+			if strings.HasPrefix(idx, "#") {
+				n, err = strconv.Atoi(strings.TrimPrefix(idx, "#"))
+				n += len(arr)
+			} else {
+				n, err = strconv.Atoi(idx)
+			}
+			if err != nil || n < 0 || n >= len(arr) {
+				return nil, false
+			}
+			current = arr[n]
+		}
+	}
+	return current, true
+}
+
+// compareValues orders a metadata value against a filter literal. Numeric
+// strings compare as numbers, since metadata ingested before typed values
+// stored numbers as strings.
+func compareValues(actual, literal interface{}) (int, bool) {
+	switch lit := literal.(type) {
+	case float64:
+		n, ok := toFloat(actual)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case n < lit:
+			return -1, true
+		case n > lit:
+			return 1, true
+		}
+		return 0, true
+	case bool:
+		b, ok := actual.(bool)
+		if !ok {
+			if s, isString := actual.(string); isString {
+				b, ok = s == "true", s == "true" || s == "false"
+			}
+		}
+		if !ok {
+			return 0, false
+		}
+		if b == lit {
+			return 0, true
+		}
+		return 1, true
+	case string:
+		s, ok := actual.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(s, lit), true
+	}
+	return 0, false
+}
+
+// toList returns array metadata as decoded from JSON or as written in memory
+func toList(v interface{}) ([]interface{}, bool) {
+	switch items := v.(type) {
+	case []interface{}:
+		return items, true
+	case []string:
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = item
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// globToRegexp converts a glob with *, ?, [abc] and [^abc] into an anchored regexp
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("filter: unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
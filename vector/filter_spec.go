@@ -0,0 +1,311 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/typicalfo/prj-start/document"
+)
+
+// FilterSpec is a structured metadata filter, validated against the metadata
+// schema and compiled into the filter syntax by Compile. It is either a
+// condition on Field, or a group of filters joined by And or Or:
+//
+//	{"field": "extension", "op": "=", "value": ".go"}
+//	{"and": [{"field": "tags", "op": "contains", "value": "auth"},
+//	         {"or": [{"field": "chunk_index", "op": "<", "value": 3}, ...]}]}
+type FilterSpec struct {
+	Field string       `json:"field,omitempty"`
+	Op    string       `json:"op,omitempty"`
+	Value interface{}  `json:"value,omitempty"`
+	And   []FilterSpec `json:"and,omitempty"`
+	Or    []FilterSpec `json:"or,omitempty"`
+}
+
+// ParseFilterSpec decodes a structured filter from JSON. Unknown keys are
+// rejected so a misspelled "operator" does not silently match everything.
+func ParseFilterSpec(data []byte) (*FilterSpec, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var spec FilterSpec
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid structured filter: %w (a condition has field, op and value; a group has and or or)", err)
+	}
+	return &spec, nil
+}
+
+// Filter operators, keyed by every accepted spelling
+var filterOps = map[string]string{
+	"=": "=", "==": "=", "eq": "=",
+	"!=": "!=", "<>": "!=", "ne": "!=", "neq": "!=",
+	"<": "<", "lt": "<",
+	"<=": "<=", "lte": "<=", "le": "<=",
+	">": ">", "gt": ">",
+	">=": ">=", "gte": ">=", "ge": ">=",
+	"glob": "GLOB", "not glob": "NOT GLOB",
+	"in": "IN", "not in": "NOT IN", "nin": "NOT IN",
+	"contains": "CONTAINS", "not contains": "NOT CONTAINS",
+	"exists": "HAS FIELD", "has field": "HAS FIELD",
+	"not exists": "HAS NOT FIELD", "has not field": "HAS NOT FIELD",
+}
+
+// Operators allowed for each field type; "" is a path inside an object,
+// whose type is not declared
+var filterTypeOps = map[document.FieldType][]string{
+	document.FieldString:  {"=", "!=", "GLOB", "NOT GLOB", "IN", "NOT IN"},
+	document.FieldInt:     {"=", "!=", "<", "<=", ">", ">=", "IN", "NOT IN"},
+	document.FieldFloat:   {"=", "!=", "<", "<=", ">", ">=", "IN", "NOT IN"},
+	document.FieldBool:    {"=", "!="},
+	document.FieldStrings: {"CONTAINS", "NOT CONTAINS"},
+	document.FieldObject:  {},
+	"":                    {"=", "!=", "<", "<=", ">", ">=", "GLOB", "NOT GLOB", "IN", "NOT IN", "CONTAINS", "NOT CONTAINS"},
+}
+
+var arrayIndexRegex = regexp.MustCompile(`^(\[(\d+|#-\d+)\])+$`)
+
+// Compile validates the filter against schema and renders it in the filter
+// syntax accepted by Upstash and ParseFilter. Errors name the offending part
+// of the filter, e.g. "where.and[1]", and say how to fix it.
+func (f *FilterSpec) Compile(schema document.Schema) (string, error) {
+	return f.compile(schema, "where", false)
+}
+
+func (f *FilterSpec) compile(schema document.Schema, at string, nested bool) (string, error) {
+	isCondition := f.Field != "" || f.Op != "" || f.Value != nil
+	switch {
+	case isCondition && (f.And != nil || f.Or != nil):
+		return "", fmt.Errorf("%s: a filter is either a condition (field, op, value) or a group (and, or), not both", at)
+	case f.And != nil && f.Or != nil:
+		return "", fmt.Errorf("%s: use and or or, not both; nest one group inside the other", at)
+	case f.And != nil:
+		return compileGroup(f.And, "and", schema, at, nested)
+	case f.Or != nil:
+		return compileGroup(f.Or, "or", schema, at, nested)
+	case !isCondition:
+		return "", fmt.Errorf("%s: empty filter; give field, op and value, or an and/or group", at)
+	}
+	return f.compileCondition(schema, at)
+}
+
+func compileGroup(filters []FilterSpec, joiner string, schema document.Schema, at string, nested bool) (string, error) {
+	if len(filters) == 0 {
+		return "", fmt.Errorf("%s.%s: group is empty", at, joiner)
+	}
+	parts := make([]string, len(filters))
+	for i := range filters {
+		part, err := filters[i].compile(schema, fmt.Sprintf("%s.%s[%d]", at, joiner, i), true)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	expr := strings.Join(parts, " "+strings.ToUpper(joiner)+" ")
+	if nested && len(parts) > 1 {
+		expr = "(" + expr + ")"
+	}
+	return expr, nil
+}
+
+func (f *FilterSpec) compileCondition(schema document.Schema, at string) (string, error) {
+	if f.Field == "" {
+		return "", fmt.Errorf("%s: field is required", at)
+	}
+	fieldType, err := filterFieldType(schema, f.Field)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", at, err)
+	}
+
+	op, ok := filterOps[strings.Join(strings.Fields(strings.ToLower(f.Op)), " ")]
+	if !ok {
+		return "", fmt.Errorf("%s: unknown op %q; use one of =, !=, <, <=, >, >=, glob, not glob, in, not in, contains, not contains, exists, not exists", at, f.Op)
+	}
+	if op == "HAS FIELD" || op == "HAS NOT FIELD" {
+		return op + " " + f.Field, nil
+	}
+	if allowed := filterTypeOps[fieldType]; !containsString(allowed, op) {
+		if len(allowed) == 0 {
+			return "", fmt.Errorf("%s: field %q is an %s; filter on a nested path such as %s.key, or use exists", at, f.Field, fieldType, f.Field)
+		}
+		return "", fmt.Errorf("%s: op %q does not apply to %s field %q; use one of %s", at, f.Op, fieldType, f.Field, strings.ToLower(strings.Join(allowed, ", ")))
+	}
+
+	// Values are checked against the element type for lists and arrays
+	valueType := fieldType
+	switch op {
+	case "CONTAINS", "NOT CONTAINS":
+		if fieldType == document.FieldStrings {
+			valueType = document.FieldString
+		}
+	case "GLOB", "NOT GLOB":
+		valueType = document.FieldString
+	}
+
+	if op == "IN" || op == "NOT IN" {
+		values, ok := f.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return "", fmt.Errorf("%s: op %q needs a non-empty list value, e.g. [\".go\", \".md\"]", at, f.Op)
+		}
+		literals := make([]string, len(values))
+		for i, value := range values {
+			literal, err := filterLiteral(value, valueType)
+			if err != nil {
+				return "", fmt.Errorf("%s: value[%d] for field %q: %w", at, i, f.Field, err)
+			}
+			literals[i] = literal
+		}
+		return fmt.Sprintf("%s %s (%s)", f.Field, op, strings.Join(literals, ", ")), nil
+	}
+
+	literal, err := filterLiteral(f.Value, valueType)
+	if err != nil {
+		return "", fmt.Errorf("%s: value for field %q: %w", at, f.Field, err)
+	}
+	return fmt.Sprintf("%s %s %s", f.Field, op, literal), nil
+}
+
+// filterFieldType resolves the declared type of a field path: a schema key,
+// an index into a string array such as tags[0], or a path inside an object
+// such as config.port (type "", not declared)
+func filterFieldType(schema document.Schema, field string) (document.FieldType, error) {
+	key, rest := field, ""
+	if i := strings.IndexAny(field, ".["); i >= 0 {
+		key, rest = field[:i], field[i:]
+	}
+	fieldType, ok := schema[key]
+	if !ok {
+		return "", unknownFieldError(schema, key)
+	}
+	switch {
+	case rest == "":
+		return fieldType, nil
+	case fieldType == document.FieldStrings && arrayIndexRegex.MatchString(rest):
+		return document.FieldString, nil
+	case fieldType == document.FieldObject && strings.HasPrefix(rest, ".") && len(rest) > 1:
+		return "", nil
+	}
+	return "", fmt.Errorf("field %q: %s is not valid on %s field %q", field, rest, fieldType, key)
+}
+
+func unknownFieldError(schema document.Schema, key string) error {
+	known := make([]string, 0, len(schema))
+	for name := range schema {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+
+	hint := ""
+	best, bestDistance := "", math.MaxInt
+	for _, name := range known {
+		if d := editDistance(strings.ToLower(key), name); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	if bestDistance <= max(2, len(key)/3) {
+		hint = fmt.Sprintf("did you mean %q? ", best)
+	}
+	return fmt.Errorf("unknown field %q; %sknown fields: %s (declare others under metadata_schema in the config)", key, hint, strings.Join(known, ", "))
+}
+
+// filterLiteral renders a JSON value as a filter literal of the given type
+func filterLiteral(value interface{}, fieldType document.FieldType) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("value is required")
+	case string:
+		if fieldType != document.FieldString && fieldType != "" {
+			return "", fmt.Errorf("expected %s, got string %q%s", typeExample(fieldType), v, numericHint(v, fieldType))
+		}
+		return quoteFilterString(v), nil
+	case float64:
+		switch fieldType {
+		case document.FieldInt:
+			if v != math.Trunc(v) {
+				return "", fmt.Errorf("expected %s, got %v", typeExample(fieldType), v)
+			}
+		case document.FieldFloat, "":
+		default:
+			return "", fmt.Errorf("expected %s, got number %v", typeExample(fieldType), v)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		if fieldType != document.FieldBool && fieldType != "" {
+			return "", fmt.Errorf("expected %s, got %v", typeExample(fieldType), v)
+		}
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("expected %s, got %T", typeExample(fieldType), value)
+}
+
+func typeExample(fieldType document.FieldType) string {
+	switch fieldType {
+	case document.FieldString:
+		return `a string such as "api/main.go"`
+	case document.FieldInt:
+		return "a whole number such as 3"
+	case document.FieldFloat:
+		return "a number such as 0.5"
+	case document.FieldBool:
+		return "true or false"
+	}
+	return "a string, number or boolean"
+}
+
+// numericHint suggests dropping the quotes around a number
+func numericHint(value string, fieldType document.FieldType) string {
+	if fieldType != document.FieldInt && fieldType != document.FieldFloat {
+		return ""
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("; drop the quotes: %s", value)
+}
+
+// quoteFilterString quotes a string literal, preferring quotes that do not
+// occur in it. Backslashes start escapes, so strings with one are escaped.
+func quoteFilterString(s string) string {
+	switch {
+	case strings.Contains(s, `\`):
+	case !strings.Contains(s, "'"):
+		return "'" + s + "'"
+	case !strings.Contains(s, `"`):
+		return `"` + s + `"`
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
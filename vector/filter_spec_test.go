@@ -0,0 +1,164 @@
+package vector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/document"
+)
+
+func testFilterSchema(t *testing.T) document.Schema {
+	t.Helper()
+	schema, err := document.ParseSchema(map[string]string{"score": "float", "draft": "bool", "config": "object"})
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	return schema
+}
+
+func TestFilterSpecCompile(t *testing.T) {
+	schema := testFilterSchema(t)
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"equals", `{"field": "extension", "op": "=", "value": ".go"}`, "extension = '.go'"},
+		{"equals alias", `{"field": "extension", "op": "eq", "value": ".go"}`, "extension = '.go'"},
+		{"not equals", `{"field": "extension", "op": "!=", "value": ".md"}`, "extension != '.md'"},
+		{"not equals alias", `{"field": "extension", "op": "<>", "value": ".md"}`, "extension != '.md'"},
+		{"less", `{"field": "chunk_index", "op": "<", "value": 3}`, "chunk_index < 3"},
+		{"less or equal", `{"field": "chunk_index", "op": "lte", "value": 3}`, "chunk_index <= 3"},
+		{"greater", `{"field": "score", "op": "gt", "value": 0.5}`, "score > 0.5"},
+		{"greater or equal", `{"field": "start_line", "op": ">=", "value": 10}`, "start_line >= 10"},
+		{"glob", `{"field": "source_file", "op": "glob", "value": "api/*.go"}`, "source_file GLOB 'api/*.go'"},
+		{"not glob", `{"field": "source_file", "op": "NOT  GLOB", "value": "test/*"}`, "source_file NOT GLOB 'test/*'"},
+		{"in", `{"field": "extension", "op": "in", "value": [".go", ".md"]}`, "extension IN ('.go', '.md')"},
+		{"not in", `{"field": "chunk_index", "op": "nin", "value": [0, 1]}`, "chunk_index NOT IN (0, 1)"},
+		{"contains", `{"field": "tags", "op": "contains", "value": "auth"}`, "tags CONTAINS 'auth'"},
+		{"not contains", `{"field": "tags", "op": "not contains", "value": "legacy"}`, "tags NOT CONTAINS 'legacy'"},
+		{"exists", `{"field": "symbol", "op": "exists"}`, "HAS FIELD symbol"},
+		{"not exists", `{"field": "parent_id", "op": "not exists"}`, "HAS NOT FIELD parent_id"},
+		{"bool", `{"field": "draft", "op": "=", "value": false}`, "draft = false"},
+		{"array index", `{"field": "tags[0]", "op": "=", "value": "auth"}`, "tags[0] = 'auth'"},
+		{"array index from end", `{"field": "tags[#-1]", "op": "glob", "value": "a*"}`, "tags[#-1] GLOB 'a*'"},
+		{"object path", `{"field": "config.port", "op": ">", "value": 1024}`, "config.port > 1024"},
+		{"and", `{"and": [{"field": "extension", "op": "=", "value": ".go"}, {"field": "chunk_index", "op": "<", "value": 3}]}`,
+			"extension = '.go' AND chunk_index < 3"},
+		{"nested groups", `{"and": [{"field": "extension", "op": "=", "value": ".go"}, {"or": [{"field": "tags", "op": "contains", "value": "auth"}, {"field": "chunk_index", "op": "=", "value": 0}]}]}`,
+			"extension = '.go' AND (tags CONTAINS 'auth' OR chunk_index = 0)"},
+		{"single-item group", `{"or": [{"and": [{"field": "extension", "op": "=", "value": ".go"}]}]}`, "extension = '.go'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseFilterSpec([]byte(tt.spec))
+			if err != nil {
+				t.Fatalf("ParseFilterSpec: %v", err)
+			}
+			got, err := spec.Compile(schema)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if _, err := ParseFilter(got); err != nil {
+				t.Errorf("compiled filter does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestFilterSpecCompileErrors(t *testing.T) {
+	schema := testFilterSchema(t)
+	tests := []struct {
+		name string
+		spec string
+		want string // substring of the error
+	}{
+		{"string for int", `{"field": "chunk_index", "op": "=", "value": "3"}`, "drop the quotes: 3"},
+		{"fraction for int", `{"field": "chunk_index", "op": "<", "value": 2.5}`, "whole number"},
+		{"number for string", `{"field": "extension", "op": "=", "value": 1}`, "got number 1"},
+		{"bool for float", `{"field": "score", "op": ">", "value": true}`, "a number such as 0.5"},
+		{"string for bool", `{"field": "draft", "op": "=", "value": "yes"}`, "true or false"},
+		{"list for string", `{"field": "extension", "op": "=", "value": [".go"]}`, "got []interface {}"},
+		{"bad list element", `{"field": "chunk_index", "op": "in", "value": [1, "two"]}`, "where: value[1]"},
+		{"in without list", `{"field": "extension", "op": "in", "value": ".go"}`, "non-empty list"},
+		{"empty in list", `{"field": "extension", "op": "in", "value": []}`, "non-empty list"},
+		{"missing value", `{"field": "extension", "op": "="}`, "value is required"},
+		{"order on string", `{"field": "extension", "op": "<", "value": ".go"}`, "does not apply to string"},
+		{"equals on array", `{"field": "tags", "op": "=", "value": "auth"}`, "use one of contains, not contains"},
+		{"contains on string", `{"field": "extension", "op": "contains", "value": "g"}`, "does not apply to string"},
+		{"glob on bool", `{"field": "draft", "op": "glob", "value": "t*"}`, "does not apply to bool"},
+		{"compare object", `{"field": "config", "op": "=", "value": "x"}`, "nested path such as config.key"},
+		{"index into string", `{"field": "extension[0]", "op": "=", "value": "."}`, "is not valid on string"},
+		{"path into array", `{"field": "tags.first", "op": "=", "value": "auth"}`, "is not valid on string_array"},
+		{"unknown field", `{"field": "extention", "op": "=", "value": ".go"}`, `did you mean "extension"?`},
+		{"unknown op", `{"field": "extension", "op": "like", "value": ".go"}`, `unknown op "like"`},
+		{"missing field", `{"op": "=", "value": ".go"}`, "field is required"},
+		{"empty filter", `{}`, "empty filter"},
+		{"condition and group", `{"field": "extension", "op": "=", "value": ".go", "and": [{"field": "chunk_index", "op": "=", "value": 0}]}`, "not both"},
+		{"and with or", `{"and": [{"field": "chunk_index", "op": "=", "value": 0}], "or": [{"field": "chunk_index", "op": "=", "value": 1}]}`, "use and or or"},
+		{"empty group", `{"and": []}`, "where.and: group is empty"},
+		{"nested error path", `{"and": [{"field": "extension", "op": "=", "value": ".go"}, {"or": [{"field": "tags", "op": "=", "value": "x"}]}]}`, "where.and[1].or[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseFilterSpec([]byte(tt.spec))
+			if err != nil {
+				t.Fatalf("ParseFilterSpec: %v", err)
+			}
+			_, err = spec.Compile(schema)
+			if err == nil {
+				t.Fatalf("expected an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterSpecRejectsUnknownKeys(t *testing.T) {
+	if _, err := ParseFilterSpec([]byte(`{"field": "extension", "operator": "=", "value": ".go"}`)); err == nil {
+		t.Fatal("expected an error for the unknown key operator")
+	}
+}
+
+func TestFilterSpecQuoting(t *testing.T) {
+	schema := testFilterSchema(t)
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "'plain'"},
+		{"it's", `"it's"`},
+		{`say "hi"`, `'say "hi"'`},
+		{`it's "quoted"`, `'it\'s "quoted"'`},
+		{`back\slash`, `'back\\slash'`},
+		{`it's a \ "mix"`, `'it\'s a \\ "mix"'`},
+		{"", "''"},
+	}
+	for _, tt := range tests {
+		spec := &FilterSpec{Field: "heading", Op: "=", Value: tt.value}
+		got, err := spec.Compile(schema)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", tt.value, err)
+		}
+		if want := "heading = " + tt.want; got != want {
+			t.Errorf("value %q compiled to %s, want %s", tt.value, got, want)
+		}
+
+		// The filter evaluator reads the literal back as the original value
+		filter, err := ParseFilter(got)
+		if err != nil {
+			t.Fatalf("ParseFilter(%s): %v", got, err)
+		}
+		if !filter.Match(map[string]interface{}{"heading": tt.value}) {
+			t.Errorf("%s does not match the value %q", got, tt.value)
+		}
+		if filter.Match(map[string]interface{}{"heading": tt.value + "x"}) {
+			t.Errorf("%s matches a different value", got)
+		}
+	}
+}
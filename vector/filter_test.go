@@ -0,0 +1,77 @@
+package vector
+
+import "testing"
+
+func TestParseFilterMatch(t *testing.T) {
+	metadata := map[string]interface{}{
+		"extension":   ".go",
+		"source_file": "api/handlers/user.go",
+		"chunk_index": float64(2),
+		"generated":   false,
+		"tags":        []interface{}{"auth", "http"},
+		"config":      map[string]interface{}{"port": float64(8080), "name": "it's"},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"", true},
+		{"extension = '.go'", true},
+		{`extension = ".md"`, false},
+		{"extension != '.md'", true},
+		{"chunk_index < 3", true},
+		{"chunk_index <= 2", true},
+		{"chunk_index > 2", false},
+		{"chunk_index >= 2.0", true},
+		{"generated = false", true},
+		{"source_file GLOB 'api/*/user.go'", true},
+		{"source_file GLOB 'api/*.go'", true},
+		{"source_file NOT GLOB 'cmd/*'", true},
+		{"extension IN ('.go', '.md')", true},
+		{"extension NOT IN ('.go', '.md')", false},
+		{"chunk_index IN (1, 2)", true},
+		{"tags CONTAINS 'auth'", true},
+		{"tags NOT CONTAINS 'grpc'", true},
+		{"tags[0] = 'auth'", true},
+		{"tags[#-1] = 'http'", true},
+		{"tags[2] = 'http'", false},
+		{"config.port = 8080", true},
+		{`config.name = 'it\'s'`, true},
+		{`config.name = "it's"`, true},
+		{"HAS FIELD config.port", true},
+		{"HAS NOT FIELD priority", true},
+		{"HAS FIELD priority", false},
+		{"priority > 1", false},
+		{"extension = '.go' AND chunk_index > 5", false},
+		{"extension = '.md' OR chunk_index = 2", true},
+		{"extension = '.md' AND chunk_index = 2 OR generated = false", true},
+		{"extension = '.md' AND (chunk_index = 2 OR generated = false)", false},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := filter.Match(metadata); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"extension = '.go",
+		"extension ! '.go'",
+		"extension = '.go' AND",
+		"(extension = '.go'",
+		"extension IN '.go'",
+		"chunk_index = 1.2.3",
+		"extension = '.go' extension",
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q): expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package vector
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashEmbedder is a deterministic, dependency-free Embedder built on
+// hashEmbedding. It is the local store's default and is handy in tests.
+type HashEmbedder struct {
+	dimension int
+}
+
+var _ Embedder = (*HashEmbedder)(nil)
+
+func NewHashEmbedder(dimension int) *HashEmbedder {
+	if dimension <= 0 {
+		dimension = defaultLocalDimension
+	}
+	return &HashEmbedder{dimension: dimension}
+}
+
+func (h *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = hashEmbedding(text, h.dimension)
+	}
+	return vectors, nil
+}
+
+func (h *HashEmbedder) Dimension() int {
+	return h.dimension
+}
+
+// hashEmbedding turns text into a deterministic bag-of-words vector using the
+// hashing trick: every lowercased token and adjacent token pair is hashed into
+// one of dimension buckets with a hashed sign. The result is L2-normalised.
+// It needs no model or network, which makes it suitable for offline stores.
+func hashEmbedding(text string, dimension int) []float32 {
+	v := make([]float32, dimension)
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		sign := float32(1)
+		if sum>>63 == 1 {
+			sign = -1
+		}
+		v[sum%uint64(dimension)] += sign * weight
+	}
+	for i, token := range tokens {
+		add(token, 1)
+		if i > 0 {
+			add(tokens[i-1]+" "+token, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range v {
+			v[i] *= scale
+		}
+	}
+	return v
+}
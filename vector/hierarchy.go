@@ -0,0 +1,220 @@
+package vector
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+
+	"github.com/typicalfo/prj-start/document"
+)
+
+// ParentLevel controls which parent records the upserter creates for
+// small-to-big retrieval
+type ParentLevel string
+
+const (
+	// ParentsNone indexes chunks flat, without parent records
+	ParentsNone ParentLevel = ""
+	// ParentsFile adds one outline record per file
+	ParentsFile ParentLevel = "file"
+	// ParentsSection adds the file outline plus one record per section
+	// (a Markdown section or a type/class with its methods)
+	ParentsSection ParentLevel = "section"
+)
+
+// ParseParentLevel validates a parent level from config or flags
+func ParseParentLevel(level string) (ParentLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "", "none":
+		return ParentsNone, nil
+	case "file":
+		return ParentsFile, nil
+	case "section":
+		return ParentsSection, nil
+	}
+	return ParentsNone, fmt.Errorf("invalid parent level '%s' (expected none, file or section)", level)
+}
+
+// maxSectionSize caps the content stored for a section record
+const maxSectionSize = 16 * 1024
+
+// buildParentDocuments creates the outline (and section) records for a file
+// and links the child documents to them through parent_id and file_id.
+func (u *Upserter) buildParentDocuments(doc document.FileInfo, namespace string, chunks []document.Chunk, children []Document) []Document {
+	if u.parentLevel == ParentsNone || len(children) == 0 {
+		return nil
+	}
+
+	fileID := generateParentID(doc.RelativePath, "file")
+	fileMetadata := u.parentMetadata(children[0].Metadata, "file")
+	fileMetadata["symbol"] = doc.RelativePath
+	fileMetadata["child_count"] = len(children)
+
+	parents := []Document{{
+		ID:        fileID,
+		Content:   buildOutline(doc.RelativePath, chunks),
+		Metadata:  fileMetadata,
+		Namespace: namespace,
+	}}
+
+	for i := range children {
+		children[i].Metadata["level"] = "chunk"
+		children[i].Metadata["file_id"] = fileID
+		children[i].Metadata["parent_id"] = fileID
+	}
+
+	if u.parentLevel != ParentsSection {
+		return parents
+	}
+
+	// Group consecutive chunks that share a section key
+	start := 0
+	for start < len(chunks) {
+		key := sectionKey(chunks[start])
+		end := start + 1
+		for end < len(chunks) && key != "" && sectionKey(chunks[end]) == key {
+			end++
+		}
+
+		// A section of one chunk is already its own context
+		if key != "" && end-start > 1 {
+			sectionID := generateParentID(doc.RelativePath, "section:"+key)
+			metadata := u.parentMetadata(children[start].Metadata, "section")
+			metadata["symbol"] = key
+			metadata["parent_id"] = fileID
+			metadata["child_count"] = end - start
+			copyLocation(metadata, chunks[start], chunks[end-1])
+
+			parents = append(parents, Document{
+				ID:        sectionID,
+				Content:   sectionContent(doc.Content, chunks[start:end]),
+				Metadata:  metadata,
+				Namespace: namespace,
+			})
+			for i := start; i < end; i++ {
+				children[i].Metadata["parent_id"] = sectionID
+			}
+		}
+		start = end
+	}
+
+	return parents
+}
+
+// parentMetadata copies the file-level metadata of a child for a parent record
+func (u *Upserter) parentMetadata(child document.Metadata, level string) document.Metadata {
+	metadata := make(document.Metadata)
+	for _, key := range []string{"filename", "topic", "extension", "source_file", "file_size", "namespace", "full_path", "recipe_name", "project_type", "total_chunks"} {
+		if v, ok := child[key]; ok {
+			metadata[key] = v
+		}
+	}
+	metadata["level"] = level
+	metadata["chunk_type"] = level + "_outline"
+	if level == "section" {
+		metadata["chunk_type"] = "section"
+	}
+	return metadata
+}
+
+// buildOutline lists the declarations or headings of a file, falling back to
+// the first line of each chunk for unstructured text
+func buildOutline(relativePath string, chunks []document.Chunk) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Outline of %s\n\n", relativePath)
+
+	seen := make(map[string]bool)
+	for _, chunk := range chunks {
+		var entry string
+		switch {
+		case chunk.Metadata.String("symbol") != "":
+			entry = strings.TrimSpace(chunk.Metadata.String("kind") + " " + chunk.Metadata.String("symbol"))
+		case chunk.Metadata.String("heading") != "":
+			entry = chunk.Metadata.String("heading")
+		default:
+			entry = firstLine(chunk.Content)
+		}
+		if entry == "" || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+
+		switch {
+		case chunk.StartLine > 0 && chunk.EndLine > chunk.StartLine:
+			fmt.Fprintf(&b, "- %s (lines %d-%d)\n", entry, chunk.StartLine, chunk.EndLine)
+		case chunk.StartLine > 0:
+			fmt.Fprintf(&b, "- %s (line %d)\n", entry, chunk.StartLine)
+		default:
+			fmt.Fprintf(&b, "- %s\n", entry)
+		}
+	}
+	return b.String()
+}
+
+// sectionKey returns the section a chunk belongs to: the receiver or class of
+// a method, or the top two levels of a Markdown heading breadcrumb
+func sectionKey(chunk document.Chunk) string {
+	if symbol := chunk.Metadata.String("symbol"); symbol != "" {
+		if idx := strings.LastIndex(symbol, "."); idx > 0 {
+			return symbol[:idx]
+		}
+		switch chunk.Metadata.String("kind") {
+		case "struct", "type", "interface", "class":
+			return symbol
+		}
+		return ""
+	}
+	if heading := chunk.Metadata.String("heading"); heading != "" {
+		parts := strings.Split(heading, " > ")
+		if len(parts) > 2 {
+			parts = parts[:2]
+		}
+		return strings.Join(parts, " > ")
+	}
+	return ""
+}
+
+// sectionContent returns the original text spanned by the chunks, or the
+// chunks joined together when their location is unknown
+func sectionContent(content string, chunks []document.Chunk) string {
+	first, last := chunks[0], chunks[len(chunks)-1]
+	var text string
+	if first.EndByte > 0 && last.EndByte > first.StartByte && last.EndByte <= len(content) {
+		text = content[first.StartByte:last.EndByte]
+	} else {
+		parts := make([]string, len(chunks))
+		for i, c := range chunks {
+			parts[i] = c.Content
+		}
+		text = strings.Join(parts, "\n\n")
+	}
+	if len(text) > maxSectionSize {
+		text = text[:maxSectionSize]
+	}
+	return text
+}
+
+func copyLocation(metadata document.Metadata, first, last document.Chunk) {
+	if first.StartLine > 0 && last.EndLine > 0 {
+		metadata["start_line"] = first.StartLine
+		metadata["end_line"] = last.EndLine
+		metadata["start_byte"] = first.StartByte
+		metadata["end_byte"] = last.EndByte
+	}
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if idx := strings.Index(text, "\n"); idx >= 0 {
+		text = text[:idx]
+	}
+	if len(text) > 80 {
+		text = text[:80] + "..."
+	}
+	return text
+}
+
+func generateParentID(filePath, key string) string {
+	hash := md5.Sum([]byte(fmt.Sprintf("%s#%s", filePath, key)))
+	return fmt.Sprintf("doc_%x", hash[:8])
+}
@@ -0,0 +1,128 @@
+package vector
+
+import (
+	"math"
+	"sort"
+)
+
+// rrfK is the rank offset of reciprocal rank fusion, as used by Upstash
+const rrfK = 60
+
+// hybridScores runs the dense and sparse halves of a hybrid query over the
+// filtered records of a namespace and fuses them
+func (s *LocalStore) hybridScores(ns *localNamespace, queryVector []float32, sparseQuery *SparseVector, filter MetadataFilter, fusion, weighting string) []QueryResult {
+	ids := sortedRecordIDs(ns.records)
+	dense := s.score(ns, ids, queryVector, filter)
+
+	// Query term weights: IDF over the namespace, or one for NONE
+	weights := make([]float64, len(sparseQuery.Indices))
+	for i := range weights {
+		weights[i] = 1
+	}
+	if weighting != SparseWeightingNone {
+		docFreq := make([]int, len(sparseQuery.Indices))
+		total := 0
+		for _, record := range ns.records {
+			if record.SparseVector == nil {
+				continue
+			}
+			total++
+			for i, index := range sparseQuery.Indices {
+				if _, ok := sparseValue(record.SparseVector, index); ok {
+					docFreq[i]++
+				}
+			}
+		}
+		for i, n := range docFreq {
+			weights[i] = bm25IDF(total, n)
+		}
+	}
+
+	var sparse []QueryResult
+	for _, id := range ids {
+		record := ns.records[id]
+		if record.SparseVector == nil || !filter.Match(record.Metadata) {
+			continue
+		}
+		score := 0.0
+		for i, index := range sparseQuery.Indices {
+			if value, ok := sparseValue(record.SparseVector, index); ok {
+				score += weights[i] * float64(value)
+			}
+		}
+		if score > 0 {
+			sparse = append(sparse, QueryResult{ID: id, Score: score})
+		}
+	}
+
+	if fusion == FusionDBSF {
+		return fuseDBSF(dense, sparse)
+	}
+	return fuseRRF(dense, sparse)
+}
+
+// sparseValue looks up a dimension in a sparse vector with sorted indices
+func sparseValue(v *SparseVector, index int32) (float32, bool) {
+	i := sort.Search(len(v.Indices), func(i int) bool { return v.Indices[i] >= index })
+	if i < len(v.Indices) && v.Indices[i] == index {
+		return v.Values[i], true
+	}
+	return 0, false
+}
+
+// fuseRRF scores each result by the sum of 1/(k + rank) over both lists
+func fuseRRF(lists ...[]QueryResult) []QueryResult {
+	fused := make(map[string]float64)
+	for _, list := range lists {
+		sorted := append([]QueryResult(nil), list...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Score > sorted[j].Score })
+		for rank, result := range sorted {
+			fused[result.ID] += 1 / float64(rrfK+rank+1)
+		}
+	}
+	return fusedResults(fused)
+}
+
+// fuseDBSF normalises each list to [0, 1] using mean ± 3 standard deviations
+// as bounds, then sums the normalised scores
+func fuseDBSF(lists ...[]QueryResult) []QueryResult {
+	fused := make(map[string]float64)
+	for _, list := range lists {
+		if len(list) == 0 {
+			continue
+		}
+		var mean, variance float64
+		for _, result := range list {
+			mean += result.Score
+		}
+		mean /= float64(len(list))
+		for _, result := range list {
+			variance += (result.Score - mean) * (result.Score - mean)
+		}
+		std := math.Sqrt(variance / float64(len(list)))
+		lower, upper := mean-3*std, mean+3*std
+
+		for _, result := range list {
+			normalised := 1.0
+			if upper > lower {
+				normalised = math.Max(0, math.Min(1, (result.Score-lower)/(upper-lower)))
+			}
+			fused[result.ID] += normalised
+		}
+	}
+	return fusedResults(fused)
+}
+
+func fusedResults(fused map[string]float64) []QueryResult {
+	results := make([]QueryResult, 0, len(fused))
+	for id, score := range fused {
+		results = append(results, QueryResult{ID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
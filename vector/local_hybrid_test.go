@@ -0,0 +1,90 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+func resultIDs(results []QueryResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
+
+func TestFuseRRF(t *testing.T) {
+	dense := []QueryResult{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.8}, {ID: "c", Score: 0.1}}
+	sparse := []QueryResult{{ID: "b", Score: 3}, {ID: "c", Score: 2}}
+
+	fused := fuseRRF(dense, sparse)
+	if got := resultIDs(fused); len(got) != 3 || got[0] != "b" || got[1] != "c" || got[2] != "a" {
+		t.Fatalf("expected b, c, a; got %v", got)
+	}
+	want := 1.0/(rrfK+2) + 1.0/(rrfK+1)
+	if math.Abs(fused[0].Score-want) > 1e-12 {
+		t.Errorf("b scored %v, want %v", fused[0].Score, want)
+	}
+
+	// Input order does not matter, only scores
+	reordered := fuseRRF([]QueryResult{dense[2], dense[0], dense[1]}, sparse)
+	for i := range fused {
+		if reordered[i].ID != fused[i].ID || reordered[i].Score != fused[i].Score {
+			t.Fatalf("fusion depends on input order: %v vs %v", reordered, fused)
+		}
+	}
+}
+
+func TestFuseDBSF(t *testing.T) {
+	dense := []QueryResult{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.5}, {ID: "c", Score: 0.1}}
+	sparse := []QueryResult{{ID: "c", Score: 12}, {ID: "b", Score: 6}, {ID: "a", Score: 0}}
+
+	fused := fuseDBSF(dense, sparse)
+	scores := make(map[string]float64)
+	for _, result := range fused {
+		scores[result.ID] = result.Score
+	}
+	// Both lists are symmetric around their mean, so every result sums to one
+	for id, score := range scores {
+		if math.Abs(score-1) > 1e-9 {
+			t.Errorf("%s scored %v, want 1", id, score)
+		}
+	}
+	if got := resultIDs(fused); got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("ties should order by ID, got %v", got)
+	}
+
+	// A list of equal scores normalises to one each
+	single := fuseDBSF([]QueryResult{{ID: "x", Score: 4}, {ID: "y", Score: 4}}, nil)
+	if len(single) != 2 || single[0].Score != 1 || single[1].Score != 1 {
+		t.Errorf("expected equal scores to normalise to 1, got %+v", single)
+	}
+}
+
+func TestHybridScores(t *testing.T) {
+	s := &LocalStore{similarity: similarityCosine}
+	ns := &localNamespace{records: map[string]Record{
+		"dense":  {ID: "dense", Vector: []float32{1, 0}, Metadata: map[string]interface{}{"kind": "a"}},
+		"sparse": {ID: "sparse", Vector: []float32{0, 1}, SparseVector: &SparseVector{Indices: []int32{7}, Values: []float32{1}}, Metadata: map[string]interface{}{"kind": "b"}},
+		"none":   {ID: "none", Vector: []float32{0, 1}, SparseVector: &SparseVector{Indices: []int32{8}, Values: []float32{1}}, Metadata: map[string]interface{}{"kind": "a"}},
+	}}
+	query := &SparseVector{Indices: []int32{7}, Values: []float32{1}}
+
+	results := s.hybridScores(ns, []float32{1, 0}, query, matchAll{}, FusionRRF, SparseWeightingIDF)
+	if got := resultIDs(results); len(got) != 3 || got[0] != "sparse" && got[0] != "dense" {
+		t.Fatalf("expected the dense and sparse matches first, got %v", got)
+	}
+	if results[2].ID != "none" {
+		t.Errorf("expected the record matching neither half last, got %v", resultIDs(results))
+	}
+
+	filter, err := ParseFilter("kind = 'a'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range s.hybridScores(ns, []float32{1, 0}, query, filter, FusionDBSF, SparseWeightingNone) {
+		if result.ID == "sparse" {
+			t.Errorf("filtered record %q was scored", result.ID)
+		}
+	}
+}
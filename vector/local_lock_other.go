@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package vector
+
+import "os"
+
+// Platforms without file locking fall back to a single process per journal
+func lockFile(f *os.File, exclusive bool) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,715 @@
+package vector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/logger"
+)
+
+const defaultLocalDimension = 256
+
+// LocalStore is an embedded, file-backed VectorStore for offline use. Every
+// mutation is appended to a JSON-lines journal that is replayed on open, so
+// ingestion and a running MCP server can share one file. A lock file next to
+// the journal serializes processes: operations hold it shared while reading
+// and exclusively while appending or compacting, and pick up changes other
+// processes made before each operation.
+type LocalStore struct {
+	mu         sync.Mutex
+	path       string
+	lock       *os.File // <path>.lock, flocked around every operation
+	dimension  int
+	similarity similarity
+	embedder   Embedder
+	sparse     bool // hybrid queries over stored BM25 sparse vectors
+	namespaces map[string]*localNamespace
+	offset     int64       // journal bytes applied so far
+	journal    os.FileInfo // journal as of the last refresh or append
+	generation uint64      // compactions so far, kept in the lock file
+	entries    int         // journal entries applied, used to decide on compaction
+}
+
+var _ VectorStore = (*LocalStore)(nil)
+
+type localNamespace struct {
+	records map[string]Record
+	ann     *annIndex // built lazily, dropped on every change
+}
+
+// journalEntry is one line of the journal. The first line is a header
+// recording the index settings.
+type journalEntry struct {
+	Op         string   `json:"op"` // header, upsert, delete or drop
+	Namespace  string   `json:"ns,omitempty"`
+	Records    []Record `json:"records,omitempty"`
+	IDs        []string `json:"ids,omitempty"`
+	Dimension  int      `json:"dimension,omitempty"`
+	Similarity string   `json:"similarity,omitempty"`
+}
+
+// NewLocalStore opens or creates the journal described by cfg. A nil
+// embedder selects the hashing embedder with the configured dimension.
+func NewLocalStore(cfg config.LocalStoreConfig, embedder Embedder) (*LocalStore, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if embedder == nil {
+		embedder = NewHashEmbedder(cfg.Dimension)
+	}
+	dimension, err := embedderDimension(context.Background(), embedder)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Dimension != 0 && cfg.Dimension != dimension {
+		return nil, fmt.Errorf("local store dimension %d does not match the embedder's %d", cfg.Dimension, dimension)
+	}
+
+	s := &LocalStore{
+		path:       cfg.StorePath(),
+		dimension:  dimension,
+		similarity: parseSimilarity(cfg.Similarity),
+		embedder:   embedder,
+		namespaces: make(map[string]*localNamespace),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create local store directory: %w", err)
+	}
+	lock, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local store lock: %w", err)
+	}
+	s.lock = lock
+
+	release, err := s.acquire(true)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	defer release()
+
+	if s.offset == 0 {
+		header := journalEntry{Op: "header", Dimension: s.dimension, Similarity: string(s.similarity)}
+		if err := s.append(header); err != nil {
+			lock.Close()
+			return nil, err
+		}
+		s.apply(header)
+	}
+
+	// Rewrite journals dominated by superseded entries
+	if s.entries > 2*(s.vectorCount()/500+len(s.namespaces))+100 {
+		if err := s.compact(); err != nil {
+			logger.LogWarning(fmt.Sprintf("Failed to compact local store %s: %v", s.path, err))
+		}
+	}
+
+	logger.LogSuccess(fmt.Sprintf("Local vector store opened at %s (%d vectors, %s)", s.path, s.vectorCount(), s.similarity))
+	return s, nil
+}
+
+// SetSparseVectors makes text queries hybrid: dense similarity fused with
+// BM25 scores over the sparse vectors stored at ingestion
+func (s *LocalStore) SetSparseVectors(enabled bool) {
+	s.sparse = enabled
+}
+
+func (s *LocalStore) UpsertBatch(ctx context.Context, documents []Document, namespace string) error {
+	// Embed before locking, so slow embedding providers do not block readers
+	texts := make([]string, len(documents))
+	for i, doc := range documents {
+		texts[i] = doc.Content
+	}
+	vectors, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed batch for namespace '%s': %w", namespace, err)
+	}
+
+	records := make([]Record, len(documents))
+	for i, doc := range documents {
+		metadata := make(map[string]interface{}, len(doc.Metadata))
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		records[i] = Record{
+			ID:           doc.ID,
+			Vector:       vectors[i],
+			SparseVector: doc.SparseVector,
+			Metadata:     metadata,
+			Data:         doc.Content,
+		}
+	}
+
+	release, err := s.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	entry := journalEntry{Op: "upsert", Namespace: namespace, Records: records}
+	if err := s.append(entry); err != nil {
+		return err
+	}
+	s.apply(entry)
+	return nil
+}
+
+func (s *LocalStore) Query(ctx context.Context, namespace string, req QueryRequest) ([]QueryResult, error) {
+	release, err := s.acquire(false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	queryVector := req.Vector
+	if len(queryVector) == 0 {
+		var err error
+		if queryVector, err = embedOne(ctx, s.embedder, req.Data); err != nil {
+			return nil, fmt.Errorf("query namespace '%s': failed to embed query: %w", namespace, err)
+		}
+	}
+	if len(queryVector) != s.dimension {
+		return nil, fmt.Errorf("query namespace '%s': vector dimension %d does not match index dimension %d", namespace, len(queryVector), s.dimension)
+	}
+	filter, err := ParseFilter(req.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("query namespace '%s': %w", namespace, err)
+	}
+	fusion, weighting, err := hybridOptions(req)
+	if err != nil {
+		return nil, fmt.Errorf("query namespace '%s': %w", namespace, err)
+	}
+	topK := req.TopK
+	if topK <= 0 {
+		topK = 10
+	}
+
+	ns := s.namespaces[namespace]
+	if ns == nil {
+		return []QueryResult{}, nil
+	}
+
+	var results []QueryResult
+	if s.sparse && req.Data != "" {
+		results = s.hybridScores(ns, queryVector, EncodeSparseQuery(req.Data), filter, fusion, weighting)
+	} else {
+		results = s.denseScores(ns, queryVector, filter, topK)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > topK {
+		results = results[:topK]
+	}
+	for i := range results {
+		record := ns.records[results[i].ID]
+		if req.IncludeMetadata {
+			results[i].Metadata = record.Metadata
+		}
+		if req.IncludeData {
+			results[i].Data = record.Data
+		}
+		if req.IncludeVectors {
+			results[i].Vector = record.Vector
+		}
+	}
+	return results, nil
+}
+
+// denseScores rates records by vector similarity, probing the ANN index in
+// large namespaces
+func (s *LocalStore) denseScores(ns *localNamespace, queryVector []float32, filter MetadataFilter, topK int) []QueryResult {
+	var results []QueryResult
+	if len(ns.records) >= annThreshold {
+		if ns.ann == nil {
+			ns.ann = newANNIndex(s.dimension, len(ns.records))
+			for _, id := range sortedRecordIDs(ns.records) {
+				ns.ann.add(id, ns.records[id].Vector)
+			}
+		}
+		results = s.score(ns, ns.ann.candidates(queryVector), queryVector, filter)
+	}
+	// Small namespaces, and ANN probes that found too few matches, use an exact scan
+	if len(results) < topK {
+		results = s.score(ns, sortedRecordIDs(ns.records), queryVector, filter)
+	}
+	return results
+}
+
+// score rates the given records that pass the filter
+func (s *LocalStore) score(ns *localNamespace, ids []string, queryVector []float32, filter MetadataFilter) []QueryResult {
+	results := make([]QueryResult, 0, len(ids))
+	for _, id := range ids {
+		record := ns.records[id]
+		if !filter.Match(record.Metadata) {
+			continue
+		}
+		results = append(results, QueryResult{ID: id, Score: s.similarity.score(queryVector, record.Vector)})
+	}
+	return results
+}
+
+func (s *LocalStore) Fetch(ctx context.Context, namespace string, req FetchRequest) ([]Record, error) {
+	release, err := s.acquire(false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	records := make([]Record, 0, len(req.IDs))
+	ns := s.namespaces[namespace]
+	if ns == nil {
+		return records, nil
+	}
+	for _, id := range req.IDs {
+		if record, ok := ns.records[id]; ok {
+			records = append(records, projectRecord(record, req.IncludeMetadata, req.IncludeData, req.IncludeVectors))
+		}
+	}
+	return records, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, namespace string, ids []string) (int, error) {
+	release, err := s.acquire(true)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	count := 0
+	if ns := s.namespaces[namespace]; ns != nil {
+		for _, id := range ids {
+			if _, ok := ns.records[id]; ok {
+				count++
+			}
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	entry := journalEntry{Op: "delete", Namespace: namespace, IDs: ids}
+	if err := s.append(entry); err != nil {
+		return 0, err
+	}
+	s.apply(entry)
+	return count, nil
+}
+
+func (s *LocalStore) Range(ctx context.Context, namespace string, req RangeRequest) (RangePage, error) {
+	release, err := s.acquire(false)
+	if err != nil {
+		return RangePage{}, err
+	}
+	defer release()
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	start := 0
+	if req.Cursor != "" {
+		n, err := strconv.Atoi(req.Cursor)
+		if err != nil || n < 0 {
+			return RangePage{}, fmt.Errorf("range namespace '%s': invalid cursor %q", namespace, req.Cursor)
+		}
+		start = n
+	}
+
+	ns := s.namespaces[namespace]
+	if ns == nil {
+		return RangePage{Records: []Record{}}, nil
+	}
+
+	// The cursor is an offset into the ID-ordered records
+	ids := sortedRecordIDs(ns.records)
+	if start > len(ids) {
+		start = len(ids)
+	}
+	end := start + limit
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	page := RangePage{Records: make([]Record, 0, end-start)}
+	for _, id := range ids[start:end] {
+		page.Records = append(page.Records, projectRecord(ns.records[id], req.IncludeMetadata, req.IncludeData, req.IncludeVectors))
+	}
+	if end < len(ids) {
+		page.NextCursor = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+func (s *LocalStore) ListNamespaces(ctx context.Context) ([]string, error) {
+	release, err := s.acquire(false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Like Upstash, the default namespace always exists
+	namespaces := []string{""}
+	for name := range s.namespaces {
+		if name != "" {
+			namespaces = append(namespaces, name)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+func (s *LocalStore) DeleteNamespace(ctx context.Context, namespace string) error {
+	release, err := s.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if namespace == "" {
+		return fmt.Errorf("delete namespace '': the default namespace cannot be deleted")
+	}
+	if _, ok := s.namespaces[namespace]; !ok {
+		return fmt.Errorf("delete namespace '%s': namespace not found", namespace)
+	}
+
+	entry := journalEntry{Op: "drop", Namespace: namespace}
+	if err := s.append(entry); err != nil {
+		return err
+	}
+	s.apply(entry)
+	return nil
+}
+
+func (s *LocalStore) ResetNamespace(ctx context.Context, namespace string) error {
+	release, err := s.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	entry := journalEntry{Op: "drop", Namespace: namespace}
+	if err := s.append(entry); err != nil {
+		return err
+	}
+	s.apply(entry)
+	return nil
+}
+
+func (s *LocalStore) UpsertRecords(ctx context.Context, namespace string, records []Record) error {
+	release, err := s.acquire(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, r := range records {
+		if len(r.Vector) != s.dimension {
+			return fmt.Errorf("upsert to namespace '%s': record %s has dimension %d, index has %d", namespace, r.ID, len(r.Vector), s.dimension)
+		}
+	}
+
+	entry := journalEntry{Op: "upsert", Namespace: namespace, Records: records}
+	if err := s.append(entry); err != nil {
+		return err
+	}
+	s.apply(entry)
+	return nil
+}
+
+func (s *LocalStore) Info(ctx context.Context) (IndexInfo, error) {
+	release, err := s.acquire(false)
+	if err != nil {
+		return IndexInfo{}, err
+	}
+	defer release()
+
+	info := IndexInfo{
+		VectorCount:        s.vectorCount(),
+		IndexSize:          int(s.offset),
+		Dimension:          s.dimension,
+		SimilarityFunction: string(s.similarity),
+		Namespaces:         map[string]NamespaceInfo{"": {}},
+	}
+	for name, ns := range s.namespaces {
+		info.Namespaces[name] = NamespaceInfo{VectorCount: len(ns.records)}
+	}
+	return info, nil
+}
+
+// apply updates the in-memory state with a journal entry. Entries are
+// idempotent, so replaying one twice is harmless.
+func (s *LocalStore) apply(entry journalEntry) {
+	s.entries++
+	switch entry.Op {
+	case "upsert":
+		ns := s.namespaces[entry.Namespace]
+		if ns == nil {
+			ns = &localNamespace{records: make(map[string]Record)}
+			s.namespaces[entry.Namespace] = ns
+		}
+		for _, record := range entry.Records {
+			ns.records[record.ID] = record
+		}
+		ns.ann = nil
+	case "delete":
+		if ns := s.namespaces[entry.Namespace]; ns != nil {
+			for _, id := range entry.IDs {
+				delete(ns.records, id)
+			}
+			ns.ann = nil
+			if len(ns.records) == 0 {
+				delete(s.namespaces, entry.Namespace)
+			}
+		}
+	case "drop":
+		delete(s.namespaces, entry.Namespace)
+	}
+}
+
+// acquire locks the store in this process and the journal across processes,
+// then replays changes other processes made. Mutations lock exclusively, so
+// their appends cannot interleave with another process's compaction.
+func (s *LocalStore) acquire(exclusive bool) (func(), error) {
+	s.mu.Lock()
+	if err := lockFile(s.lock, exclusive); err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to lock local store: %w", err)
+	}
+	release := func() {
+		unlockFile(s.lock)
+		s.mu.Unlock()
+	}
+	if err := s.refresh(); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// refresh replays journal entries appended since the last call. The journal
+// is reloaded from scratch when it was replaced (compacted by another
+// process), shrank, or changed without growing.
+func (s *LocalStore) refresh() error {
+	stat, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.reset()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat local store: %w", err)
+	}
+	generation, err := s.readGeneration()
+	if err != nil {
+		return err
+	}
+	if generation == s.generation && s.journal != nil && os.SameFile(stat, s.journal) &&
+		stat.Size() == s.offset && stat.ModTime().Equal(s.journal.ModTime()) {
+		return nil
+	}
+	// A new generation means another process compacted the journal; file
+	// identity alone misses this when the rewritten file reuses the inode
+	if generation != s.generation || s.journal == nil || !os.SameFile(stat, s.journal) || stat.Size() <= s.offset {
+		s.reset()
+		s.generation = generation
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to open local store: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(s.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read local store: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A partial trailing line is left by a writer that failed midway;
+			// it is retried on the next refresh
+			s.journal = stat
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read local store: %w", err)
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("corrupt local store entry at byte %d: %w", s.offset, err)
+		}
+		if entry.Op == "header" {
+			if entry.Dimension != s.dimension || parseSimilarity(entry.Similarity) != s.similarity {
+				return fmt.Errorf("local store %s was created with dimension %d and %s similarity; configured %d and %s",
+					s.path, entry.Dimension, entry.Similarity, s.dimension, s.similarity)
+			}
+		}
+		s.apply(entry)
+		s.offset += int64(len(line))
+	}
+}
+
+// reset drops the in-memory state before a full reload
+func (s *LocalStore) reset() {
+	s.namespaces = make(map[string]*localNamespace)
+	s.offset = 0
+	s.entries = 0
+	s.journal = nil
+}
+
+// append writes an entry to the end of the journal. Callers hold the
+// exclusive lock and have refreshed, so the journal ends where replay stopped
+// and the entry is applied by the caller rather than replayed.
+func (s *LocalStore) append(entry journalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode local store entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open local store: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write local store: %w", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local store: %w", err)
+	}
+	s.offset = stat.Size()
+	s.journal = stat
+	return nil
+}
+
+// compact rewrites the journal with one upsert entry per namespace page.
+// Callers hold the exclusive lock, so no process appends meanwhile.
+func (s *LocalStore) compact() error {
+	tmp := s.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	entries := []journalEntry{{Op: "header", Dimension: s.dimension, Similarity: string(s.similarity)}}
+	for name, ns := range s.namespaces {
+		ids := sortedRecordIDs(ns.records)
+		for start := 0; start < len(ids); start += 500 {
+			end := start + 500
+			if end > len(ids) {
+				end = len(ids)
+			}
+			entry := journalEntry{Op: "upsert", Namespace: name}
+			for _, id := range ids[start:end] {
+				entry.Records = append(entry.Records, ns.records[id])
+			}
+			entries = append(entries, entry)
+		}
+	}
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	if err := s.writeGeneration(s.generation + 1); err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	s.offset = stat.Size()
+	s.journal = stat
+	s.entries = len(entries)
+	logger.LogInfo(fmt.Sprintf("Compacted local store %s to %d bytes", s.path, s.offset))
+	return nil
+}
+
+// readGeneration reads the compaction count from the lock file; an empty
+// lock file is generation zero
+func (s *LocalStore) readGeneration() (uint64, error) {
+	buf := make([]byte, 20)
+	n, err := s.lock.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("failed to read local store lock: %w", err)
+	}
+	text := strings.TrimSpace(string(buf[:n]))
+	if text == "" {
+		return 0, nil
+	}
+	generation, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt local store lock %s.lock: %w", s.path, err)
+	}
+	return generation, nil
+}
+
+func (s *LocalStore) writeGeneration(generation uint64) error {
+	if _, err := s.lock.WriteAt([]byte(fmt.Sprintf("%020d", generation)), 0); err != nil {
+		return fmt.Errorf("failed to write local store lock: %w", err)
+	}
+	s.generation = generation
+	return nil
+}
+
+func (s *LocalStore) vectorCount() int {
+	count := 0
+	for _, ns := range s.namespaces {
+		count += len(ns.records)
+	}
+	return count
+}
+
+func sortedRecordIDs(records map[string]Record) []string {
+	ids := make([]string, 0, len(records))
+	for id := range records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// projectRecord copies a record with only the requested fields
+func projectRecord(record Record, includeMetadata, includeData, includeVectors bool) Record {
+	projected := Record{ID: record.ID}
+	if includeMetadata {
+		projected.Metadata = record.Metadata
+	}
+	if includeData {
+		projected.Data = record.Data
+	}
+	if includeVectors {
+		projected.Vector = record.Vector
+		projected.SparseVector = record.SparseVector
+	}
+	return projected
+}
@@ -0,0 +1,163 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/logger"
+)
+
+func openTestStore(t *testing.T, path string) *LocalStore {
+	t.Helper()
+	logger.SetOutput(io.Discard)
+	store, err := NewLocalStore(config.LocalStoreConfig{Path: path, Dimension: 32}, nil)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	t.Cleanup(func() { store.lock.Close() })
+	return store
+}
+
+func upsertTestDocuments(t *testing.T, store *LocalStore, namespace string, ids ...string) {
+	t.Helper()
+	documents := make([]Document, len(ids))
+	for i, id := range ids {
+		documents[i] = Document{ID: id, Content: "content of " + id, Metadata: map[string]interface{}{"id": id}}
+	}
+	if err := store.UpsertBatch(context.Background(), documents, namespace); err != nil {
+		t.Fatalf("UpsertBatch: %v", err)
+	}
+}
+
+func storeIDs(t *testing.T, store *LocalStore, namespace string) []string {
+	t.Helper()
+	page, err := store.Range(context.Background(), namespace, RangeRequest{Limit: 1000})
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	ids := make([]string, len(page.Records))
+	for i, record := range page.Records {
+		ids[i] = record.ID
+	}
+	return ids
+}
+
+func TestLocalStoreReplaysJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.jsonl")
+	ctx := context.Background()
+
+	store := openTestStore(t, path)
+	upsertTestDocuments(t, store, "docs", "a", "b", "c")
+	upsertTestDocuments(t, store, "notes", "n")
+	if _, err := store.Delete(ctx, "docs", []string{"b"}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.DeleteNamespace(ctx, "notes"); err != nil {
+		t.Fatalf("DeleteNamespace: %v", err)
+	}
+
+	reopened := openTestStore(t, path)
+	if got := strings.Join(storeIDs(t, reopened, "docs"), ","); got != "a,c" {
+		t.Errorf("replayed docs = %s, want a,c", got)
+	}
+	namespaces, err := reopened.ListNamespaces(ctx)
+	if err != nil {
+		t.Fatalf("ListNamespaces: %v", err)
+	}
+	if strings.Join(namespaces, ",") != ",docs" {
+		t.Errorf("replayed namespaces = %q, want the default namespace and docs", namespaces)
+	}
+}
+
+func TestLocalStoreRejectsMismatchedJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.jsonl")
+	openTestStore(t, path)
+
+	_, err := NewLocalStore(config.LocalStoreConfig{Path: path, Dimension: 64}, nil)
+	if err == nil || !strings.Contains(err.Error(), "dimension 32") {
+		t.Fatalf("expected a dimension mismatch error, got %v", err)
+	}
+}
+
+func TestLocalStoreCompactsJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.jsonl")
+	store := openTestStore(t, path)
+	for i := 0; i < 150; i++ {
+		upsertTestDocuments(t, store, "docs", "a", fmt.Sprintf("doc-%d", i%5))
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening a journal dominated by superseded entries rewrites it
+	reopened := openTestStore(t, path)
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() >= before.Size() {
+		t.Errorf("journal was not compacted: %d bytes before, %d after", before.Size(), after.Size())
+	}
+	if got := len(storeIDs(t, reopened, "docs")); got != 6 {
+		t.Errorf("compacted store holds %d records, want 6", got)
+	}
+
+	// The store that wrote the journal reloads the rewritten file
+	upsertTestDocuments(t, store, "docs", "z")
+	if got := len(storeIDs(t, reopened, "docs")); got != 7 {
+		t.Errorf("after an append to the compacted journal, store holds %d records, want 7", got)
+	}
+}
+
+func TestLocalStoreSharedBetweenStores(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.jsonl")
+	writer := openTestStore(t, path)
+	reader := openTestStore(t, path)
+	ctx := context.Background()
+
+	upsertTestDocuments(t, writer, "docs", "a", "b")
+	if got := strings.Join(storeIDs(t, reader, "docs"), ","); got != "a,b" {
+		t.Fatalf("reader sees %s, want a,b", got)
+	}
+
+	// Deletions are picked up as well as additions
+	if err := writer.ResetNamespace(ctx, "docs"); err != nil {
+		t.Fatalf("ResetNamespace: %v", err)
+	}
+	if got := storeIDs(t, reader, "docs"); len(got) != 0 {
+		t.Fatalf("reader still sees %v after a reset", got)
+	}
+
+	// Another store compacting while this one appends loses no entries
+	var wg sync.WaitGroup
+	for w, store := range []*LocalStore{writer, reader} {
+		wg.Add(1)
+		go func(w int, store *LocalStore) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				upsertTestDocuments(t, store, "docs", fmt.Sprintf("w%d-%d", w, i))
+				if i%5 == 0 {
+					if release, err := store.acquire(true); err == nil {
+						store.compact()
+						release()
+					}
+				}
+			}
+		}(w, store)
+	}
+	wg.Wait()
+
+	for _, store := range []*LocalStore{writer, reader, openTestStore(t, path)} {
+		if got := len(storeIDs(t, store, "docs")); got != 40 {
+			t.Errorf("store holds %d records after concurrent writes, want 40", got)
+		}
+	}
+}
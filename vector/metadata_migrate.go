@@ -0,0 +1,75 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/typicalfo/prj-start/document"
+)
+
+// NormalizeNamespaceMetadata pages through a namespace and rewrites the
+// metadata of every record that does not match the schema, e.g. numbers
+// stored as strings by earlier versions. Records are upserted with their
+// existing vectors, so nothing is re-embedded. With dryRun set nothing is
+// written. progress, if set, is called with the running totals after each
+// page. It returns the number of records scanned and updated.
+func NormalizeNamespaceMetadata(ctx context.Context, store VectorStore, namespace string, schema document.Schema, pageSize int, dryRun bool, progress func(scanned, updated int)) (int, int, error) {
+	scanned, updated := 0, 0
+	cursor := ""
+	for {
+		page, err := store.Range(ctx, namespace, RangeRequest{
+			Cursor:          cursor,
+			Limit:           pageSize,
+			IncludeMetadata: true,
+			IncludeData:     true,
+			IncludeVectors:  true,
+		})
+		if err != nil {
+			return scanned, updated, err
+		}
+
+		var changed []Record
+		for _, record := range page.Records {
+			metadata, err := schema.Normalize(document.Metadata(record.Metadata))
+			if err != nil {
+				return scanned, updated, fmt.Errorf("record %s: %w", record.ID, err)
+			}
+			if !sameJSON(record.Metadata, metadata) {
+				record.Metadata = metadata
+				changed = append(changed, record)
+			}
+		}
+		scanned += len(page.Records)
+
+		if len(changed) > 0 && !dryRun {
+			if err := store.UpsertRecords(ctx, namespace, changed); err != nil {
+				return scanned, updated, fmt.Errorf("failed after updating %d records: %w", updated, err)
+			}
+		}
+		updated += len(changed)
+		if progress != nil {
+			progress(scanned, updated)
+		}
+
+		if page.NextCursor == "" {
+			return scanned, updated, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// sameJSON reports whether two metadata maps serialize identically, so a
+// float64 decoded from JSON equals the int it was written as
+func sameJSON(a map[string]interface{}, b document.Metadata) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
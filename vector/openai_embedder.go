@@ -0,0 +1,162 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/typicalfo/prj-start/config"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint. Besides
+// OpenAI itself this covers local servers such as Ollama, LM Studio,
+// llama.cpp and vLLM.
+type OpenAIEmbedder struct {
+	baseURL string
+	apiKey  string
+	model   string
+	// requestedDimension is sent to models that support shortened
+	// embeddings; dimension is learned from the first response otherwise
+	requestedDimension int
+	mu                 sync.Mutex
+	dimension          int
+	batchSize          int
+	httpClient         *http.Client
+}
+
+var _ Embedder = (*OpenAIEmbedder)(nil)
+
+func NewOpenAIEmbedder(cfg config.EmbeddingConfig) *OpenAIEmbedder {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 64
+	}
+	return &OpenAIEmbedder{
+		baseURL:            baseURL,
+		apiKey:             cfg.APIKey,
+		model:              cfg.Model,
+		requestedDimension: cfg.Dimension,
+		dimension:          cfg.Dimension,
+		batchSize:          batchSize,
+		httpClient:         &http.Client{Timeout: cfg.TimeoutDuration()},
+	}
+}
+
+type embeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += e.batchSize {
+		end := start + e.batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{
+		Model:      e.model,
+		Input:      texts,
+		Dimensions: e.requestedDimension,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request to %s failed: %w", e.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+
+	var parsed embeddingResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, truncate(string(data), 512))
+		}
+		return nil, fmt.Errorf("invalid embedding response: %w", err)
+	}
+	if parsed.Error != nil {
+		return nil, fmt.Errorf("embedding request failed: %s", parsed.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed with status %d", resp.StatusCode)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response has %d vectors for %d inputs", len(parsed.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response has out-of-range index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+
+	e.mu.Lock()
+	if e.dimension == 0 && len(vectors) > 0 {
+		e.dimension = len(vectors[0])
+	}
+	e.mu.Unlock()
+	return vectors, nil
+}
+
+func (e *OpenAIEmbedder) Dimension() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dimension
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
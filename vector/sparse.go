@@ -0,0 +1,189 @@
+package vector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SparseVector holds the non-zero dimensions of a sparse vector
+type SparseVector struct {
+	Indices []int32   `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+// Fusion algorithms and sparse weighting strategies accepted by QueryRequest.
+// They mirror Upstash's hybrid query options.
+const (
+	FusionRRF  = "RRF"
+	FusionDBSF = "DBSF"
+
+	SparseWeightingIDF  = "IDF"
+	SparseWeightingNone = "NONE"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// BM25Encoder builds BM25 sparse vectors. Document vectors carry the
+// term-frequency part of BM25, normalised by the corpus's average document
+// length; the IDF part is applied at query time (Upstash's IDF weighting
+// strategy), so indexes never need re-encoding as the corpus grows.
+// Terms map to dimensions by hash, which keeps the vocabulary implicit and
+// stable between ingestion and queries.
+type BM25Encoder struct {
+	avgDocLength float64
+}
+
+// NewBM25Encoder computes corpus statistics over the texts that will be
+// encoded; CorpusStats.Encoder uses statistics kept between runs instead
+func NewBM25Encoder(texts []string) *BM25Encoder {
+	total := 0
+	for _, text := range texts {
+		total += len(SparseTokens(text))
+	}
+	avg := 1.0
+	if len(texts) > 0 && total > 0 {
+		avg = float64(total) / float64(len(texts))
+	}
+	return &BM25Encoder{avgDocLength: avg}
+}
+
+// EncodeDocument returns the BM25 term-frequency vector of a document
+func (e *BM25Encoder) EncodeDocument(text string) *SparseVector {
+	tokens := SparseTokens(text)
+	counts := termCounts(tokens)
+	norm := bm25K1 * (1 - bm25B + bm25B*float64(len(tokens))/e.avgDocLength)
+
+	weights := make(map[int32]float64, len(counts))
+	for term, tf := range counts {
+		weights[termIndex(term)] += float64(tf) * (bm25K1 + 1) / (float64(tf) + norm)
+	}
+	return newSparseVector(weights)
+}
+
+// bm25IDF is the inverse document frequency of a term found in docFreq of
+// documents chunks, as Upstash's IDF weighting applies it at query time
+func bm25IDF(documents, docFreq int) float64 {
+	return math.Log((float64(documents-docFreq)+0.5)/(float64(docFreq)+0.5) + 1)
+}
+
+// EncodeSparseQuery returns the query vector: weight one for every distinct term
+func EncodeSparseQuery(text string) *SparseVector {
+	weights := make(map[int32]float64)
+	for term := range termCounts(SparseTokens(text)) {
+		weights[termIndex(term)] = 1
+	}
+	return newSparseVector(weights)
+}
+
+// SparseTokens tokenizes text for lexical matching. Identifiers are kept
+// whole and also split on dots, underscores and camelCase boundaries, so
+// "fiber.Config" yields fiber.config, fiber and config, and "UpsertDataMany"
+// yields upsertdatamany, upsert, data and many.
+func SparseTokens(text string) []string {
+	raw := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.'
+	})
+
+	var tokens []string
+	emit := func(token string) {
+		if len(token) >= 2 {
+			tokens = append(tokens, strings.ToLower(token))
+		}
+	}
+	for _, word := range raw {
+		word = strings.Trim(word, "._")
+		if word == "" {
+			continue
+		}
+		emit(word)
+
+		parts := strings.FieldsFunc(word, func(r rune) bool { return r == '.' || r == '_' })
+		for _, part := range parts {
+			if len(parts) > 1 {
+				emit(part)
+			}
+			if sub := splitCamelCase(part); len(sub) > 1 {
+				for _, s := range sub {
+					emit(s)
+				}
+			}
+		}
+	}
+	return tokens
+}
+
+// splitCamelCase splits "UpsertDataMany" into Upsert, Data, Many and
+// "HTTPServer" into HTTP, Server
+func splitCamelCase(word string) []string {
+	runes := []rune(word)
+	var parts []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		boundary := (unicode.IsLower(prev) && unicode.IsUpper(cur)) ||
+			(unicode.IsLetter(prev) != unicode.IsLetter(cur)) ||
+			(unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))
+		if boundary {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
+
+func termCounts(tokens []string) map[string]int {
+	counts := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		counts[token]++
+	}
+	return counts
+}
+
+// termIndex maps a term to a non-negative sparse dimension
+func termIndex(term string) int32 {
+	h := fnv.New32a()
+	h.Write([]byte(term))
+	return int32(h.Sum32() & math.MaxInt32)
+}
+
+func newSparseVector(weights map[int32]float64) *SparseVector {
+	v := &SparseVector{
+		Indices: make([]int32, 0, len(weights)),
+		Values:  make([]float32, 0, len(weights)),
+	}
+	for index := range weights {
+		v.Indices = append(v.Indices, index)
+	}
+	sort.Slice(v.Indices, func(i, j int) bool { return v.Indices[i] < v.Indices[j] })
+	for _, index := range v.Indices {
+		v.Values = append(v.Values, float32(weights[index]))
+	}
+	return v
+}
+
+// hybridOptions validates and upper-cases the fusion and sparse weighting of
+// a query. Empty values are left for the store to default.
+func hybridOptions(req QueryRequest) (fusion, weighting string, err error) {
+	fusion = strings.ToUpper(req.Fusion)
+	switch fusion {
+	case "", FusionRRF, FusionDBSF:
+	default:
+		return "", "", fmt.Errorf("unknown fusion algorithm %q (use RRF or DBSF)", req.Fusion)
+	}
+
+	weighting = strings.ToUpper(req.SparseWeighting)
+	switch weighting {
+	case "", SparseWeightingIDF, SparseWeightingNone:
+	default:
+		return "", "", fmt.Errorf("unknown sparse weighting %q (use IDF or NONE)", req.SparseWeighting)
+	}
+	return fusion, weighting, nil
+}
@@ -0,0 +1,248 @@
+package vector
+
+import (
+	"context"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/document"
+	"github.com/typicalfo/prj-start/logger"
+)
+
+func TestSparseTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"fiber.Config", "fiber.config fiber config"},
+		{"UpsertDataMany", "upsertdatamany upsert data many"},
+		{"HTTPServer", "httpserver http server"},
+		{"max_batch_size", "max_batch_size max batch size"},
+		{"a b v2 go", "v2 go"},
+		{"(config.Port)", "config.port config port"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(SparseTokens(tt.text), " "); got != tt.want {
+			t.Errorf("SparseTokens(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// sparseWeight returns the weight of a term in v, or zero
+func sparseWeight(v *SparseVector, term string) float64 {
+	value, _ := sparseValue(v, termIndex(term))
+	return float64(value)
+}
+
+func TestBM25EncoderDocument(t *testing.T) {
+	short := "router middleware"
+	long := "router middleware handler context request response logger config"
+	encoder := NewBM25Encoder([]string{short, long})
+	if encoder.avgDocLength != 5 {
+		t.Fatalf("average length = %v, want 5", encoder.avgDocLength)
+	}
+
+	s, l := encoder.EncodeDocument(short), encoder.EncodeDocument(long)
+	if !sort.SliceIsSorted(l.Indices, func(i, j int) bool { return l.Indices[i] < l.Indices[j] }) {
+		t.Errorf("indices are not sorted: %v", l.Indices)
+	}
+	if len(l.Indices) != 8 || len(l.Values) != 8 {
+		t.Errorf("expected 8 dimensions, got %d", len(l.Indices))
+	}
+
+	// A term weighs more in a shorter document
+	if sparseWeight(s, "router") <= sparseWeight(l, "router") {
+		t.Errorf("router weighs %v in the short and %v in the long document", sparseWeight(s, "router"), sparseWeight(l, "router"))
+	}
+
+	// Repeated terms saturate below k1 + 1
+	repeated := encoder.EncodeDocument(strings.Repeat("router ", 50))
+	if w := sparseWeight(repeated, "router"); w <= sparseWeight(s, "router") || w >= bm25K1+1 {
+		t.Errorf("50 repetitions weigh %v, want between one occurrence and %v", w, bm25K1+1)
+	}
+
+	// Exact BM25 term frequency for one occurrence at average length
+	want := (bm25K1 + 1) / (1 + bm25K1)
+	avg := encoder.EncodeDocument("alpha beta gamma delta epsilon")
+	if w := sparseWeight(avg, "alpha"); math.Abs(w-want) > 1e-6 {
+		t.Errorf("weight at average length = %v, want %v", w, want)
+	}
+}
+
+func TestEncodeSparseQuery(t *testing.T) {
+	v := EncodeSparseQuery("Config config fiber.Config")
+	if len(v.Indices) != 3 {
+		t.Fatalf("expected config, fiber.config and fiber, got %d dimensions", len(v.Indices))
+	}
+	for i, value := range v.Values {
+		if value != 1 {
+			t.Errorf("dimension %d weighs %v, want 1", v.Indices[i], value)
+		}
+	}
+	for _, term := range []string{"config", "fiber", "fiber.config"} {
+		if _, ok := sparseValue(v, termIndex(term)); !ok {
+			t.Errorf("query lacks %q", term)
+		}
+	}
+
+	// Queries and documents map terms to the same dimensions
+	doc := NewBM25Encoder(nil).EncodeDocument("fiber.Config")
+	if len(doc.Indices) != len(v.Indices) {
+		t.Fatalf("document has %d dimensions, query %d", len(doc.Indices), len(v.Indices))
+	}
+	for i := range doc.Indices {
+		if doc.Indices[i] != v.Indices[i] {
+			t.Errorf("document and query dimensions differ: %v vs %v", doc.Indices, v.Indices)
+		}
+	}
+
+	if empty := EncodeSparseQuery("a ! ?"); len(empty.Indices) != 0 {
+		t.Errorf("expected no dimensions for a query without terms, got %v", empty.Indices)
+	}
+}
+
+func TestBM25IDF(t *testing.T) {
+	if rare, common := bm25IDF(100, 1), bm25IDF(100, 90); rare <= common || common <= 0 {
+		t.Errorf("idf of a rare term %v should exceed that of a common term %v, both positive", rare, common)
+	}
+	if got, want := bm25IDF(10, 0), math.Log(10.5/0.5+1); math.Abs(got-want) > 1e-12 {
+		t.Errorf("bm25IDF(10, 0) = %v, want %v", got, want)
+	}
+}
+
+func TestCorpusStats(t *testing.T) {
+	stats := NewCorpusStats()
+	stats.Add("a", "router middleware")
+	stats.Add("b", "router handler context logger")
+	if stats.DocumentCount() != 2 || stats.AvgDocLength() != 3 {
+		t.Fatalf("got %d documents of average length %v, want 2 and 3", stats.DocumentCount(), stats.AvgDocLength())
+	}
+	if n := stats.DocFreq(termIndex("router")); n != 2 {
+		t.Errorf("router is in %d documents, want 2", n)
+	}
+
+	// Re-adding a document replaces it
+	stats.Add("b", "handler")
+	if stats.DocumentCount() != 2 || stats.AvgDocLength() != 1.5 {
+		t.Errorf("after replacing b: %d documents of average length %v, want 2 and 1.5", stats.DocumentCount(), stats.AvgDocLength())
+	}
+	if n := stats.DocFreq(termIndex("router")); n != 1 {
+		t.Errorf("router is in %d documents after replacing b, want 1", n)
+	}
+
+	stats.Remove("a", "missing")
+	if stats.DocumentCount() != 1 || stats.DocFreq(termIndex("router")) != 0 {
+		t.Errorf("after removing a: %d documents, router in %d", stats.DocumentCount(), stats.DocFreq(termIndex("router")))
+	}
+	if encoder := stats.Encoder(); encoder.avgDocLength != 1 {
+		t.Errorf("encoder average length = %v, want 1", encoder.avgDocLength)
+	}
+}
+
+func TestCorpusStatsFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats", "bm25.json")
+	file, err := LoadCorpusStats(path)
+	if err != nil {
+		t.Fatalf("LoadCorpusStats of a missing file: %v", err)
+	}
+	file.Namespace("docs").Add("a", "router middleware handler")
+	file.Namespace("notes").Add("n", "deploy")
+	if err := file.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadCorpusStats(path)
+	if err != nil {
+		t.Fatalf("LoadCorpusStats: %v", err)
+	}
+	docs := loaded.Namespace("docs")
+	if docs.DocumentCount() != 1 || docs.AvgDocLength() != 3 || docs.DocFreq(termIndex("middleware")) != 1 {
+		t.Errorf("loaded docs stats: %d documents, average %v", docs.DocumentCount(), docs.AvgDocLength())
+	}
+	if loaded.Namespace("notes").DocumentCount() != 1 {
+		t.Error("notes statistics were not saved")
+	}
+}
+
+func TestUpserterKeepsCorpusStatsBetweenRuns(t *testing.T) {
+	logger.SetOutput(io.Discard)
+	dir := t.TempDir()
+	store, err := NewLocalStore(config.LocalStoreConfig{Path: filepath.Join(dir, "vectors.jsonl"), Dimension: 32}, nil)
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	t.Cleanup(func() { store.lock.Close() })
+	statsPath := filepath.Join(dir, "bm25.json")
+	ctx := context.Background()
+
+	ingest := func(relPath, content string) {
+		t.Helper()
+		stats, err := LoadCorpusStats(statsPath)
+		if err != nil {
+			t.Fatalf("LoadCorpusStats: %v", err)
+		}
+		upserter := NewUpserter(store, 10)
+		upserter.SetSparseVectors(true)
+		upserter.SetCorpusStats(stats)
+		doc := document.FileInfo{Path: relPath, RelativePath: relPath, Extension: ".md", Content: content, Size: int64(len(content))}
+		if err := upserter.UpsertAllDocuments(ctx, []document.FileInfo{doc}); err != nil {
+			t.Fatalf("UpsertAllDocuments: %v", err)
+		}
+	}
+
+	ingest("guides/short.md", "router")
+	ingest("guides/long.md", "router middleware handler context request response logger config")
+
+	stats, err := LoadCorpusStats(statsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guides := stats.Namespace("guides")
+	if guides.DocumentCount() != 2 || guides.DocFreq(termIndex("router")) != 2 {
+		t.Fatalf("expected both runs in the statistics, got %d documents", guides.DocumentCount())
+	}
+
+	// The second run's vector is normalised over both files, not itself alone
+	records, err := store.Fetch(ctx, "guides", FetchRequest{IDs: []string{DocumentID("guides/long.md", 0)}, IncludeData: true, IncludeVectors: true})
+	if err != nil || len(records) != 1 || records[0].SparseVector == nil {
+		t.Fatalf("Fetch: %v, %+v", err, records)
+	}
+	want := guides.Encoder().EncodeDocument(records[0].Data)
+	alone := NewBM25Encoder([]string{records[0].Data}).EncodeDocument(records[0].Data)
+	got := sparseWeight(records[0].SparseVector, "router")
+	if math.Abs(got-sparseWeight(want, "router")) > 1e-6 || math.Abs(got-sparseWeight(alone, "router")) < 1e-6 {
+		t.Errorf("router weighs %v; over both files %v, over the run alone %v", got, sparseWeight(want, "router"), sparseWeight(alone, "router"))
+	}
+
+	// Removing a file drops it from the statistics
+	reloaded, _ := LoadCorpusStats(statsPath)
+	upserter := NewUpserter(store, 10)
+	upserter.SetCorpusStats(reloaded)
+	if _, err := upserter.RemoveSource(ctx, "guides/short.md"); err != nil {
+		t.Fatalf("RemoveSource: %v", err)
+	}
+	after, _ := LoadCorpusStats(statsPath)
+	if n := after.Namespace("guides").DocumentCount(); n != 1 {
+		t.Errorf("after removing short.md the statistics hold %d documents, want 1", n)
+	}
+}
+
+func TestHybridOptionsOnlySentWithSparse(t *testing.T) {
+	if got := sdkFusion(false, FusionDBSF); got != "" {
+		t.Errorf("dense query sends fusion %q", got)
+	}
+	if got := sdkWeighting(false, SparseWeightingIDF, SparseWeightingIDF); got != "" {
+		t.Errorf("dense query sends weighting %q", got)
+	}
+	if got := sdkFusion(true, FusionDBSF); string(got) != FusionDBSF {
+		t.Errorf("hybrid query sends fusion %q, want %s", got, FusionDBSF)
+	}
+	if got := sdkWeighting(true, "", SparseWeightingIDF); string(got) != SparseWeightingIDF {
+		t.Errorf("hybrid query sends weighting %q, want the IDF default", got)
+	}
+}
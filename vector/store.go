@@ -0,0 +1,138 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/typicalfo/prj-start/config"
+)
+
+// VectorStore is the storage backend used by the ingest pipeline and the MCP
+// tools. Client is the Upstash implementation and LocalStore the offline one; the empty namespace is the
+// index's default namespace.
+type VectorStore interface {
+	// UpsertBatch stores documents, letting the backend embed their content
+	UpsertBatch(ctx context.Context, documents []Document, namespace string) error
+	// UpsertRecords stores records with their existing vectors, e.g. when
+	// copying between namespaces or restoring a backup
+	UpsertRecords(ctx context.Context, namespace string, records []Record) error
+	// Query searches by QueryRequest.Data (text) or QueryRequest.Vector
+	Query(ctx context.Context, namespace string, req QueryRequest) ([]QueryResult, error)
+	Fetch(ctx context.Context, namespace string, req FetchRequest) ([]Record, error)
+	Delete(ctx context.Context, namespace string, ids []string) (int, error)
+	Range(ctx context.Context, namespace string, req RangeRequest) (RangePage, error)
+	ListNamespaces(ctx context.Context) ([]string, error)
+	DeleteNamespace(ctx context.Context, namespace string) error
+	// ResetNamespace deletes every vector but keeps the namespace; it also
+	// works on the default namespace, which cannot be deleted
+	ResetNamespace(ctx context.Context, namespace string) error
+	Info(ctx context.Context) (IndexInfo, error)
+}
+
+// OpenStore returns the backend selected by cfg.Store: the embedded local
+// store or, by default, Upstash. A configured embedder is attached to either.
+func OpenStore(cfg *config.Config) (VectorStore, error) {
+	embedder, err := NewEmbedder(cfg.Embedding)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.UsesLocalStore() {
+		store, err := NewLocalStore(cfg.Local, embedder)
+		if err != nil {
+			return nil, err
+		}
+		store.SetSparseVectors(cfg.SparseVectors)
+		return store, nil
+	}
+
+	// Upserts carrying sparse vectors must also carry the dense vector
+	if cfg.SparseVectors && embedder == nil {
+		return nil, fmt.Errorf("sparse_vectors on Upstash requires an embedding provider for the dense vectors")
+	}
+	client, err := NewClient(&cfg.Upstash)
+	if err != nil {
+		return nil, err
+	}
+	if embedder != nil {
+		client.SetEmbedder(embedder)
+	}
+	client.SetSparseVectors(cfg.SparseVectors)
+	client.SetBatchLimits(BatchLimitsFromConfig(cfg))
+	return client, nil
+}
+
+// BatchLimitsFromConfig returns the configured upsert batch limits, with
+// batch_size as the ceiling on documents per request
+func BatchLimitsFromConfig(cfg *config.Config) BatchLimits {
+	return BatchLimits{
+		MaxCount:  cfg.BatchSize,
+		MaxBytes:  cfg.BatchMaxBytes,
+		MaxTokens: cfg.BatchMaxTokens,
+	}
+}
+
+// QueryRequest describes a similarity query. When Vector is empty the
+// backend embeds Data itself.
+type QueryRequest struct {
+	Data            string
+	Vector          []float32
+	TopK            int
+	Filter          string
+	IncludeMetadata bool
+	IncludeData     bool
+	IncludeVectors  bool
+
+	// Hybrid search options, used when the store has sparse vectors enabled
+	// or the index embeds both parts itself: RRF or DBSF, and IDF or NONE
+	Fusion          string
+	SparseWeighting string
+}
+
+// FetchRequest retrieves records by ID
+type FetchRequest struct {
+	IDs             []string
+	IncludeMetadata bool
+	IncludeData     bool
+	IncludeVectors  bool
+}
+
+// RangeRequest pages through all records of a namespace. An empty Cursor
+// starts from the beginning.
+type RangeRequest struct {
+	Cursor          string
+	Limit           int
+	IncludeMetadata bool
+	IncludeData     bool
+	IncludeVectors  bool
+}
+
+// RangePage is one page of a range scan; NextCursor is empty on the last page
+type RangePage struct {
+	NextCursor string
+	Records    []Record
+}
+
+// Record is a stored vector with its data and metadata
+type Record struct {
+	ID           string                 `json:"id"`
+	Vector       []float32              `json:"vector,omitempty"`
+	SparseVector *SparseVector          `json:"sparseVector,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Data         string                 `json:"data,omitempty"`
+}
+
+// IndexInfo summarizes the index and its namespaces
+type IndexInfo struct {
+	VectorCount        int                      `json:"vectorCount"`
+	PendingVectorCount int                      `json:"pendingVectorCount"`
+	IndexSize          int                      `json:"indexSize"`
+	Dimension          int                      `json:"dimension"`
+	SimilarityFunction string                   `json:"similarityFunction"`
+	Namespaces         map[string]NamespaceInfo `json:"namespaces"`
+}
+
+// NamespaceInfo holds per-namespace vector counts
+type NamespaceInfo struct {
+	VectorCount        int `json:"vectorCount"`
+	PendingVectorCount int `json:"pendingVectorCount"`
+}
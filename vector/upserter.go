@@ -11,17 +11,80 @@ import (
 )
 
 type Upserter struct {
-	client    *Client
-	batchSize int
+	client         VectorStore
+	limits         BatchLimits
+	chunker        *document.Chunker
+	contextHeaders bool
+	parentLevel    ParentLevel
+	sparseVectors  bool
+	schema         document.Schema
+	progress       func(processed, total int)
+	written        map[string]map[string]bool // source_file to the IDs upserted, for RemoveStale
+	corpus         *CorpusStatsFile           // BM25 statistics kept between runs, optional
 }
 
-func NewUpserter(client *Client, batchSize int) *Upserter {
-	if batchSize <= 0 {
-		batchSize = 10 // default batch size
-	}
+// NewUpserter creates an upserter whose batches hold at most batchSize
+// documents, within the default byte and token budgets
+func NewUpserter(client VectorStore, batchSize int) *Upserter {
 	return &Upserter{
-		client:    client,
-		batchSize: batchSize,
+		client:  client,
+		limits:  BatchLimits{MaxCount: batchSize},
+		chunker: document.NewChunker(1000),
+		schema:  document.DefaultSchema,
+	}
+}
+
+// SetMetadataSchema replaces the schema metadata is normalized against
+// before upserting
+func (u *Upserter) SetMetadataSchema(schema document.Schema) {
+	if schema != nil {
+		u.schema = schema
+	}
+}
+
+// SetBatchLimits replaces the batch count ceiling and byte and token budgets
+func (u *Upserter) SetBatchLimits(limits BatchLimits) {
+	u.limits = limits
+}
+
+// SetContextHeaders enables embedding a synthesized header (file path, recipe,
+// heading or symbol) in front of each chunk. The header length is recorded in
+// metadata so StripContextHeader can return the original content.
+func (u *Upserter) SetContextHeaders(enabled bool) {
+	u.contextHeaders = enabled
+}
+
+// SetParentLevel enables parent records: a file outline for ParentsFile, plus
+// section records for ParentsSection. Chunks reference them via parent_id.
+func (u *Upserter) SetParentLevel(level ParentLevel) {
+	u.parentLevel = level
+}
+
+// SetSparseVectors enables BM25 sparse vectors for hybrid indexes, normalised
+// over the documents of each namespace in an upsert run unless corpus
+// statistics are kept with SetCorpusStats
+func (u *Upserter) SetSparseVectors(enabled bool) {
+	u.sparseVectors = enabled
+}
+
+// SetCorpusStats keeps the BM25 statistics of each namespace in stats, so
+// sparse vectors are normalised over everything ingested into a namespace
+// rather than over the documents of one run. The statistics are saved after
+// every successful upsert or removal.
+func (u *Upserter) SetCorpusStats(stats *CorpusStatsFile) {
+	u.corpus = stats
+}
+
+// SetProgress registers a callback that receives the number of records
+// upserted so far and the total after every batch
+func (u *Upserter) SetProgress(progress func(processed, total int)) {
+	u.progress = progress
+}
+
+// SetChunker replaces the default chunker, e.g. with one that has plugins registered
+func (u *Upserter) SetChunker(chunker *document.Chunker) {
+	if chunker != nil {
+		u.chunker = chunker
 	}
 }
 
@@ -32,21 +95,7 @@ func (u *Upserter) UpsertAllDocuments(ctx context.Context, documents []document.
 	processedChunks := 0
 	failedDocuments := 0
 
-	// First pass: count total chunks for progress tracking
-	for _, doc := range documents {
-		chunker := document.NewChunker(1000)
-		chunks, err := chunker.ChunkDocument(doc)
-		if err != nil {
-			logger.LogError(fmt.Sprintf("Error chunking document %s: %v", doc.RelativePath, err))
-			failedDocuments++
-			continue
-		}
-		totalChunks += len(chunks)
-	}
-
-	logger.LogInfo(fmt.Sprintf("Total chunks to process: %d", totalChunks))
-
-	// Second pass: process documents by namespace
+	// Chunk every document and group the results by namespace
 	namespaces := make(map[string][]Document)
 
 	for i, doc := range documents {
@@ -55,8 +104,7 @@ func (u *Upserter) UpsertAllDocuments(ctx context.Context, documents []document.
 		// Extract namespace from file path (exclude dev-docs)
 		namespace := u.extractNamespace(doc.RelativePath)
 
-		chunker := document.NewChunker(1000)
-		chunks, err := chunker.ChunkDocument(doc)
+		chunks, err := u.chunker.ChunkDocument(doc)
 		if err != nil {
 			logger.LogError(fmt.Sprintf("Error chunking document %s: %v", doc.RelativePath, err))
 			failedDocuments++
@@ -64,67 +112,58 @@ func (u *Upserter) UpsertAllDocuments(ctx context.Context, documents []document.
 		}
 
 		// Convert chunks to documents
-		for _, chunk := range chunks {
-			docID := u.generateDocumentID(doc.RelativePath, chunk.Index)
+		docs, err := u.buildDocuments(doc, namespace, chunks)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error building metadata for %s: %v", doc.RelativePath, err))
+			failedDocuments++
+			continue
+		}
+		namespaces[namespace] = append(namespaces[namespace], docs...)
+	}
 
-			// Prepare metadata
-			metadata := make(map[string]string)
-			for k, v := range chunk.Metadata {
-				metadata[k] = v
-			}
-			metadata["chunk_index"] = fmt.Sprintf("%d", chunk.Index)
-			metadata["source_file"] = doc.RelativePath
-			metadata["file_size"] = fmt.Sprintf("%d", doc.Size)
-
-			// Add recipe/project information
-			fullPath := u.extractFullPath(doc.RelativePath)
-			metadata["namespace"] = namespace
-			metadata["full_path"] = fullPath
-			metadata["recipe_name"] = u.extractRecipeName(doc.RelativePath)
-			metadata["project_type"] = u.extractProjectType(doc.RelativePath)
-
-			namespaces[namespace] = append(namespaces[namespace], Document{
-				ID:        docID,
-				Content:   chunk.Content,
-				Metadata:  metadata,
-				Namespace: namespace,
-			})
+	// Count records (chunks plus any parent records) for progress tracking
+	for _, docs := range namespaces {
+		totalChunks += len(docs)
+	}
+	logger.LogInfo(fmt.Sprintf("Total chunks to process: %d", totalChunks))
+
+	if u.sparseVectors {
+		for _, docs := range namespaces {
+			u.addSparseVectors(docs)
 		}
 	}
 
-	// Process batches by namespace
+	// Plan every batch first, so an oversized chunk fails before any upsert
+	batches := make(map[string][][]Document, len(namespaces))
 	for namespace, docs := range namespaces {
-		logger.LogInfo(fmt.Sprintf("Processing %d documents for namespace: %s", len(docs), namespace))
-
-		batch := make([]Document, 0, u.batchSize)
-		for _, doc := range docs {
-			batch = append(batch, doc)
-
-			// Process batch when it reaches the size limit
-			if len(batch) >= u.batchSize {
-				err := u.client.UpsertBatch(ctx, batch, namespace)
-				if err != nil {
-					logger.LogError(fmt.Sprintf("Error upserting batch for namespace %s: %v", namespace, err))
-					return err
-				}
-				processedChunks += len(batch)
-				logger.LogProgress(processedChunks, totalChunks, "Chunks processed")
-				batch = batch[:0] // Clear batch
-			}
+		planned, err := u.limits.Split(docs, 0)
+		if err != nil {
+			return fmt.Errorf("namespace '%s': %w", namespace, err)
 		}
+		batches[namespace] = planned
+	}
 
-		// Process remaining items in batch for this namespace
-		if len(batch) > 0 {
+	// Process batches by namespace
+	for namespace, planned := range batches {
+		logger.LogInfo(fmt.Sprintf("Processing %d documents in %d batches for namespace: %s", len(namespaces[namespace]), len(planned), namespace))
+
+		for _, batch := range planned {
 			err := u.client.UpsertBatch(ctx, batch, namespace)
 			if err != nil {
-				logger.LogError(fmt.Sprintf("Error upserting final batch for namespace %s: %v", namespace, err))
+				logger.LogError(fmt.Sprintf("Error upserting batch for namespace %s: %v", namespace, err))
 				return err
 			}
+			u.recordWritten(batch)
 			processedChunks += len(batch)
-			logger.LogProgress(processedChunks, totalChunks, "Final chunks processed")
+			logger.LogProgress(processedChunks, totalChunks, "Chunks processed")
+			if u.progress != nil {
+				u.progress(processedChunks, totalChunks)
+			}
 		}
 	}
 
+	u.saveCorpus()
+
 	logger.LogSuccess(fmt.Sprintf("Upsert completed! Processed %d chunks from %d documents across %d namespaces", processedChunks, len(documents)-failedDocuments, len(namespaces)))
 	if failedDocuments > 0 {
 		logger.LogWarning(fmt.Sprintf("Failed to process %d documents", failedDocuments))
@@ -133,14 +172,126 @@ func (u *Upserter) UpsertAllDocuments(ctx context.Context, documents []document.
 	return nil
 }
 
+// buildDocuments converts the chunks of a file into vector documents with
+// metadata normalized against the schema
+func (u *Upserter) buildDocuments(doc document.FileInfo, namespace string, chunks []document.Chunk) ([]Document, error) {
+	documents := make([]Document, len(chunks))
+	for i, chunk := range chunks {
+		docID := u.generateDocumentID(doc.RelativePath, chunk.Index)
+
+		// Prepare metadata
+		metadata := chunk.Metadata.Clone()
+		metadata["chunk_index"] = chunk.Index
+		metadata["source_file"] = doc.RelativePath
+		metadata["file_size"] = doc.Size
+
+		// Add recipe/project information
+		fullPath := u.extractFullPath(doc.RelativePath)
+		metadata["namespace"] = namespace
+		metadata["full_path"] = fullPath
+		metadata["recipe_name"] = u.extractRecipeName(doc.RelativePath)
+		metadata["project_type"] = u.extractProjectType(doc.RelativePath)
+
+		content := chunk.Content
+		if u.contextHeaders {
+			header := buildContextHeader(metadata)
+			content = header + content
+			metadata[ContextHeaderLengthKey] = len(header)
+		}
+
+		documents[i] = Document{
+			ID:        docID,
+			Content:   content,
+			Metadata:  metadata,
+			Namespace: namespace,
+		}
+	}
+
+	// Parent records go first so they are upserted with their children
+	if parents := u.buildParentDocuments(doc, namespace, chunks, documents); len(parents) > 0 {
+		documents = append(parents, documents...)
+	}
+
+	for i := range documents {
+		metadata, err := u.schema.Normalize(documents[i].Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %s: %w", documents[i].ID, err)
+		}
+		documents[i].Metadata = metadata
+	}
+	return documents, nil
+}
+
+// addSparseVectors attaches BM25 sparse vectors to documents of one
+// namespace, in place. Lengths are normalised over the namespace's corpus
+// statistics when they are kept, or else over the documents themselves.
+func (u *Upserter) addSparseVectors(documents []Document) {
+	if u.corpus == nil {
+		texts := make([]string, len(documents))
+		for i, doc := range documents {
+			texts[i] = doc.Content
+		}
+		encoder := NewBM25Encoder(texts)
+		for i := range documents {
+			documents[i].SparseVector = encoder.EncodeDocument(documents[i].Content)
+		}
+		logger.LogInfo(fmt.Sprintf("Computed BM25 sparse vectors for %d chunks", len(documents)))
+		return
+	}
+
+	if len(documents) == 0 {
+		return
+	}
+	stats := u.corpus.Namespace(documents[0].Namespace)
+	for _, doc := range documents {
+		stats.Add(doc.ID, doc.Content)
+	}
+	encoder := stats.Encoder()
+	for i := range documents {
+		documents[i].SparseVector = encoder.EncodeDocument(documents[i].Content)
+	}
+	logger.LogInfo(fmt.Sprintf("Computed BM25 sparse vectors for %d chunks over %d in namespace %s", len(documents), stats.DocumentCount(), documents[0].Namespace))
+}
+
+// saveCorpus saves the BM25 statistics; failing to only costs accuracy, so
+// it is reported as a warning
+func (u *Upserter) saveCorpus() {
+	if u.corpus == nil {
+		return
+	}
+	if err := u.corpus.Save(); err != nil {
+		logger.LogWarning(fmt.Sprintf("Failed to save BM25 statistics: %v", err))
+	}
+}
+
+// deleteRecords deletes records and drops them from the BM25 statistics
+func (u *Upserter) deleteRecords(ctx context.Context, namespace string, ids []string) (int, error) {
+	deleted, err := u.client.Delete(ctx, namespace, ids)
+	if err != nil {
+		return deleted, err
+	}
+	if u.corpus != nil {
+		u.corpus.Namespace(namespace).Remove(ids...)
+		u.saveCorpus()
+	}
+	return deleted, nil
+}
+
 func (u *Upserter) extractNamespace(relativePath string) string {
+	return NamespaceForPath(relativePath)
+}
+
+// NamespaceForPath returns the namespace a file is ingested into: its parent
+// directory, sanitized
+func NamespaceForPath(relativePath string) string {
 	// Extract namespace as recipe name (last directory in path)
 	// Upstash Vector doesn't support nested namespaces with slashes
 	// Format: "go-fiber-recipes/404-handler/main.go" -> "404-handler"
 	// Format: "clean-architecture/api/handlers/book_handler.go" -> "handlers"
 	// Format: "clean-code/app/server/domain/books.go" -> "server"
 
-	parts := strings.Split(relativePath, string(filepath.Separator))
+	// Notes ingested over MCP have slash-separated sources on every platform
+	parts := strings.Split(filepath.ToSlash(relativePath), "/")
 	if len(parts) >= 2 {
 		// Get the immediate parent directory of the file
 		parentDir := parts[len(parts)-2]
@@ -215,7 +366,12 @@ func (u *Upserter) extractFullPath(relativePath string) string {
 }
 
 func (u *Upserter) generateDocumentID(filePath string, chunkIndex int) string {
-	// Create a unique ID based on file path and chunk index
+	return DocumentID(filePath, chunkIndex)
+}
+
+// DocumentID is the record ID of a chunk, derived from the file path and
+// chunk index so neighboring chunks can be fetched without a search
+func DocumentID(filePath string, chunkIndex int) string {
 	hash := md5.Sum([]byte(fmt.Sprintf("%s:%d", filePath, chunkIndex)))
 	return fmt.Sprintf("doc_%x", hash[:8])
 }
@@ -226,40 +382,45 @@ func (u *Upserter) UpsertDocument(ctx context.Context, doc document.FileInfo) er
 	// Extract namespace from file path
 	namespace := u.extractNamespace(doc.RelativePath)
 
-	chunker := document.NewChunker(1000)
-	chunks, err := chunker.ChunkDocument(doc)
+	chunks, err := u.chunker.ChunkDocument(doc)
 	if err != nil {
 		return fmt.Errorf("error chunking document %s: %w", doc.RelativePath, err)
 	}
 
-	documents := make([]Document, len(chunks))
-	for i, chunk := range chunks {
-		docID := u.generateDocumentID(doc.RelativePath, chunk.Index)
+	documents, err := u.buildDocuments(doc, namespace, chunks)
+	if err != nil {
+		return fmt.Errorf("error building metadata for %s: %w", doc.RelativePath, err)
+	}
+	if u.sparseVectors {
+		u.addSparseVectors(documents)
+	}
 
-		metadata := make(map[string]string)
-		for k, v := range chunk.Metadata {
-			metadata[k] = v
+	batches, err := u.limits.Split(documents, 0)
+	if err != nil {
+		return fmt.Errorf("error batching document %s: %w", doc.RelativePath, err)
+	}
+	for _, batch := range batches {
+		if err := u.client.UpsertBatch(ctx, batch, namespace); err != nil {
+			return err
 		}
-		metadata["chunk_index"] = fmt.Sprintf("%d", chunk.Index)
-		metadata["source_file"] = doc.RelativePath
-		metadata["file_size"] = fmt.Sprintf("%d", doc.Size)
-
-		// Add recipe/project information
-		fullPath := u.extractFullPath(doc.RelativePath)
-		metadata["namespace"] = namespace
-		metadata["full_path"] = fullPath
-		metadata["recipe_name"] = u.extractRecipeName(doc.RelativePath)
-		metadata["project_type"] = u.extractProjectType(doc.RelativePath)
+		u.recordWritten(batch)
+	}
+	u.saveCorpus()
+	return nil
+}
 
-		documents[i] = Document{
-			ID:        docID,
-			Content:   chunk.Content,
-			Metadata:  metadata,
-			Namespace: namespace,
+// recordWritten remembers the IDs upserted for each source file
+func (u *Upserter) recordWritten(batch []Document) {
+	if u.written == nil {
+		u.written = make(map[string]map[string]bool)
+	}
+	for _, doc := range batch {
+		source := document.Metadata(doc.Metadata).String("source_file")
+		if u.written[source] == nil {
+			u.written[source] = make(map[string]bool)
 		}
+		u.written[source][doc.ID] = true
 	}
-
-	return u.client.UpsertBatch(ctx, documents, namespace)
 }
 
 func (u *Upserter) ValidateDocument(doc document.FileInfo) error {
@@ -277,3 +438,78 @@ func (u *Upserter) ValidateDocument(doc document.FileInfo) error {
 
 	return nil
 }
+
+// RemoveSource deletes every record ingested from a file, including its parent
+// records, from the namespace the file was ingested into. It returns the
+// number of records deleted.
+func (u *Upserter) RemoveSource(ctx context.Context, relativePath string) (int, error) {
+	namespace := u.extractNamespace(relativePath)
+	ids, err := u.scanSources(ctx, namespace, func(source, id string) bool {
+		return source == relativePath
+	})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return u.deleteRecords(ctx, namespace, ids)
+}
+
+// RemoveStale deletes the records of the files upserted so far that were not
+// written again, such as the chunks of sections removed since a file was last
+// ingested. Calling it after a successful upsert, rather than removing a file
+// before upserting it, keeps the previous version searchable if the upsert
+// fails. It returns the number of records deleted.
+func (u *Upserter) RemoveStale(ctx context.Context) (int, error) {
+	namespaces := make(map[string]bool)
+	for source := range u.written {
+		namespaces[u.extractNamespace(source)] = true
+	}
+
+	deleted := 0
+	for namespace := range namespaces {
+		ids, err := u.scanSources(ctx, namespace, func(source, id string) bool {
+			written, ok := u.written[source]
+			return ok && !written[id]
+		})
+		if err != nil {
+			return deleted, err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		n, err := u.deleteRecords(ctx, namespace, ids)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	if deleted > 0 {
+		logger.LogInfo(fmt.Sprintf("Removed %d stale records", deleted))
+	}
+	return deleted, nil
+}
+
+// scanSources returns the IDs of the records in a namespace for which match,
+// given the record's source_file and ID, returns true
+func (u *Upserter) scanSources(ctx context.Context, namespace string, match func(source, id string) bool) ([]string, error) {
+	var ids []string
+	cursor := ""
+	for {
+		page, err := u.client.Range(ctx, namespace, RangeRequest{
+			Cursor:          cursor,
+			Limit:           1000,
+			IncludeMetadata: true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan namespace '%s': %w", namespace, err)
+		}
+		for _, record := range page.Records {
+			if match(document.Metadata(record.Metadata).String("source_file"), record.ID) {
+				ids = append(ids, record.ID)
+			}
+		}
+		if page.NextCursor == "" {
+			return ids, nil
+		}
+		cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,388 @@
+// Package vectortest provides an in-process fake of the Upstash Vector REST
+// API, so the vector client, processor and MCP tools can run without
+// credentials:
+//
+//	srv, err := vectortest.NewServer(vectortest.Options{})
+//	if err != nil { ... }
+//	defer srv.Close()
+//	client, err := vector.NewClient(srv.Config())
+//
+// The fake stores vectors in a vector.LocalStore, embeds data with the
+// deterministic hashing embedder and evaluates metadata filters with
+// vector.ParseFilter, so results are reproducible between runs.
+package vectortest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/typicalfo/prj-start/config"
+	"github.com/typicalfo/prj-start/vector"
+)
+
+// DefaultToken is the bearer token accepted when Options.Token is empty
+const DefaultToken = "vectortest-token"
+
+// Options configures a fake index
+type Options struct {
+	Token      string // bearer token clients must send, defaults to DefaultToken
+	Dimension  int    // embedding dimension, defaults to the hashing embedder's 256
+	Similarity string // COSINE (default), DOT_PRODUCT or EUCLIDEAN
+	Path       string // journal file; empty keeps the index in a temporary directory
+}
+
+// Handler serves the Upstash Vector REST API backed by a local store
+type Handler struct {
+	token    string
+	store    *vector.LocalStore
+	embedder *vector.HashEmbedder
+	tempDir  string
+}
+
+// NewHandler creates a fake index. Close removes its temporary storage.
+func NewHandler(opts Options) (*Handler, error) {
+	h := &Handler{token: opts.Token, embedder: vector.NewHashEmbedder(opts.Dimension)}
+	if h.token == "" {
+		h.token = DefaultToken
+	}
+
+	path := opts.Path
+	if path == "" {
+		dir, err := os.MkdirTemp("", "vectortest-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary index directory: %w", err)
+		}
+		h.tempDir = dir
+		path = filepath.Join(dir, "vectors.jsonl")
+	}
+
+	store, err := vector.NewLocalStore(config.LocalStoreConfig{
+		Path:       path,
+		Similarity: opts.Similarity,
+		Dimension:  opts.Dimension,
+	}, h.embedder)
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	h.store = store
+	return h, nil
+}
+
+// Store returns the backing store, for seeding or inspecting the index directly
+func (h *Handler) Store() *vector.LocalStore {
+	return h.store
+}
+
+// Close removes the temporary index directory, if one was created
+func (h *Handler) Close() error {
+	if h.tempDir == "" {
+		return nil
+	}
+	return os.RemoveAll(h.tempDir)
+}
+
+// Server is a fake index listening on a local httptest server
+type Server struct {
+	*Handler
+	URL    string
+	server *httptest.Server
+}
+
+// NewServer starts a fake index on a random local port
+func NewServer(opts Options) (*Server, error) {
+	h, err := NewHandler(opts)
+	if err != nil {
+		return nil, err
+	}
+	server := httptest.NewServer(h)
+	return &Server{Handler: h, URL: server.URL, server: server}, nil
+}
+
+// Token returns the bearer token the server accepts
+func (s *Server) Token() string {
+	return s.token
+}
+
+// Config returns an Upstash configuration pointing at the server
+func (s *Server) Config() *config.UpstashConfig {
+	return &config.UpstashConfig{URL: s.URL, Token: s.token}
+}
+
+// Close shuts the server down and removes its temporary storage
+func (s *Server) Close() error {
+	s.server.Close()
+	return s.Handler.Close()
+}
+
+// ServeHTTP routes POST /<operation>[/<namespace>] like Upstash Vector does
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+h.token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized: Invalid auth token")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "only POST is supported")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	operation, namespace, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	ctx := r.Context()
+
+	var result interface{}
+	switch operation {
+	case "upsert":
+		result, err = h.upsert(ctx, namespace, body)
+	case "upsert-data":
+		result, err = h.upsertData(ctx, namespace, body)
+	case "query":
+		result, err = h.query(ctx, namespace, body, false)
+	case "query-data":
+		result, err = h.query(ctx, namespace, body, true)
+	case "fetch":
+		result, err = h.fetch(ctx, namespace, body)
+	case "range":
+		result, err = h.rangeVectors(ctx, namespace, body)
+	case "delete":
+		result, err = h.delete(ctx, namespace, body)
+	case "list-namespaces":
+		result, err = h.store.ListNamespaces(ctx)
+	case "delete-namespace":
+		err = h.store.DeleteNamespace(ctx, namespace)
+		result = "Success"
+	case "reset":
+		err = h.store.ResetNamespace(ctx, namespace)
+		result = "Success"
+	case "info":
+		result, err = h.store.Info(ctx)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unsupported operation %q", operation))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+type upsertRequest struct {
+	ID           string                 `json:"id"`
+	Vector       []float32              `json:"vector"`
+	SparseVector *vector.SparseVector   `json:"sparseVector"`
+	Data         string                 `json:"data"`
+	Metadata     map[string]interface{} `json:"metadata"`
+}
+
+func (h *Handler) upsert(ctx context.Context, namespace string, body []byte) (interface{}, error) {
+	var requests []upsertRequest
+	if err := decodeOneOrMany(body, &requests); err != nil {
+		return nil, err
+	}
+	records := make([]vector.Record, len(requests))
+	for i, req := range requests {
+		if req.ID == "" {
+			return nil, fmt.Errorf("vector id is required")
+		}
+		if len(req.Vector) == 0 {
+			return nil, fmt.Errorf("vector %s has no dense vector", req.ID)
+		}
+		records[i] = vector.Record{
+			ID:           req.ID,
+			Vector:       req.Vector,
+			SparseVector: req.SparseVector,
+			Metadata:     req.Metadata,
+			Data:         req.Data,
+		}
+	}
+	return "Success", h.store.UpsertRecords(ctx, namespace, records)
+}
+
+// upsertData embeds the data itself, keeping metadata values as sent
+func (h *Handler) upsertData(ctx context.Context, namespace string, body []byte) (interface{}, error) {
+	var requests []upsertRequest
+	if err := decodeOneOrMany(body, &requests); err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(requests))
+	for i, req := range requests {
+		if req.ID == "" {
+			return nil, fmt.Errorf("vector id is required")
+		}
+		if req.Data == "" {
+			return nil, fmt.Errorf("vector %s has no data to embed", req.ID)
+		}
+		texts[i] = req.Data
+	}
+	vectors, err := h.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]vector.Record, len(requests))
+	for i, req := range requests {
+		records[i] = vector.Record{
+			ID:       req.ID,
+			Vector:   vectors[i],
+			Metadata: req.Metadata,
+			Data:     req.Data,
+		}
+	}
+	return "Success", h.store.UpsertRecords(ctx, namespace, records)
+}
+
+type queryRequest struct {
+	Vector          []float32 `json:"vector"`
+	Data            string    `json:"data"`
+	TopK            int       `json:"topK"`
+	Filter          string    `json:"filter"`
+	IncludeMetadata bool      `json:"includeMetadata"`
+	IncludeData     bool      `json:"includeData"`
+	IncludeVectors  bool      `json:"includeVectors"`
+}
+
+// query scores by the dense vector only; sparse vectors and fusion options
+// are accepted but ignored
+func (h *Handler) query(ctx context.Context, namespace string, body []byte, data bool) (interface{}, error) {
+	var req queryRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if data && req.Data == "" {
+		return nil, fmt.Errorf("data is required")
+	}
+	if !data && len(req.Vector) == 0 {
+		return nil, fmt.Errorf("vector is required")
+	}
+	if data {
+		req.Vector = nil
+	} else {
+		req.Data = ""
+	}
+	return h.store.Query(ctx, namespace, vector.QueryRequest{
+		Data:            req.Data,
+		Vector:          req.Vector,
+		TopK:            req.TopK,
+		Filter:          req.Filter,
+		IncludeMetadata: req.IncludeMetadata,
+		IncludeData:     req.IncludeData,
+		IncludeVectors:  req.IncludeVectors,
+	})
+}
+
+type fetchRequest struct {
+	IDs             []string `json:"ids"`
+	IncludeMetadata bool     `json:"includeMetadata"`
+	IncludeData     bool     `json:"includeData"`
+	IncludeVectors  bool     `json:"includeVectors"`
+}
+
+// fetch returns one entry per requested ID, null where it does not exist
+func (h *Handler) fetch(ctx context.Context, namespace string, body []byte) (interface{}, error) {
+	var req fetchRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid fetch: %w", err)
+	}
+	records, err := h.store.Fetch(ctx, namespace, vector.FetchRequest{
+		IDs:             req.IDs,
+		IncludeMetadata: req.IncludeMetadata,
+		IncludeData:     req.IncludeData,
+		IncludeVectors:  req.IncludeVectors,
+	})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]vector.Record, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+	result := make([]*vector.Record, len(req.IDs))
+	for i, id := range req.IDs {
+		if record, ok := byID[id]; ok {
+			result[i] = &record
+		}
+	}
+	return result, nil
+}
+
+type rangeRequest struct {
+	Cursor          string `json:"cursor"`
+	Limit           int    `json:"limit"`
+	IncludeMetadata bool   `json:"includeMetadata"`
+	IncludeData     bool   `json:"includeData"`
+	IncludeVectors  bool   `json:"includeVectors"`
+}
+
+func (h *Handler) rangeVectors(ctx context.Context, namespace string, body []byte) (interface{}, error) {
+	var req rangeRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid range: %w", err)
+	}
+	if req.Limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	page, err := h.store.Range(ctx, namespace, vector.RangeRequest{
+		Cursor:          req.Cursor,
+		Limit:           req.Limit,
+		IncludeMetadata: req.IncludeMetadata,
+		IncludeData:     req.IncludeData,
+		IncludeVectors:  req.IncludeVectors,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"nextCursor": page.NextCursor, "vectors": page.Records}, nil
+}
+
+// delete accepts a JSON array of IDs, or a single raw ID as the SDK sends it
+func (h *Handler) delete(ctx context.Context, namespace string, body []byte) (interface{}, error) {
+	var ids []string
+	if err := json.Unmarshal(body, &ids); err != nil {
+		ids = []string{strings.Trim(string(bytes.TrimSpace(body)), `"`)}
+	}
+	count, err := h.store.Delete(ctx, namespace, ids)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{"deleted": count}, nil
+}
+
+// decodeOneOrMany decodes a JSON object or array of objects into a slice
+func decodeOneOrMany(body []byte, v *[]upsertRequest) error {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, v); err != nil {
+			return fmt.Errorf("invalid upsert: %w", err)
+		}
+		return nil
+	}
+	var one upsertRequest
+	if err := json.Unmarshal(body, &one); err != nil {
+		return fmt.Errorf("invalid upsert: %w", err)
+	}
+	*v = []upsertRequest{one}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"error": message, "status": status})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}